@@ -3,7 +3,14 @@ package apitypes
 import "time"
 
 type ErrRsp struct {
-	Error string `json:"error,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Fields []*FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is the error of a message data field, the field is empty if the error is about the whole data
+type FieldError struct {
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
 }
 
 func NewErrRsp(err error) *ErrRsp {
@@ -14,16 +21,107 @@ type HandleMessageReq struct {
 	ProjectID      uint64 `json:"projectID"        binding:"required"`
 	ProjectVersion string `json:"projectVersion"   binding:"required"`
 	Data           string `json:"data"             binding:"required"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty" binding:"max=128"` // optional, also accepted from the Idempotency-Key header
+	Signature      string `json:"signature,omitempty"`                        // optional, hex encoded device signature of data
 }
 
 type HandleMessageRsp struct {
 	MessageID string `json:"messageID"`
 }
 
+// HandleMessagesItem is the result of a message in batch, either the message id or the error
+type HandleMessagesItem struct {
+	MessageID string        `json:"messageID,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Fields    []*FieldError `json:"fields,omitempty"`
+}
+
+type HandleMessagesRsp struct {
+	Messages []*HandleMessagesItem `json:"messages"`
+}
+
+// MQTTMessageReq is the payload published on {prefix}/{projectID}/{projectVersion}/messages, the subscriber
+// could not see the publisher credentials, so the client authenticates by the token in payload
+type MQTTMessageReq struct {
+	Token          string `json:"token"`               // the credential token issued by /issue_vc
+	RequestID      string `json:"requestID,omitempty"` // optional, echoed in the replies
+	Data           string `json:"data"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	Signature      string `json:"signature,omitempty"`
+}
+
+// MQTTReply is published on {prefix}/{projectID}/{projectVersion}/replies/{clientDID} encrypted for the client, first
+// the message id or the error, and then a reply for each state update of the message
+type MQTTReply struct {
+	RequestID string        `json:"requestID,omitempty"`
+	MessageID string        `json:"messageID,omitempty"`
+	State     *StateLog     `json:"state,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Fields    []*FieldError `json:"fields,omitempty"`
+}
+
+// QueryMessagesReq is the filter of listing messages, the result is paginated by message id in ascending order
+type QueryMessagesReq struct {
+	ClientID       string    `form:"clientID"`
+	ProjectID      uint64    `form:"projectID"`
+	ProjectVersion string    `form:"projectVersion"`
+	Since          time.Time `form:"since"  time_format:"2006-01-02T15:04:05Z07:00"`
+	Until          time.Time `form:"until"  time_format:"2006-01-02T15:04:05Z07:00"`
+	Status         string    `form:"status" binding:"omitempty,oneof=packed unpacked"`
+	After          uint64    `form:"after"` // the cursor, returned as next of the previous page
+	Limit          int       `form:"limit"  binding:"omitempty,min=1,max=1000"`
+}
+
+type MessageItem struct {
+	ID             uint64    `json:"id"`
+	MessageID      string    `json:"messageID"`
+	ClientID       string    `json:"clientID"`
+	ProjectID      uint64    `json:"projectID"`
+	ProjectVersion string    `json:"projectVersion"`
+	Data           string    `json:"data"`
+	Packed         bool      `json:"packed"`
+	InternalTaskID string    `json:"internalTaskID,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type QueryMessagesRsp struct {
+	Messages []*MessageItem `json:"messages"`
+	Next     uint64         `json:"next,omitempty"` // the cursor of next page, empty if no more messages
+}
+
+// QueryTasksReq is the filter of listing tasks, the result is paginated by task id in ascending order
+type QueryTasksReq struct {
+	ClientID  string    `form:"clientID"` // the tasks containing messages of the client
+	ProjectID uint64    `form:"projectID"`
+	Since     time.Time `form:"since"  time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until"  time_format:"2006-01-02T15:04:05Z07:00"`
+	After     uint64    `form:"after"`
+	Limit     int       `form:"limit"  binding:"omitempty,min=1,max=1000"`
+}
+
+type TaskItem struct {
+	TaskID         uint64    `json:"taskID"`
+	ProjectID      uint64    `json:"projectID"`
+	InternalTaskID string    `json:"internalTaskID"`
+	MessageIDs     []string  `json:"messageIDs"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type QueryTasksRsp struct {
+	Tasks []*TaskItem `json:"tasks"`
+	Next  uint64      `json:"next,omitempty"`
+}
+
 type LivenessRsp struct {
 	Status string `json:"status"`
 }
 
+// the server sent event names of message and task state streams, the data of ErrorEvent is ErrRsp
+const (
+	StateLogEvent = "state"
+	ErrorEvent    = "error"
+)
+
 type StateLog struct {
 	State   string    `json:"state"`
 	Time    time.Time `json:"time"`
@@ -38,14 +136,62 @@ type QueryTaskStateLogRsp struct {
 }
 
 type QueryMessageStateLogRsp struct {
-	MessageID string      `json:"messageID"`
-	States    []*StateLog `json:"states"`
+	MessageID string            `json:"messageID"`
+	States    []*StateLog       `json:"states"`
+	Inclusion *MessageInclusion `json:"inclusion,omitempty"` // set after the message is packed
+}
+
+// MessageInclusion proves the message data is committed by the merkle root of the task, see util/merkle.Verify
+type MessageInclusion struct {
+	TaskID        uint64             `json:"taskID"`
+	Root          string             `json:"root"`
+	RootSignature string             `json:"rootSignature"` // signed by the sequencer over keccak256(taskID || projectID || root)
+	Proof         []*MerkleProofNode `json:"proof"`
+	OutputTx      string             `json:"outputTx,omitempty"`
+}
+
+type MerkleProofNode struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// ProcessedTaskRsp is the last task of the project processed by the coordinator, the sequencer only purges the tasks
+// processed
+type ProcessedTaskRsp struct {
+	ProjectID uint64 `json:"projectID"`
+	TaskID    uint64 `json:"taskID"`
 }
 
 type CoordinatorConfigRsp struct {
-	ProjectContractAddress string `json:"projectContractAddress"`
-	OperatorETHAddress     string `json:"OperatorETHAddress,omitempty"`
-	OperatorSolanaAddress  string `json:"operatorSolanaAddress,omitempty"`
+	ProjectContractAddress string             `json:"projectContractAddress"`
+	OperatorETHAddress     string             `json:"OperatorETHAddress,omitempty"`
+	OperatorSolanaAddress  string             `json:"operatorSolanaAddress,omitempty"`
+	ProjectOperators       []*ProjectOperator `json:"projectOperators,omitempty"`
+}
+
+// ProjectOperator is the operator of a project which has dedicated keys
+type ProjectOperator struct {
+	ProjectID             uint64 `json:"projectID"`
+	OperatorETHAddress    string `json:"operatorETHAddress,omitempty"`
+	OperatorSolanaAddress string `json:"operatorSolanaAddress,omitempty"`
+}
+
+type BudgetStatusRsp struct {
+	ProjectID    uint64  `json:"projectID"`
+	Window       string  `json:"window,omitempty"`
+	GasUsed      uint64  `json:"gasUsed"`
+	FeePaid      string  `json:"feePaid"`
+	GasLimit     uint64  `json:"gasLimit,omitempty"`
+	GasRemaining *uint64 `json:"gasRemaining,omitempty"` // nil if no gas limit, 0 if exhausted
+	FeeLimit     string  `json:"feeLimit,omitempty"`
+	FeeRemaining string  `json:"feeRemaining,omitempty"`
+	Paused       bool    `json:"paused"`
+	HeldTasks    int     `json:"heldTasks"`
+}
+
+type ResumeBudgetRsp struct {
+	ProjectID    uint64 `json:"projectID"`
+	ResumedTasks int    `json:"resumedTasks"`
 }
 
 type IssueTokenReq struct {
@@ -0,0 +1,379 @@
+package budget
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/metrics"
+	"github.com/machinefi/sprout/output"
+)
+
+// LimitConfig is the spend limit of a project over a rolling window
+type LimitConfig struct {
+	Window   string `json:"window"`             // rolling window duration, e.g. 24h
+	GasLimit uint64 `json:"gasLimit,omitempty"` // 0 means unlimited
+	FeeLimit string `json:"feeLimit,omitempty"` // in wei, empty means unlimited
+}
+
+// Config is the budget file content, a project without its own limit uses the default one, and a project without
+// any limit is never paused
+type Config struct {
+	Default  *LimitConfig            `json:"default,omitempty"`
+	Projects map[string]*LimitConfig `json:"projects,omitempty"` // project id -> limit
+}
+
+type limit struct {
+	window   time.Duration
+	gasLimit uint64
+	feeLimit *big.Int
+}
+
+type record struct {
+	at      time.Time
+	gasUsed uint64
+	fee     *big.Int
+}
+
+type project struct {
+	records   []*record
+	paused    bool
+	resumedAt time.Time // the spend before is cleared by the resume
+	held      []func()
+}
+
+// Record is a persisted spend of a project output
+type Record struct {
+	ProjectID uint64
+	GasUsed   uint64
+	Fee       *big.Int
+	CreatedAt time.Time
+}
+
+// State is the persisted pause state of a project budget
+type State struct {
+	ProjectID uint64
+	Paused    bool
+	ResumedAt time.Time // the spend before is cleared by the resume
+}
+
+// Store persists the spend and the pause state of project budgets, so that they survive restarts
+type Store interface {
+	CreateBudgetRecord(r *Record) error
+	// BudgetRecords returns the spend of the project created after since, in the created order
+	BudgetRecords(projectID uint64, since time.Time) ([]*Record, error)
+	// BudgetState returns nil if the project budget is never paused or resumed
+	BudgetState(projectID uint64) (*State, error)
+	UpsertBudgetState(s *State) error
+}
+
+// Status is the spend and remaining budget of a project in the current window
+type Status struct {
+	ProjectID    uint64
+	Window       time.Duration
+	GasUsed      uint64
+	FeePaid      *big.Int
+	GasLimit     uint64   // 0 means unlimited
+	FeeLimit     *big.Int // nil means unlimited
+	GasRemaining uint64
+	FeeRemaining *big.Int // nil means unlimited
+	Paused       bool
+	HeldTasks    int
+}
+
+// Tracker tracks the output spend of projects over rolling windows, and pauses the output of a project once its
+// budget is exceeded. the spend and the pause state are persisted to the store if configured. the outputs of a paused
+// project are held in memory until resumed by admin, the held tasks are in held state and not processed, so they are
+// dispatched again after the coordinator restarts
+type Tracker struct {
+	mux          sync.Mutex
+	defaultLimit *limit
+	limits       map[uint64]*limit
+	projects     map[uint64]*project
+	store        Store // optional, nil means the budget state is in memory only
+	now          func() time.Time
+}
+
+func (t *Tracker) limit(projectID uint64) *limit {
+	if l, ok := t.limits[projectID]; ok {
+		return l
+	}
+	return t.defaultLimit
+}
+
+func (t *Tracker) project(projectID uint64) *project {
+	p, ok := t.projects[projectID]
+	if ok {
+		return p
+	}
+	p = &project{}
+	if err := t.load(projectID, p); err != nil {
+		// not cached, the project is loaded again next time
+		slog.Error("failed to load project budget", "project_id", projectID, "error", err)
+		return p
+	}
+	t.projects[projectID] = p
+	return p
+}
+
+// load restores the pause state and the spend in window of the project from the store
+func (t *Tracker) load(projectID uint64, p *project) error {
+	if t.store == nil {
+		return nil
+	}
+	s, err := t.store.BudgetState(projectID)
+	if err != nil {
+		return err
+	}
+	if s != nil {
+		p.paused, p.resumedAt = s.Paused, s.ResumedAt
+	}
+	l := t.limit(projectID)
+	if l == nil {
+		return nil
+	}
+	since := t.now().Add(-l.window)
+	if p.resumedAt.After(since) {
+		since = p.resumedAt
+	}
+	rs, err := t.store.BudgetRecords(projectID, since)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		fee := new(big.Int)
+		if r.Fee != nil {
+			fee.Set(r.Fee)
+		}
+		p.records = append(p.records, &record{at: r.CreatedAt, gasUsed: r.GasUsed, fee: fee})
+	}
+	return nil
+}
+
+// persist persists the pause state of the project, the state in memory takes effect even if failed
+func (t *Tracker) persist(projectID uint64, p *project) {
+	if t.store == nil {
+		return
+	}
+	if err := t.store.UpsertBudgetState(&State{ProjectID: projectID, Paused: p.paused, ResumedAt: p.resumedAt}); err != nil {
+		slog.Error("failed to persist project budget state", "project_id", projectID, "error", err)
+	}
+}
+
+// spent sums the spend in window, and drops the records out of window
+func (t *Tracker) spent(projectID uint64) (uint64, *big.Int) {
+	p := t.project(projectID)
+	l := t.limit(projectID)
+	if l != nil {
+		begin := t.now().Add(-l.window)
+		i := 0
+		for i < len(p.records) && !p.records[i].at.After(begin) {
+			i++
+		}
+		p.records = p.records[i:]
+	}
+
+	gas, fee := uint64(0), new(big.Int)
+	for _, r := range p.records {
+		gas += r.gasUsed
+		fee.Add(fee, r.fee)
+	}
+	return gas, fee
+}
+
+func (t *Tracker) status(projectID uint64) *Status {
+	gas, fee := t.spent(projectID)
+	p := t.project(projectID)
+	s := &Status{
+		ProjectID: projectID,
+		GasUsed:   gas,
+		FeePaid:   fee,
+		Paused:    p.paused,
+		HeldTasks: len(p.held),
+	}
+	l := t.limit(projectID)
+	if l == nil {
+		return s
+	}
+	s.Window = l.window
+	s.GasLimit = l.gasLimit
+	if l.gasLimit > 0 && l.gasLimit > gas {
+		s.GasRemaining = l.gasLimit - gas
+	}
+	if l.feeLimit != nil {
+		s.FeeLimit = new(big.Int).Set(l.feeLimit)
+		s.FeeRemaining = new(big.Int)
+		if l.feeLimit.Cmp(fee) > 0 {
+			s.FeeRemaining.Sub(l.feeLimit, fee)
+		}
+	}
+	return s
+}
+
+func (t *Tracker) exceeded(projectID uint64) bool {
+	l := t.limit(projectID)
+	if l == nil {
+		return false
+	}
+	gas, fee := t.spent(projectID)
+	return (l.gasLimit > 0 && gas >= l.gasLimit) || (l.feeLimit != nil && fee.Cmp(l.feeLimit) >= 0)
+}
+
+func (t *Tracker) reportMetrics(s *Status) {
+	fee, _ := new(big.Float).SetInt(s.FeePaid).Float64()
+	metrics.BudgetSpentMtc(s.ProjectID, float64(s.GasUsed), fee)
+	if s.GasLimit > 0 {
+		metrics.BudgetGasRemainingMtc(s.ProjectID, float64(s.GasRemaining))
+	}
+	if s.FeeRemaining != nil {
+		remaining, _ := new(big.Float).SetInt(s.FeeRemaining).Float64()
+		metrics.BudgetFeeRemainingMtc(s.ProjectID, remaining)
+	}
+	metrics.BudgetPausedMtc(s.ProjectID, s.Paused)
+}
+
+// Record records the spend of an output, the project is paused if its budget is exceeded
+func (t *Tracker) Record(projectID uint64, spend *output.Spend) {
+	if spend == nil {
+		return
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	fee := new(big.Int)
+	if spend.Fee != nil {
+		fee.Set(spend.Fee)
+	}
+	p := t.project(projectID)
+	r := &record{at: t.now(), gasUsed: spend.GasUsed, fee: fee}
+	p.records = append(p.records, r)
+	// the spend of an unlimited project is never loaded, so it's not persisted either
+	if t.store != nil && t.limit(projectID) != nil {
+		if err := t.store.CreateBudgetRecord(&Record{
+			ProjectID: projectID,
+			GasUsed:   r.gasUsed,
+			Fee:       new(big.Int).Set(r.fee),
+			CreatedAt: r.at,
+		}); err != nil {
+			slog.Error("failed to persist project spend", "project_id", projectID, "error", err)
+		}
+	}
+	if !p.paused && t.exceeded(projectID) {
+		p.paused = true
+		t.persist(projectID, p)
+	}
+	t.reportMetrics(t.status(projectID))
+}
+
+// Hold holds the output if the project is paused, and returns false if the output could be done right now
+func (t *Tracker) Hold(projectID uint64, out func()) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	p := t.project(projectID)
+	if !p.paused {
+		return false
+	}
+	p.held = append(p.held, out)
+	return true
+}
+
+// Resume resumes the output of a paused project, and returns the number of held tasks which are output again. the
+// spend in current window is cleared, otherwise the project would be paused again right after the next output
+func (t *Tracker) Resume(projectID uint64) int {
+	t.mux.Lock()
+	p := t.project(projectID)
+	held := p.held
+	p.held = nil
+	p.paused = false
+	p.records = nil
+	p.resumedAt = t.now()
+	t.persist(projectID, p)
+	t.reportMetrics(t.status(projectID))
+	t.mux.Unlock()
+
+	go func() {
+		for _, out := range held {
+			out()
+		}
+	}()
+	return len(held)
+}
+
+// Status returns the budget status of the project
+func (t *Tracker) Status(projectID uint64) *Status {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	return t.status(projectID)
+}
+
+func parseLimit(c *LimitConfig) (*limit, error) {
+	window, err := time.ParseDuration(c.Window)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse budget window %s", c.Window)
+	}
+	if window <= 0 {
+		return nil, errors.Errorf("invalid budget window %s", c.Window)
+	}
+	l := &limit{window: window, gasLimit: c.GasLimit}
+	if c.FeeLimit != "" {
+		fee, ok := new(big.Int).SetString(c.FeeLimit, 10)
+		if !ok {
+			return nil, errors.Errorf("failed to parse budget fee limit %s", c.FeeLimit)
+		}
+		l.feeLimit = fee
+	}
+	return l, nil
+}
+
+// Load loads the budget config file
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read budget file %s", path)
+	}
+	c := &Config{}
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode budget file %s", path)
+	}
+	return c, nil
+}
+
+// NewTracker creates the tracker, store is optional
+func NewTracker(conf *Config, store Store) (*Tracker, error) {
+	t := &Tracker{
+		limits:   map[uint64]*limit{},
+		projects: map[uint64]*project{},
+		store:    store,
+		now:      time.Now,
+	}
+	if conf == nil {
+		return t, nil
+	}
+	if conf.Default != nil {
+		l, err := parseLimit(conf.Default)
+		if err != nil {
+			return nil, err
+		}
+		t.defaultLimit = l
+	}
+	for k, c := range conf.Projects {
+		projectID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid project id %s in budget config", k)
+		}
+		l, err := parseLimit(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid budget of project %d", projectID)
+		}
+		t.limits[projectID] = l
+	}
+	return t, nil
+}
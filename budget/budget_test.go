@@ -0,0 +1,179 @@
+package budget
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/machinefi/sprout/output"
+)
+
+type mockStore struct {
+	records []*Record
+	states  map[uint64]*State
+}
+
+func (m *mockStore) CreateBudgetRecord(r *Record) error {
+	m.records = append(m.records, r)
+	return nil
+}
+
+func (m *mockStore) BudgetRecords(projectID uint64, since time.Time) ([]*Record, error) {
+	rs := []*Record{}
+	for _, r := range m.records {
+		if r.ProjectID == projectID && r.CreatedAt.After(since) {
+			rs = append(rs, r)
+		}
+	}
+	return rs, nil
+}
+
+func (m *mockStore) BudgetState(projectID uint64) (*State, error) {
+	return m.states[projectID], nil
+}
+
+func (m *mockStore) UpsertBudgetState(s *State) error {
+	m.states[s.ProjectID] = s
+	return nil
+}
+
+func TestNewTracker(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("WithoutConfig", func(t *testing.T) {
+		tr, err := NewTracker(nil, nil)
+		r.NoError(err)
+		tr.Record(1, &output.Spend{GasUsed: 1 << 60, Fee: big.NewInt(1)})
+		r.False(tr.Status(1).Paused)
+	})
+	t.Run("InvalidWindow", func(t *testing.T) {
+		_, err := NewTracker(&Config{Default: &LimitConfig{Window: "any"}}, nil)
+		r.Error(err)
+		_, err = NewTracker(&Config{Default: &LimitConfig{Window: "-1h"}}, nil)
+		r.Error(err)
+	})
+	t.Run("InvalidFeeLimit", func(t *testing.T) {
+		_, err := NewTracker(&Config{Projects: map[string]*LimitConfig{"1": {Window: "1h", FeeLimit: "any"}}}, nil)
+		r.Error(err)
+	})
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		_, err := NewTracker(&Config{Projects: map[string]*LimitConfig{"any": {Window: "1h"}}}, nil)
+		r.Error(err)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("FailedToRead", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "none.json"))
+		r.Error(err)
+	})
+	t.Run("FailedToDecode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "budget.json")
+		r.NoError(os.WriteFile(path, []byte("{"), 0600))
+		_, err := Load(path)
+		r.Error(err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "budget.json")
+		r.NoError(os.WriteFile(path, []byte(`{"default":{"window":"24h","gasLimit":100},"projects":{"1":{"window":"1h","feeLimit":"1000"}}}`), 0600))
+		c, err := Load(path)
+		r.NoError(err)
+		r.Equal(uint64(100), c.Default.GasLimit)
+		r.Equal("1000", c.Projects["1"].FeeLimit)
+	})
+}
+
+func TestTracker(t *testing.T) {
+	r := require.New(t)
+
+	tr, err := NewTracker(&Config{
+		Default:  &LimitConfig{Window: "1h", GasLimit: 100},
+		Projects: map[string]*LimitConfig{"2": {Window: "1h", FeeLimit: "1000"}},
+	}, nil)
+	r.NoError(err)
+	now := time.Now()
+	tr.now = func() time.Time { return now }
+
+	t.Run("GasLimit", func(t *testing.T) {
+		tr.Record(1, &output.Spend{GasUsed: 60, Fee: big.NewInt(1)})
+		r.False(tr.Hold(1, func() {}))
+
+		tr.Record(1, &output.Spend{GasUsed: 40})
+		s := tr.Status(1)
+		r.True(s.Paused)
+		r.Equal(uint64(100), s.GasUsed)
+		r.Equal(uint64(0), s.GasRemaining)
+		r.Nil(s.FeeLimit)
+	})
+	t.Run("FeeLimit", func(t *testing.T) {
+		tr.Record(2, &output.Spend{GasUsed: 1000, Fee: big.NewInt(400)})
+		s := tr.Status(2)
+		r.False(s.Paused)
+		r.Equal(uint64(0), s.GasLimit)
+		r.Equal(big.NewInt(600), s.FeeRemaining)
+
+		tr.Record(2, &output.Spend{Fee: big.NewInt(600)})
+		r.True(tr.Status(2).Paused)
+	})
+	t.Run("WindowExpired", func(t *testing.T) {
+		tr.Record(3, &output.Spend{GasUsed: 50})
+		now = now.Add(time.Hour)
+		r.Equal(uint64(0), tr.Status(3).GasUsed)
+	})
+	t.Run("HoldAndResume", func(t *testing.T) {
+		done := make(chan struct{}, 2)
+		r.True(tr.Hold(1, func() { done <- struct{}{} }))
+		r.True(tr.Hold(1, func() { done <- struct{}{} }))
+		r.Equal(2, tr.Status(1).HeldTasks)
+
+		r.Equal(2, tr.Resume(1))
+		<-done
+		<-done
+		s := tr.Status(1)
+		r.False(s.Paused)
+		r.Equal(0, s.HeldTasks)
+		r.Equal(uint64(0), s.GasUsed)
+		r.False(tr.Hold(1, func() {}))
+	})
+}
+
+func TestTracker_Store(t *testing.T) {
+	r := require.New(t)
+
+	store := &mockStore{states: map[uint64]*State{}}
+	conf := &Config{Default: &LimitConfig{Window: "1h", GasLimit: 100}}
+	now := time.Now()
+	newTracker := func() *Tracker {
+		tr, err := NewTracker(conf, store)
+		r.NoError(err)
+		tr.now = func() time.Time { return now }
+		return tr
+	}
+
+	tr := newTracker()
+	tr.Record(1, &output.Spend{GasUsed: 60})
+	tr.Record(1, &output.Spend{GasUsed: 40})
+	r.True(tr.Status(1).Paused)
+
+	t.Run("PausedAfterRestart", func(t *testing.T) {
+		s := newTracker().Status(1)
+		r.True(s.Paused)
+		r.Equal(uint64(100), s.GasUsed)
+	})
+	t.Run("SpendClearedAfterRestart", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		r.Equal(0, tr.Resume(1))
+
+		tr := newTracker()
+		s := tr.Status(1)
+		r.False(s.Paused)
+		r.Equal(uint64(0), s.GasUsed)
+		r.False(tr.Hold(1, func() {}))
+	})
+}
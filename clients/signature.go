@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/task"
+)
+
+// VerifySignature verifies the signature of data by the authentication keys in the client DID document, and returns
+// the verified device signature
+func (c *Client) VerifySignature(data, sig []byte) (*task.DeviceSignature, error) {
+	return verifySignature(c.Doc(), data, sig)
+}
+
+func verifySignature(doc *ioconnect.Doc, data, sig []byte) (*task.DeviceSignature, error) {
+	if doc == nil {
+		return nil, errors.New("client did document not exist")
+	}
+	var err error
+	for _, kid := range doc.Authentication {
+		for i := range doc.VerificationMethod {
+			vm := &doc.VerificationMethod[i]
+			if vm.ID != kid {
+				continue
+			}
+			var pk []byte
+			if pk, err = publicKey(&vm.PublicKeyJwk); err != nil {
+				continue
+			}
+			s := &task.DeviceSignature{
+				KeyID:     kid,
+				Curve:     vm.PublicKeyJwk.Crv,
+				PublicKey: pk,
+				Signature: sig,
+			}
+			if err = s.Verify(data); err == nil {
+				return s, nil
+			}
+		}
+	}
+	if err == nil {
+		return nil, errors.New("no authentication key in client did document")
+	}
+	return nil, err
+}
+
+// publicKey returns the uncompressed public key of the ec jwk
+func publicKey(k *ioconnect.PublicKeyJWK) ([]byte, error) {
+	if k.Kty != ioconnect.JwkType_EC.String() {
+		return nil, errors.Errorf("unsupported jwk key type %s", k.Kty)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.X, "="))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode jwk x")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.Y, "="))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode jwk y")
+	}
+	return task.NewDevicePublicKey(k.Crv, x, y)
+}
@@ -0,0 +1,85 @@
+package clients
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
+	"github.com/stretchr/testify/require"
+
+	"github.com/machinefi/sprout/task"
+)
+
+func TestVerifySignature(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	r.NoError(err)
+	data := []byte("data")
+	h := sha256.Sum256(data)
+	sr, ss, err := ecdsa.Sign(rand.Reader, sk, h[:])
+	r.NoError(err)
+	sig := make([]byte, 64)
+	sr.FillBytes(sig[:32])
+	ss.FillBytes(sig[32:])
+
+	jwk := ioconnect.PublicKeyJWK{
+		Crv: task.CurveP256,
+		X:   base64.RawURLEncoding.EncodeToString(sk.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(sk.Y.FillBytes(make([]byte, 32))),
+		Kty: ioconnect.JwkType_EC.String(),
+	}
+	doc := &ioconnect.Doc{
+		Authentication: []string{"did:io:0x01#key"},
+		VerificationMethod: []ioconnect.VerificationMethod{
+			{ID: "did:io:0x01#ka", PublicKeyJwk: ioconnect.PublicKeyJWK{Kty: "OKP"}},
+			{ID: "did:io:0x01#key", PublicKeyJwk: jwk},
+		},
+	}
+
+	t.Run("NilDocument", func(t *testing.T) {
+		_, err := verifySignature(nil, data, sig)
+		r.ErrorContains(err, "did document not exist")
+	})
+
+	t.Run("NoAuthenticationKey", func(t *testing.T) {
+		_, err := verifySignature(&ioconnect.Doc{}, data, sig)
+		r.ErrorContains(err, "no authentication key")
+	})
+
+	t.Run("UnsupportedKeyType", func(t *testing.T) {
+		_, err := verifySignature(&ioconnect.Doc{
+			Authentication:     []string{"did:io:0x01#ka"},
+			VerificationMethod: doc.VerificationMethod,
+		}, data, sig)
+		r.ErrorContains(err, "unsupported jwk key type")
+	})
+
+	t.Run("InvalidJWK", func(t *testing.T) {
+		k := jwk
+		k.X = "!"
+		_, err := verifySignature(&ioconnect.Doc{
+			Authentication:     doc.Authentication,
+			VerificationMethod: []ioconnect.VerificationMethod{{ID: "did:io:0x01#key", PublicKeyJwk: k}},
+		}, data, sig)
+		r.ErrorContains(err, "failed to decode jwk x")
+	})
+
+	t.Run("Unmatched", func(t *testing.T) {
+		_, err := verifySignature(doc, []byte("other"), sig)
+		r.ErrorContains(err, "device signature unmatched")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		s, err := verifySignature(doc, data, sig)
+		r.NoError(err)
+		r.Equal("did:io:0x01#key", s.KeyID)
+		r.Equal(task.CurveP256, s.Curve)
+		r.Equal(sig, s.Signature)
+		r.NoError(s.Verify(data))
+	})
+}
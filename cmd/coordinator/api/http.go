@@ -1,58 +1,70 @@
 package api
 
 import (
+	"crypto/subtle"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
-	solanatypes "github.com/blocto/solana-go-sdk/types"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	solanacommon "github.com/blocto/solana-go-sdk/common"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/budget"
 	"github.com/machinefi/sprout/cmd/coordinator/config"
 	"github.com/machinefi/sprout/persistence/postgres"
+	"github.com/machinefi/sprout/signer"
+	"github.com/machinefi/sprout/task"
 )
 
 type HttpServer struct {
 	engine          *gin.Engine
 	persistence     *postgres.Postgres
 	conf            *config.Config
+	operators       *signer.Registry
+	projectIDs      func() []uint64
+	budget          *budget.Tracker
 	coordinatorConf *apitypes.CoordinatorConfigRsp
 }
 
-func NewHttpServer(persistence *postgres.Postgres, conf *config.Config) *HttpServer {
+func NewHttpServer(persistence *postgres.Postgres, conf *config.Config, operators *signer.Registry, projectIDs func() []uint64,
+	budget *budget.Tracker) *HttpServer {
 	s := &HttpServer{
 		engine:      gin.Default(),
 		persistence: persistence,
 		conf:        conf,
+		operators:   operators,
+		projectIDs:  projectIDs,
+		budget:      budget,
 	}
 
 	s.coordinatorConf = &apitypes.CoordinatorConfigRsp{
 		ProjectContractAddress: s.conf.ProjectContractAddr,
 	}
 
-	if len(s.conf.OperatorPriKey) > 0 {
-		pk := crypto.ToECDSAUnsafe(common.FromHex(s.conf.OperatorPriKey))
-		sender := crypto.PubkeyToAddress(pk.PublicKey)
-		s.coordinatorConf.OperatorETHAddress = sender.String()
-	}
-
-	if len(s.conf.OperatorPriKeyED25519) > 0 {
-		wallet, err := solanatypes.AccountFromHex(s.conf.OperatorPriKeyED25519)
-		if err != nil {
-			panic(errors.Wrapf(err, "invalid solana wallet address"))
-		}
-		s.coordinatorConf.OperatorSolanaAddress = wallet.PublicKey.String()
+	if operators != nil {
+		operatorECDSA, operatorED25519 := operators.Default()
+		s.coordinatorConf.OperatorETHAddress, s.coordinatorConf.OperatorSolanaAddress = operatorAddresses(operatorECDSA, operatorED25519)
 	}
 
 	s.engine.GET("/live", s.liveness)
 	s.engine.GET("/task/:project_id/:task_id", s.getTaskStateLog)
+	s.engine.GET("/task/:project_id/:task_id/stream", s.streamTaskStateLog)
+	s.engine.GET("/project/:project_id/processed_task", s.getProcessedTask)
 	s.engine.GET("/coordinator_config", s.getCoordinatorConfigInfo)
 	s.engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// admin endpoints are only served when admin token configured
+	if s.conf.AdminToken != "" && s.budget != nil {
+		admin := s.engine.Group("/admin", s.adminAuth)
+		admin.GET("/budget/:project_id", s.getBudgetStatus)
+		admin.POST("/budget/:project_id/resume", s.resumeBudget)
+	}
+
 	return s
 }
 
@@ -95,12 +107,7 @@ func (s *HttpServer) getTaskStateLog(c *gin.Context) {
 
 	ss := []*apitypes.StateLog{}
 	for _, l := range ls {
-		ss = append(ss, &apitypes.StateLog{
-			State:   l.State.String(),
-			Time:    l.CreatedAt,
-			Comment: l.Comment,
-			Result:  string(l.Result),
-		})
+		ss = append(ss, stateLog(l))
 	}
 
 	c.JSON(http.StatusOK, &apitypes.QueryTaskStateLogRsp{
@@ -110,6 +117,176 @@ func (s *HttpServer) getTaskStateLog(c *gin.Context) {
 	})
 }
 
+// streamTaskStateLog pushes the task state logs as server sent events, the persisted logs are sent first, and the
+// stream ends after the task is outputted or failed
+func (s *HttpServer) streamTaskStateLog(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("project_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+	taskID, err := strconv.ParseUint(c.Param("task_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+
+	// subscribe before fetching, so no state log is missed in between
+	states, cancel := s.persistence.Subscribe(taskID, projectID)
+	defer cancel()
+
+	ls, err := s.persistence.Fetch(taskID, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	// the logs persisted between subscribing and fetching are published too, the postgres timestamp is in microseconds
+	type sentKey struct {
+		state task.State
+		time  int64
+	}
+	sent := map[sentKey]bool{}
+	for _, l := range ls {
+		sent[sentKey{l.State, l.CreatedAt.UnixMicro()}] = true
+		c.SSEvent(apitypes.StateLogEvent, stateLog(l))
+		if terminalState(l.State) {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case l, ok := <-states:
+			if !ok {
+				// dropped as slow subscriber, the client could reconnect and resume from the persisted logs
+				return
+			}
+			if sent[sentKey{l.State, l.CreatedAt.UnixMicro()}] {
+				continue
+			}
+			c.SSEvent(apitypes.StateLogEvent, stateLog(l))
+			c.Writer.Flush()
+			if terminalState(l.State) {
+				return
+			}
+		}
+	}
+}
+
+func stateLog(l *task.StateLog) *apitypes.StateLog {
+	return &apitypes.StateLog{
+		State:   l.State.String(),
+		Time:    l.CreatedAt,
+		Comment: l.Comment,
+		Result:  string(l.Result),
+	}
+}
+
+func terminalState(s task.State) bool {
+	return s == task.StateOutputted || s == task.StateFailed
+}
+
+// getProcessedTask returns the last task of the project processed by the dispatcher, 0 if none
+func (s *HttpServer) getProcessedTask(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("project_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+	taskID, err := s.persistence.ProcessedTaskID(projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+		return
+	}
+	c.JSON(http.StatusOK, &apitypes.ProcessedTaskRsp{ProjectID: projectID, TaskID: taskID})
+}
+
 func (s *HttpServer) getCoordinatorConfigInfo(c *gin.Context) {
-	c.JSON(http.StatusOK, s.coordinatorConf)
+	if s.operators == nil || s.projectIDs == nil {
+		c.JSON(http.StatusOK, s.coordinatorConf)
+		return
+	}
+
+	rsp := *s.coordinatorConf
+	ids := s.projectIDs()
+	slices.Sort(ids)
+	for _, id := range ids {
+		if !s.operators.Dedicated(id) {
+			continue
+		}
+		operatorECDSA, operatorED25519, err := s.operators.Signers(id)
+		if err != nil {
+			slog.Error("failed to get project operator signers", "error", err, "project_id", id)
+			continue
+		}
+		po := &apitypes.ProjectOperator{ProjectID: id}
+		po.OperatorETHAddress, po.OperatorSolanaAddress = operatorAddresses(operatorECDSA, operatorED25519)
+		rsp.ProjectOperators = append(rsp.ProjectOperators, po)
+	}
+	c.JSON(http.StatusOK, &rsp)
+}
+
+func operatorAddresses(operatorECDSA signer.ECDSA, operatorED25519 signer.ED25519) (ethAddress, solanaAddress string) {
+	if operatorECDSA != nil {
+		ethAddress = operatorECDSA.Address().String()
+	}
+	if operatorED25519 != nil {
+		solanaAddress = solanacommon.PublicKeyFromBytes(operatorED25519.PublicKey()).String()
+	}
+	return
+}
+
+func (s *HttpServer) adminAuth(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.conf.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.New("invalid admin token")))
+		return
+	}
+	c.Next()
+}
+
+func (s *HttpServer) getBudgetStatus(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("project_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+
+	st := s.budget.Status(projectID)
+	rsp := &apitypes.BudgetStatusRsp{
+		ProjectID: st.ProjectID,
+		GasUsed:   st.GasUsed,
+		FeePaid:   st.FeePaid.String(),
+		GasLimit:  st.GasLimit,
+		Paused:    st.Paused,
+		HeldTasks: st.HeldTasks,
+	}
+	if st.Window > 0 {
+		rsp.Window = st.Window.String()
+	}
+	if st.GasLimit > 0 {
+		rsp.GasRemaining = &st.GasRemaining
+	}
+	if st.FeeLimit != nil {
+		rsp.FeeLimit = st.FeeLimit.String()
+		rsp.FeeRemaining = st.FeeRemaining.String()
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+func (s *HttpServer) resumeBudget(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("project_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+
+	n := s.budget.Resume(projectID)
+	slog.Info("project budget resumed", "project_id", projectID, "resumed_tasks", n)
+	c.JSON(http.StatusOK, &apitypes.ResumeBudgetRsp{ProjectID: projectID, ResumedTasks: n})
 }
@@ -1,59 +1,52 @@
 package api
 
 import (
-	"crypto/ecdsa"
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	. "github.com/agiledragon/gomonkey/v2"
-	solanatypes "github.com/blocto/solana-go-sdk/types"
-	"github.com/ethereum/go-ethereum/common"
+	solanacommon "github.com/blocto/solana-go-sdk/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
 	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/budget"
 	"github.com/machinefi/sprout/cmd/coordinator/config"
+	"github.com/machinefi/sprout/output"
 	"github.com/machinefi/sprout/persistence/postgres"
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
 func TestNewHttpServer(t *testing.T) {
 	r := require.New(t)
 
-	conf := &config.Config{
-		OperatorPriKey:        "privateKey",
-		OperatorPriKeyED25519: "PrivateKeyED25519",
-	}
-	t.Run("InvalidSolanaAddress", func(t *testing.T) {
-		p := NewPatches()
-		defer p.Reset()
-
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
-		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
-		defer func() {
-			r.NotNil(recover())
-		}()
-
-		_ = NewHttpServer(nil, conf)
+	t.Run("WithoutOperator", func(t *testing.T) {
+		s := NewHttpServer(nil, &config.Config{}, nil, nil, nil)
+		r.Empty(s.coordinatorConf.OperatorETHAddress)
+		r.Empty(s.coordinatorConf.OperatorSolanaAddress)
 	})
 
 	t.Run("Success", func(t *testing.T) {
-		p := NewPatches()
-		defer p.Reset()
-
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
-		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
-		p.ApplyFuncReturn(solanatypes.AccountFromHex, solanatypes.Account{PublicKey: [32]byte{1}}, nil)
+		sk, err := crypto.GenerateKey()
+		r.NoError(err)
+		ed25519Signer, err := signer.NewED25519FromHex(hexutil.Encode(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))))
+		r.NoError(err)
 
-		s := NewHttpServer(nil, conf)
-		r.NotNil(s)
+		s := NewHttpServer(nil, &config.Config{}, signer.NewRegistry(signer.NewECDSA(sk), ed25519Signer, nil, nil, nil, nil), nil, nil)
+		r.Equal(crypto.PubkeyToAddress(sk.PublicKey).String(), s.coordinatorConf.OperatorETHAddress)
+		r.Equal(solanacommon.PublicKeyFromBytes(ed25519Signer.PublicKey()).String(), s.coordinatorConf.OperatorSolanaAddress)
 	})
 }
 
@@ -130,6 +123,181 @@ func TestHttpServer_getCoordinatorConfigInfo(t *testing.T) {
 		OperatorETHAddress:     "operatorETHAddress",
 		OperatorSolanaAddress:  "operatorSolanaAddress",
 	}, actualResponse)
+
+	t.Run("ProjectOperators", func(t *testing.T) {
+		sk, err := crypto.GenerateKey()
+		r.NoError(err)
+		projectSK, err := crypto.GenerateKey()
+		r.NoError(err)
+		ks := signer.NewServer("")
+		ks.AddECDSA("project", projectSK)
+		srv := httptest.NewServer(ks)
+		defer srv.Close()
+
+		s.operators = signer.NewRegistry(signer.NewECDSA(sk), nil, signer.NewRemoteResolver(srv.URL, ""),
+			map[uint64]*signer.Identity{2: {ECDSA: "project"}, 3: {ECDSA: "notExist"}}, nil, nil)
+		s.projectIDs = func() []uint64 { return []uint64{3, 2, 1} }
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		s.getCoordinatorConfigInfo(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		actualResponse := &apitypes.CoordinatorConfigRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), &actualResponse))
+		r.Equal([]*apitypes.ProjectOperator{{
+			ProjectID:          2,
+			OperatorETHAddress: crypto.PubkeyToAddress(projectSK.PublicKey).String(),
+		}}, actualResponse.ProjectOperators)
+		r.Empty(s.coordinatorConf.ProjectOperators)
+	})
+}
+
+func TestHttpServer_streamTaskStateLog(t *testing.T) {
+	r := require.New(t)
+
+	s := &HttpServer{
+		persistence: &postgres.Postgres{},
+	}
+	newContext := func(projectID string) (*gin.Context, *httptest.ResponseRecorder, context.CancelFunc) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		ctx, cancel := context.WithCancel(context.Background())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		c.Params = gin.Params{{Key: "project_id", Value: projectID}, {Key: "task_id", Value: "2"}}
+		return c, w, cancel
+	}
+	now := time.Now()
+
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		c, w, cancel := newContext("x")
+		defer cancel()
+
+		s.streamTaskStateLog(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("FailedToFetch", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext("1")
+		defer cancel()
+
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Subscribe", make(<-chan *task.StateLog), func() {})
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Fetch", nil, errors.New(t.Name()))
+		s.streamTaskStateLog(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("EndedByPersistedLog", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext("1")
+		defer cancel()
+
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Subscribe", make(<-chan *task.StateLog), func() {})
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Fetch", []*task.StateLog{
+			{State: task.StateDispatched, CreatedAt: now},
+			{State: task.StateOutputted, CreatedAt: now},
+		}, nil)
+		s.streamTaskStateLog(c)
+		r.Equal(http.StatusOK, w.Code)
+		r.Equal(2, strings.Count(w.Body.String(), "event:"+apitypes.StateLogEvent))
+		r.Contains(w.Body.String(), task.StateOutputted.String())
+	})
+
+	t.Run("EndedByPublishedLog", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext("1")
+		defer cancel()
+
+		ch := make(chan *task.StateLog, 3)
+		ch <- &task.StateLog{State: task.StateDispatched, CreatedAt: now}
+		ch <- &task.StateLog{State: task.StateProved, CreatedAt: now}
+		ch <- &task.StateLog{State: task.StateFailed, CreatedAt: now}
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Subscribe", (<-chan *task.StateLog)(ch), func() {})
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Fetch", []*task.StateLog{
+			{State: task.StateDispatched, CreatedAt: now},
+		}, nil)
+		s.streamTaskStateLog(c)
+		r.Equal(3, strings.Count(w.Body.String(), "event:"+apitypes.StateLogEvent))
+		r.Contains(w.Body.String(), task.StateFailed.String())
+	})
+
+	t.Run("SubscriptionDropped", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext("1")
+		defer cancel()
+
+		ch := make(chan *task.StateLog)
+		close(ch)
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Subscribe", (<-chan *task.StateLog)(ch), func() {})
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Fetch", []*task.StateLog{}, nil)
+		s.streamTaskStateLog(c)
+		r.Equal(0, strings.Count(w.Body.String(), "event:"))
+	})
+
+	t.Run("ClientGone", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, _, cancel := newContext("1")
+		cancel()
+
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Subscribe", make(<-chan *task.StateLog), func() {})
+		p.ApplyMethodReturn(&postgres.Postgres{}, "Fetch", []*task.StateLog{}, nil)
+		s.streamTaskStateLog(c)
+	})
+}
+
+func TestHttpServer_getProcessedTask(t *testing.T) {
+	r := require.New(t)
+
+	s := &HttpServer{persistence: &postgres.Postgres{}}
+
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "project_id", Value: "x"}}
+		s.getProcessedTask(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("FailedToQuery", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&postgres.Postgres{}, "ProcessedTaskID", uint64(0), errors.New(t.Name()))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "project_id", Value: "1"}}
+		s.getProcessedTask(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&postgres.Postgres{}, "ProcessedTaskID", uint64(10), nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "project_id", Value: "1"}}
+		s.getProcessedTask(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.ProcessedTaskRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Equal(&apitypes.ProcessedTaskRsp{ProjectID: 1, TaskID: 10}, rsp)
+	})
 }
 
 func TestHttpServer_getTaskStateLog(t *testing.T) {
@@ -278,3 +446,81 @@ func TestHttpServer_getTaskStateLog(t *testing.T) {
 		}, actualResponse)
 	})
 }
+
+func TestHttpServer_adminBudget(t *testing.T) {
+	r := require.New(t)
+
+	tracker, err := budget.NewTracker(&budget.Config{Default: &budget.LimitConfig{Window: "1h", GasLimit: 10}}, nil)
+	r.NoError(err)
+	s := NewHttpServer(nil, &config.Config{AdminToken: "token"}, nil, nil, tracker)
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/budget/1", nil)
+		req.Header.Set("Authorization", "Bearer invalid")
+		s.engine.ServeHTTP(w, req)
+		r.Equal(http.StatusUnauthorized, w.Code)
+	})
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/budget/a", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		s.engine.ServeHTTP(w, req)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+	t.Run("Status", func(t *testing.T) {
+		tracker.Record(1, &output.Spend{GasUsed: 4})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/budget/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		s.engine.ServeHTTP(w, req)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.BudgetStatusRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Equal(uint64(4), rsp.GasUsed)
+		r.Equal("1h0m0s", rsp.Window)
+		r.NotNil(rsp.GasRemaining)
+		r.Equal(uint64(6), *rsp.GasRemaining)
+		r.False(rsp.Paused)
+	})
+	t.Run("Exhausted", func(t *testing.T) {
+		tracker.Record(1, &output.Spend{GasUsed: 6})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/budget/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		s.engine.ServeHTTP(w, req)
+		r.Equal(http.StatusOK, w.Code)
+		r.Contains(w.Body.String(), `"gasRemaining":0`)
+
+		rsp := &apitypes.BudgetStatusRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Equal(uint64(10), rsp.GasUsed)
+		r.NotNil(rsp.GasRemaining)
+		r.Zero(*rsp.GasRemaining)
+		r.True(rsp.Paused)
+	})
+	t.Run("Resume", func(t *testing.T) {
+		r.True(tracker.Hold(1, func() {}))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/budget/1/resume", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		s.engine.ServeHTTP(w, req)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.ResumeBudgetRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Equal(&apitypes.ResumeBudgetRsp{ProjectID: 1, ResumedTasks: 1}, rsp)
+		r.False(tracker.Status(1).Paused)
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		s := NewHttpServer(nil, &config.Config{}, nil, nil, tracker)
+
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/budget/1", nil))
+		r.Equal(http.StatusNotFound, w.Code)
+	})
+}
@@ -3,31 +3,51 @@ package config
 import (
 	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/budget"
 	"github.com/machinefi/sprout/cmd/internal"
+	"github.com/machinefi/sprout/signer"
+	"github.com/machinefi/sprout/util/archive"
 )
 
 type Config struct {
-	ServiceEndpoint       string `env:"HTTP_SERVICE_ENDPOINT"`
-	DatabaseDSN           string `env:"DATABASE_DSN"`
-	DefaultDatasourceURI  string `env:"DEFAULT_DATASOURCE_URI"`
-	BootNodeMultiAddr     string `env:"BOOTNODE_MULTIADDR"`
-	IoTeXChainID          int    `env:"IOTEX_CHAINID"`
-	ChainEndpoint         string `env:"CHAIN_ENDPOINT,optional"`
-	ProjectContractAddr   string `env:"PROJECT_CONTRACT_ADDRESS,optional"`
-	ProverContractAddr    string `env:"PROVER_CONTRACT_ADDRESS,optional"`
-	IPFSEndpoint          string `env:"IPFS_ENDPOINT"`
-	OperatorPriKey        string `env:"OPERATOR_PRIVATE_KEY,optional"`
-	OperatorPriKeyED25519 string `env:"OPERATOR_PRIVATE_KEY_ED25519,optional"`
-	ProjectFileDir        string `env:"PROJECT_FILE_DIRECTORY,optional"`
-	ProjectCacheDir       string `env:"PROJECT_CACHE_DIRECTORY,optional"`
-	LocalDBDir            string `env:"LOCAL_DB_DIRECTORY,optional"`
-	SchedulerEpoch        uint64 `env:"SCHEDULER_EPOCH,optional"`
-	BeginningBlockNumber  uint64 `env:"BEGINNING_BLOCK_NUMBER,optional"`
-	LogLevel              int    `env:"LOG_LEVEL,optional"`
-	SequencerPubKey       string `env:"SEQUENCER_PUBKEY,optional"`
-	ContractWhitelist     string `env:"CONTRACT_WHITELIST,optional"`
-	env                   string `env:"-"`
+	ServiceEndpoint                  string `env:"HTTP_SERVICE_ENDPOINT"`
+	DatabaseDSN                      string `env:"DATABASE_DSN"`
+	DefaultDatasourceURI             string `env:"DEFAULT_DATASOURCE_URI"`
+	BootNodeMultiAddr                string `env:"BOOTNODE_MULTIADDR"`
+	IoTeXChainID                     int    `env:"IOTEX_CHAINID"`
+	ChainEndpoint                    string `env:"CHAIN_ENDPOINT,optional"`
+	ProjectContractAddr              string `env:"PROJECT_CONTRACT_ADDRESS,optional"`
+	ProverContractAddr               string `env:"PROVER_CONTRACT_ADDRESS,optional"`
+	IPFSEndpoint                     string `env:"IPFS_ENDPOINT"`
+	OperatorPriKey                   string `env:"OPERATOR_PRIVATE_KEY,optional"`
+	OperatorPriKeyED25519            string `env:"OPERATOR_PRIVATE_KEY_ED25519,optional"`
+	OperatorKeystoreFile             string `env:"OPERATOR_KEYSTORE_FILE,optional"`
+	OperatorKeystorePassword         string `env:"OPERATOR_KEYSTORE_PASSWORD,optional"`
+	OperatorRemoteSignerEndpoint     string `env:"OPERATOR_REMOTE_SIGNER_ENDPOINT,optional"`
+	OperatorRemoteSignerToken        string `env:"OPERATOR_REMOTE_SIGNER_TOKEN,optional"`
+	OperatorRemoteSignerKeyID        string `env:"OPERATOR_REMOTE_SIGNER_KEY_ID,optional"`
+	OperatorRemoteSignerKeyIDED25519 string `env:"OPERATOR_REMOTE_SIGNER_KEY_ID_ED25519,optional"`
+	OperatorKeystoreDir              string `env:"OPERATOR_KEYSTORE_DIRECTORY,optional"`
+	ProjectOperatorFile              string `env:"PROJECT_OPERATOR_FILE,optional"`
+	ProjectClaimableOperatorKeys     string `env:"PROJECT_CLAIMABLE_OPERATOR_KEYS,optional"`
+	BudgetFile                       string `env:"BUDGET_FILE,optional"`
+	AdminToken                       string `env:"ADMIN_TOKEN,optional"`
+	ProjectFileDir                   string `env:"PROJECT_FILE_DIRECTORY,optional"`
+	ProjectCacheDir                  string `env:"PROJECT_CACHE_DIRECTORY,optional"`
+	LocalDBDir                       string `env:"LOCAL_DB_DIRECTORY,optional"`
+	SchedulerEpoch                   uint64 `env:"SCHEDULER_EPOCH,optional"`
+	BeginningBlockNumber             uint64 `env:"BEGINNING_BLOCK_NUMBER,optional"`
+	LogLevel                         int    `env:"LOG_LEVEL,optional"`
+	SequencerPubKey                  string `env:"SEQUENCER_PUBKEY,optional"`
+	ContractWhitelist                string `env:"CONTRACT_WHITELIST,optional"`
+	ArchiveURI                       string `env:"ARCHIVE_URI,optional"`
+	RetentionDays                    uint64 `env:"RETENTION_DAYS,optional"`
+	RetentionFile                    string `env:"RETENTION_FILE,optional"`
+	env                              string `env:"-"`
 }
 
 var (
@@ -86,6 +106,105 @@ func (c *Config) Init() error {
 	return nil
 }
 
+// OperatorSigners resolves the operator signers, the remote signer takes precedence over the keystore file, and the
+// keystore file takes precedence over the raw private key. a nil signer means the key is not configured
+func (c *Config) OperatorSigners() (signer.ECDSA, signer.ED25519, error) {
+	var (
+		ecdsaSigner   signer.ECDSA
+		ed25519Signer signer.ED25519
+		err           error
+	)
+	switch {
+	case c.OperatorRemoteSignerEndpoint != "" && c.OperatorRemoteSignerKeyID != "":
+		ecdsaSigner, err = signer.NewRemoteECDSA(c.OperatorRemoteSignerEndpoint, c.OperatorRemoteSignerKeyID, c.OperatorRemoteSignerToken)
+	case c.OperatorKeystoreFile != "":
+		ecdsaSigner, err = signer.NewKeystoreECDSA(c.OperatorKeystoreFile, c.OperatorKeystorePassword)
+	case c.OperatorPriKey != "":
+		ecdsaSigner, err = signer.NewECDSAFromHex(c.OperatorPriKey)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to new operator ecdsa signer")
+	}
+
+	switch {
+	case c.OperatorRemoteSignerEndpoint != "" && c.OperatorRemoteSignerKeyIDED25519 != "":
+		ed25519Signer, err = signer.NewRemoteED25519(c.OperatorRemoteSignerEndpoint, c.OperatorRemoteSignerKeyIDED25519, c.OperatorRemoteSignerToken)
+	case c.OperatorPriKeyED25519 != "":
+		ed25519Signer, err = signer.NewED25519FromHex(c.OperatorPriKeyED25519)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to new operator ed25519 signer")
+	}
+	return ecdsaSigner, ed25519Signer, nil
+}
+
+// OperatorRegistry returns the registry of per project operator signers. the dedicated keys are resolved from the
+// remote signer if configured, otherwise from the keystore directory. projectIdentity is optional, and only the keys
+// listed in ProjectClaimableOperatorKeys, separated by comma, could be configured by projects
+func (c *Config) OperatorRegistry(projectIdentity signer.ProjectIdentity) (*signer.Registry, error) {
+	ecdsaSigner, ed25519Signer, err := c.OperatorSigners()
+	if err != nil {
+		return nil, err
+	}
+
+	var resolver signer.Resolver
+	switch {
+	case c.OperatorRemoteSignerEndpoint != "":
+		resolver = signer.NewRemoteResolver(c.OperatorRemoteSignerEndpoint, c.OperatorRemoteSignerToken)
+	case c.OperatorKeystoreDir != "":
+		resolver = signer.NewKeystoreDir(c.OperatorKeystoreDir, c.OperatorKeystorePassword)
+	}
+
+	var identities map[uint64]*signer.Identity
+	if c.ProjectOperatorFile != "" {
+		if identities, err = signer.LoadIdentities(c.ProjectOperatorFile); err != nil {
+			return nil, err
+		}
+	}
+	return signer.NewRegistry(ecdsaSigner, ed25519Signer, resolver, identities, projectIdentity,
+		strings.Split(c.ProjectClaimableOperatorKeys, ",")), nil
+}
+
+// BudgetTracker returns the project budget tracker persisting the budget state to store, projects are unlimited if
+// the budget file is not configured
+func (c *Config) BudgetTracker(store budget.Store) (*budget.Tracker, error) {
+	if c.BudgetFile == "" {
+		return budget.NewTracker(nil, store)
+	}
+	bc, err := budget.Load(c.BudgetFile)
+	if err != nil {
+		return nil, err
+	}
+	return budget.NewTracker(bc, store)
+}
+
+// Retention returns the retention of task state logs and the store they are archived to, the retention is nil if the
+// state logs of every project are kept forever
+func (c *Config) Retention() (*archive.Retention, archive.Store, error) {
+	var overrides map[string]uint
+	if c.RetentionFile != "" {
+		var err error
+		if overrides, err = archive.LoadRetentionOverrides(c.RetentionFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	retention, err := archive.NewRetention(uint(c.RetentionDays), overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !retention.Enabled() {
+		return nil, nil, nil
+	}
+	if c.ArchiveURI == "" {
+		return nil, nil, errors.New("archive uri is required by retention")
+	}
+	store, err := archive.NewStore(c.ArchiveURI)
+	if err != nil {
+		return nil, nil, err
+	}
+	return retention, store, nil
+}
+
 func (c *Config) Env() string {
 	return c.env
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/agiledragon/gomonkey/v2"
 	"github.com/pkg/errors"
@@ -105,3 +106,89 @@ func TestGet(t *testing.T) {
 		r.Equal(":9001", conf.ServiceEndpoint)
 	})
 }
+
+func TestConfig_OperatorSigners(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		ecdsaSigner, ed25519Signer, err := (&config.Config{}).OperatorSigners()
+		r.NoError(err)
+		r.Nil(ecdsaSigner)
+		r.Nil(ed25519Signer)
+	})
+
+	t.Run("InvalidPrivateKey", func(t *testing.T) {
+		_, _, err := (&config.Config{OperatorPriKey: "any"}).OperatorSigners()
+		r.ErrorContains(err, "ecdsa")
+
+		_, _, err = (&config.Config{OperatorPriKeyED25519: "any"}).OperatorSigners()
+		r.ErrorContains(err, "ed25519")
+	})
+
+	t.Run("RemoteSignerUnavailable", func(t *testing.T) {
+		_, _, err := (&config.Config{
+			OperatorPriKey:               "c47bbade736b0f82788aa6eaa06140cdf41a544707edef944299642e0d708cab",
+			OperatorRemoteSignerEndpoint: "http://127.0.0.1:0",
+			OperatorRemoteSignerKeyID:    "any",
+		}).OperatorSigners()
+		r.ErrorContains(err, "remote signer")
+	})
+
+	t.Run("PrivateKey", func(t *testing.T) {
+		ecdsaSigner, ed25519Signer, err := (&config.Config{
+			OperatorPriKey:        "c47bbade736b0f82788aa6eaa06140cdf41a544707edef944299642e0d708cab",
+			OperatorPriKeyED25519: "fd6ac80f1b9886a6d157cd8e71f842a63c52ebd237cf48fba03ae587e197d511f0b2439ae6da236d26f17f56c68f05d48513cd99b33143fa0b1aec7838ce4276",
+		}).OperatorSigners()
+		r.NoError(err)
+		r.NotNil(ecdsaSigner)
+		r.NotNil(ed25519Signer)
+	})
+}
+
+func TestConfig_OperatorRegistry(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidProjectOperatorFile", func(t *testing.T) {
+		_, err := (&config.Config{ProjectOperatorFile: "/path/not/exist"}).OperatorRegistry(nil)
+		r.ErrorContains(err, "project operator file")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		reg, err := (&config.Config{
+			OperatorPriKey:      "c47bbade736b0f82788aa6eaa06140cdf41a544707edef944299642e0d708cab",
+			OperatorKeystoreDir: t.TempDir(),
+		}).OperatorRegistry(nil)
+		r.NoError(err)
+		ecdsaSigner, _, err := reg.Signers(1)
+		r.NoError(err)
+		r.NotNil(ecdsaSigner)
+	})
+}
+
+func TestConfig_Retention(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidRetentionFile", func(t *testing.T) {
+		_, _, err := (&config.Config{RetentionFile: "/path/not/exist"}).Retention()
+		r.ErrorContains(err, "failed to read retention file")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		retention, store, err := (&config.Config{}).Retention()
+		r.NoError(err)
+		r.Nil(retention)
+		r.Nil(store)
+	})
+
+	t.Run("ArchiveURIRequired", func(t *testing.T) {
+		_, _, err := (&config.Config{RetentionDays: 30}).Retention()
+		r.ErrorContains(err, "archive uri is required")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		retention, store, err := (&config.Config{RetentionDays: 30, ArchiveURI: t.TempDir()}).Retention()
+		r.NoError(err)
+		r.Equal(30*24*time.Hour, retention.Of(1))
+		r.NotNil(store)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/ethereum/go-ethereum/common"
@@ -19,9 +20,16 @@ import (
 	"github.com/machinefi/sprout/persistence/postgres"
 	"github.com/machinefi/sprout/project"
 	"github.com/machinefi/sprout/scheduler"
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task/dispatcher"
 )
 
+// the expired task state logs are checked hourly, and purged by the tasks of a batch
+const (
+	retentionInterval  = time.Hour
+	retentionBatchSize = 1000
+)
+
 func main() {
 	conf, err := config.Get()
 	if err != nil {
@@ -76,16 +84,40 @@ func main() {
 		log.Fatal(errors.Wrap(err, "failed to new project manager"))
 	}
 
-	datasourcePG := datasource.NewPostgres()
+	var projectIdentity signer.ProjectIdentity
+	if !local {
+		projectIdentity = func(projectID uint64) *signer.Identity {
+			return projectOperatorIdentity(contractPersistence.LatestProject(projectID))
+		}
+	}
+	operators, err := conf.OperatorRegistry(projectIdentity)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "failed to new operator registry"))
+	}
+
+	budgetTracker, err := conf.BudgetTracker(persistence)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "failed to new budget tracker"))
+	}
+
+	retention, archiveStore, err := conf.Retention()
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "failed to new retention"))
+	}
+	if retention != nil {
+		go persistence.RunRetention(retentionInterval, retention, archiveStore, retentionBatchSize)
+	}
+
+	datasources := datasource.NewDatasources()
 	var taskDispatcher *dispatcher.Dispatcher
 	if local {
-		taskDispatcher, err = dispatcher.NewLocal(persistence, datasourcePG.New, projectManager, conf.DefaultDatasourceURI,
-			conf.OperatorPriKey, conf.OperatorPriKeyED25519, conf.BootNodeMultiAddr, conf.ContractWhitelist, sequencerPubKey, conf.IoTeXChainID)
+		taskDispatcher, err = dispatcher.NewLocal(persistence, datasources.New, projectManager, conf.DefaultDatasourceURI,
+			operators, budgetTracker, conf.BootNodeMultiAddr, conf.ContractWhitelist, sequencerPubKey, conf.IoTeXChainID)
 	} else {
 		projectOffsets := scheduler.NewProjectEpochOffsets(conf.SchedulerEpoch, contractPersistence.LatestProjects, schedulerNotification)
 
-		taskDispatcher, err = dispatcher.New(persistence, datasourcePG.New, projectManager, conf.DefaultDatasourceURI, conf.BootNodeMultiAddr,
-			conf.OperatorPriKey, conf.OperatorPriKeyED25519, conf.ContractWhitelist, sequencerPubKey, conf.IoTeXChainID,
+		taskDispatcher, err = dispatcher.New(persistence, datasources.New, projectManager, conf.DefaultDatasourceURI, conf.BootNodeMultiAddr,
+			operators, budgetTracker, conf.ContractWhitelist, sequencerPubKey, conf.IoTeXChainID,
 			dispatcherNotification, chainHeadNotification, contractPersistence, projectOffsets)
 	}
 	if err != nil {
@@ -94,7 +126,7 @@ func main() {
 	taskDispatcher.Run()
 
 	go func() {
-		if err := api.NewHttpServer(persistence, conf).Run(conf.ServiceEndpoint); err != nil {
+		if err := api.NewHttpServer(persistence, conf, operators, projectManager.ProjectIDs, budgetTracker).Run(conf.ServiceEndpoint); err != nil {
 			log.Fatal(errors.Wrap(err, "failed to run http server"))
 		}
 	}()
@@ -103,3 +135,18 @@ func main() {
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
 	<-done
 }
+
+// projectOperatorIdentity returns the identity of dedicated operator keys set by the project contract attributes
+func projectOperatorIdentity(p *contract.Project) *signer.Identity {
+	if p == nil {
+		return nil
+	}
+	id := &signer.Identity{
+		ECDSA:   string(p.Attributes[contract.OperatorSigner]),
+		ED25519: string(p.Attributes[contract.OperatorSignerED25519]),
+	}
+	if id.ECDSA == "" && id.ED25519 == "" {
+		return nil
+	}
+	return id
+}
@@ -220,16 +220,26 @@ func runCoordinator(conf *coordinatorconfig.Config) {
 		log.Fatal(err)
 	}
 
-	datasourcePG := datasource.NewPostgres()
+	operators, err := conf.OperatorRegistry(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	taskDispatcher, err := dispatcher.NewLocal(pg, datasourcePG.New, projectManager, conf.DefaultDatasourceURI, conf.OperatorPriKey, conf.OperatorPriKeyED25519, conf.BootNodeMultiAddr, conf.ContractWhitelist, sequencerPubKey, conf.IoTeXChainID)
+	budgetTracker, err := conf.BudgetTracker(pg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	datasources := datasource.NewDatasources()
+
+	taskDispatcher, err := dispatcher.NewLocal(pg, datasources.New, projectManager, conf.DefaultDatasourceURI, operators, budgetTracker, conf.BootNodeMultiAddr, conf.ContractWhitelist, sequencerPubKey, conf.IoTeXChainID)
 	if err != nil {
 		log.Fatal(errors.Wrap(err, "failed to new local dispatcher"))
 	}
 	taskDispatcher.Run()
 
 	go func() {
-		if err := api.NewHttpServer(pg, conf).Run(conf.ServiceEndpoint); err != nil {
+		if err := api.NewHttpServer(pg, conf, operators, projectManager.ProjectIDs, budgetTracker).Run(conf.ServiceEndpoint); err != nil {
 			log.Fatal(err)
 		}
 	}()
@@ -254,13 +264,20 @@ func runSequencer(privateKey, databaseDSN, coordinatorAddress, address, projectC
 		log.Fatal(err)
 	}
 
-	p, err := persistence.NewPersistence(databaseDSN)
+	p, err := persistence.NewPersistence(databaseDSN, 24*time.Hour)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	go func() {
-		if err := seqapi.NewHttpServer(p, uint(1), coordinatorAddress, sk, key, manager).Run(address); err != nil {
+		if err := p.RunListener(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	go func() {
+		aggregation := func(uint64) *persistence.Aggregation { return &persistence.Aggregation{Amount: 1} }
+		if err := seqapi.NewHttpServer(p, aggregation, 1<<20, nil, nil, coordinatorAddress, sk, key, manager).Run(address); err != nil {
 			log.Fatal(err)
 		}
 	}()
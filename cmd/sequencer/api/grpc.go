@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/api/proto"
+)
+
+type GrpcConfig struct {
+	CertFile string // the tls certificate, in pem
+	KeyFile  string // the tls private key, in pem
+	Insecure bool   // allows serving without tls, the plain protobuf requests and responses are exposed on the wire then
+}
+
+// grpcServer serves the sequencer api over grpc, the requests are checked by the same handlers as the http server.
+// the responses are plain protobuf protected by tls, and the cipher rpcs wrap them in didcomm as the http api
+type grpcServer struct {
+	proto.UnimplementedSequencerServer
+	s      *httpServer
+	server *grpc.Server
+}
+
+// NewGrpcServer returns the grpc server, which is served over tls unless insecure is configured
+func NewGrpcServer(s *httpServer, conf *GrpcConfig) (*grpcServer, error) {
+	opts := []grpc.ServerOption{}
+	switch {
+	case conf.CertFile != "" || conf.KeyFile != "":
+		creds, err := credentials.NewServerTLSFromFile(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load grpc tls credentials")
+		}
+		opts = append(opts, grpc.Creds(creds))
+	case !conf.Insecure:
+		return nil, errors.New("grpc tls certificate and key are required unless insecure")
+	}
+
+	g := &grpcServer{s: s}
+	g.server = grpc.NewServer(opts...)
+	proto.RegisterSequencerServer(g.server, g)
+	return g, nil
+}
+
+// this func will block caller
+func (g *grpcServer) Run(address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen %s", address)
+	}
+	if err := g.server.Serve(lis); err != nil {
+		return errors.Wrap(err, "failed to start grpc server")
+	}
+	return nil
+}
+
+// client returns the client of the credential token in the `authorization` metadata, nil if no token
+func (g *grpcServer) client(ctx context.Context) (*clients.Client, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	toks := md.Get("authorization")
+	if len(toks) == 0 || toks[0] == "" {
+		return nil, nil
+	}
+	tok := strings.TrimSpace(strings.Replace(toks[0], "Bearer", " ", 1))
+
+	client, err := g.s.clientByToken(tok)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return client, nil
+}
+
+// cipherClient returns the client of the credential token, the didcomm envelope requires an authenticated client
+func (g *grpcServer) cipherClient(ctx context.Context) (*clients.Client, error) {
+	client, err := g.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, status.Error(codes.Unauthenticated, "credential token is required")
+	}
+	return client, nil
+}
+
+// encrypt returns the json of v encrypted for the client
+func (g *grpcServer) encrypt(client *clients.Client, v any) (*proto.CipherResponse, error) {
+	cipher, err := g.s.jwk.EncryptJSON(v, client.KeyAgreementKID())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt response")
+	}
+	return &proto.CipherResponse{Cipher: cipher}, nil
+}
+
+// peerIP returns the ip of the caller, which is the rate limit key of the client without token
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcError translates the error of shared handlers to grpc status
+func grpcError(err error) error {
+	e := &requestError{}
+	if !errors.As(err, &e) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	var code codes.Code
+	switch e.status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	default:
+		code = codes.Internal
+	}
+	st := status.New(code, e.Error())
+
+	if len(e.fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range e.fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Error})
+		}
+		if withDetails, err := st.WithDetails(br); err == nil {
+			st = withDetails
+		}
+	}
+	if e.rejection != nil {
+		if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(e.rejection.RetryAfter)}); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+func (g *grpcServer) IssueVC(_ context.Context, req *proto.IssueVCRequest) (*proto.IssueVCResponse, error) {
+	cipher, err := g.s.issueToken(req.ClientID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &proto.IssueVCResponse{Cipher: cipher}, nil
+}
+
+func (g *grpcServer) HandleMessage(ctx context.Context, req *proto.HandleMessageRequest) (*proto.HandleMessageResponse, error) {
+	client, err := g.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := g.s.saveMessage(client, rateKey(client, peerIP(ctx)), &apitypes.HandleMessageReq{
+		ProjectID:      req.ProjectID,
+		ProjectVersion: req.ProjectVersion,
+		Data:           req.Data,
+		IdempotencyKey: req.IdempotencyKey,
+		Signature:      req.Signature,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &proto.HandleMessageResponse{MessageID: id}, nil
+}
+
+func (g *grpcServer) QueryMessage(ctx context.Context, req *proto.QueryMessageRequest) (*proto.QueryMessageResponse, error) {
+	client, err := g.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := g.s.messageStateLog(client, req.MessageID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &proto.QueryMessageResponse{MessageID: rsp.MessageID}
+	for _, l := range rsp.States {
+		res.States = append(res.States, stateLog(l))
+	}
+	if i := rsp.Inclusion; i != nil {
+		res.Inclusion = &proto.MessageInclusion{
+			TaskID:        i.TaskID,
+			Root:          i.Root,
+			RootSignature: i.RootSignature,
+			OutputTx:      i.OutputTx,
+		}
+		for _, n := range i.Proof {
+			res.Inclusion.Proof = append(res.Inclusion.Proof, &proto.MerkleProofNode{Hash: n.Hash, Left: n.Left})
+		}
+	}
+	return res, nil
+}
+
+// StreamMessageState sends the states of the message until the task is outputted or failed
+func (g *grpcServer) StreamMessageState(req *proto.QueryMessageRequest, stream proto.Sequencer_StreamMessageStateServer) error {
+	ctx := stream.Context()
+	client, err := g.client(ctx)
+	if err != nil {
+		return err
+	}
+	err = g.s.followMessageState(ctx, client, req.MessageID, func(l *apitypes.StateLog) error {
+		return stream.Send(stateLog(l))
+	})
+	if err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// HandleMessageCipher is HandleMessage in the didcomm envelope, as POST /message of a DIDComm client
+func (g *grpcServer) HandleMessageCipher(ctx context.Context, req *proto.CipherRequest) (*proto.CipherResponse, error) {
+	client, err := g.cipherClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := g.s.jwk.Decrypt(req.Cipher, client.DID())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "failed to decrypt didcomm cipher data").Error())
+	}
+	r := &apitypes.HandleMessageReq{}
+	if err := binding.JSON.BindBody(payload, r); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := g.s.saveMessage(client, rateKey(client, peerIP(ctx)), r)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	rsp, err := g.encrypt(client, &apitypes.HandleMessageRsp{MessageID: id})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rsp, nil
+}
+
+// QueryMessageCipher is QueryMessage in the didcomm envelope, the response is the json of
+// apitypes.QueryMessageStateLogRsp encrypted for the client
+func (g *grpcServer) QueryMessageCipher(ctx context.Context, req *proto.QueryMessageRequest) (*proto.CipherResponse, error) {
+	client, err := g.cipherClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := g.s.messageStateLog(client, req.MessageID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	cipher, err := g.encrypt(client, rsp)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return cipher, nil
+}
+
+// StreamMessageStateCipher is StreamMessageState in the didcomm envelope, every state is the json of apitypes.StateLog
+// encrypted for the client
+func (g *grpcServer) StreamMessageStateCipher(req *proto.QueryMessageRequest, stream proto.Sequencer_StreamMessageStateCipherServer) error {
+	ctx := stream.Context()
+	client, err := g.cipherClient(ctx)
+	if err != nil {
+		return err
+	}
+	err = g.s.followMessageState(ctx, client, req.MessageID, func(l *apitypes.StateLog) error {
+		rsp, err := g.encrypt(client, l)
+		if err != nil {
+			return err
+		}
+		return stream.Send(rsp)
+	})
+	if err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+func (g *grpcServer) DidDoc(context.Context, *proto.DidDocRequest) (*proto.DidDocResponse, error) {
+	if g.s.jwk == nil {
+		return nil, status.Error(codes.FailedPrecondition, "jwk is not config")
+	}
+	doc, err := json.Marshal(g.s.jwk.Doc())
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.Wrap(err, "failed to marshal did doc").Error())
+	}
+	return &proto.DidDocResponse{Document: doc}, nil
+}
+
+func stateLog(l *apitypes.StateLog) *proto.StateLog {
+	return &proto.StateLog{
+		State:   l.State,
+		Time:    timestamppb.New(l.Time),
+		Comment: l.Comment,
+		Result:  l.Result,
+	}
+}
@@ -0,0 +1,493 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/api/proto"
+	"github.com/machinefi/sprout/cmd/sequencer/ratelimit"
+)
+
+type mockStateStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*proto.StateLog
+}
+
+func (s *mockStateStream) Context() context.Context { return s.ctx }
+
+func (s *mockStateStream) Send(l *proto.StateLog) error {
+	s.sent = append(s.sent, l)
+	return nil
+}
+
+type mockCipherStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*proto.CipherResponse
+}
+
+func (s *mockCipherStream) Context() context.Context { return s.ctx }
+
+func (s *mockCipherStream) Send(c *proto.CipherResponse) error {
+	s.sent = append(s.sent, c)
+	return nil
+}
+
+func withToken(tok string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", tok))
+}
+
+func TestNewGrpcServer(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("TLSRequired", func(t *testing.T) {
+		_, err := NewGrpcServer(&httpServer{}, &GrpcConfig{})
+		r.ErrorContains(err, "tls certificate and key are required")
+	})
+
+	t.Run("FailedToLoadCredentials", func(t *testing.T) {
+		_, err := NewGrpcServer(&httpServer{}, &GrpcConfig{CertFile: "not exist", KeyFile: "not exist"})
+		r.ErrorContains(err, "failed to load grpc tls credentials")
+	})
+
+	t.Run("Insecure", func(t *testing.T) {
+		g, err := NewGrpcServer(&httpServer{}, &GrpcConfig{Insecure: true})
+		r.NoError(err)
+		r.NotNil(g.server)
+	})
+}
+
+func TestGrpcServer_Run(t *testing.T) {
+	r := require.New(t)
+
+	g, err := NewGrpcServer(&httpServer{}, &GrpcConfig{Insecure: true})
+	r.NoError(err)
+	r.ErrorContains(g.Run("invalid address"), "failed to listen")
+}
+
+func TestGrpcServer_client(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{}}
+
+	t.Run("NoToken", func(t *testing.T) {
+		client, err := g.client(context.Background())
+		r.NoError(err)
+		r.Nil(client)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "clientByToken", func(string) (*clients.Client, error) {
+			return nil, errors.New(t.Name())
+		})
+		_, err := g.client(withToken("Bearer token"))
+		r.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		var tok string
+		p.ApplyPrivateMethod(g.s, "clientByToken", func(_ *httpServer, t string) (*clients.Client, error) {
+			tok = t
+			return &clients.Client{}, nil
+		})
+		client, err := g.client(withToken("Bearer token"))
+		r.NoError(err)
+		r.NotNil(client)
+		r.Equal("token", tok)
+	})
+}
+
+func TestPeerIP(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("", peerIP(context.Background()))
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9002}})
+	r.Equal("127.0.0.1", peerIP(ctx))
+}
+
+func TestGrpcError(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Internal", func(t *testing.T) {
+		r.Equal(codes.Internal, status.Code(grpcError(errors.New(t.Name()))))
+	})
+
+	t.Run("Codes", func(t *testing.T) {
+		for s, code := range map[int]codes.Code{
+			http.StatusBadRequest:            codes.InvalidArgument,
+			http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+			http.StatusUnauthorized:          codes.Unauthenticated,
+			http.StatusForbidden:             codes.PermissionDenied,
+			http.StatusNotFound:              codes.NotFound,
+			http.StatusTooManyRequests:       codes.ResourceExhausted,
+		} {
+			r.Equal(code, status.Code(grpcError(newRequestError(s, errors.New(t.Name())))))
+		}
+	})
+
+	t.Run("FieldViolations", func(t *testing.T) {
+		err := grpcError(&requestError{status: http.StatusBadRequest, err: errInvalidMessageData, fields: []*apitypes.FieldError{{Field: "a", Error: "required"}}})
+		st := status.Convert(err)
+		r.Equal(errInvalidMessageData.Error(), st.Message())
+		r.Len(st.Details(), 1)
+		br := st.Details()[0].(*errdetails.BadRequest)
+		r.Equal("a", br.FieldViolations[0].Field)
+		r.Equal("required", br.FieldViolations[0].Description)
+	})
+
+	t.Run("RetryInfo", func(t *testing.T) {
+		rej := &ratelimit.Rejection{RetryAfter: time.Minute}
+		st := status.Convert(grpcError(&requestError{status: http.StatusTooManyRequests, err: rej, rejection: rej}))
+		r.Equal(codes.ResourceExhausted, st.Code())
+		r.Len(st.Details(), 1)
+		r.Equal(time.Minute, st.Details()[0].(*errdetails.RetryInfo).RetryDelay.AsDuration())
+	})
+}
+
+func TestGrpcServer_IssueVC(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{}}
+
+	t.Run("NotRegistered", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "issueToken", func(string) ([]byte, error) {
+			return nil, newRequestError(http.StatusForbidden, errors.New(t.Name()))
+		})
+		_, err := g.IssueVC(context.Background(), &proto.IssueVCRequest{ClientID: "client"})
+		r.Equal(codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "issueToken", func(string) ([]byte, error) { return []byte("cipher"), nil })
+		rsp, err := g.IssueVC(context.Background(), &proto.IssueVCRequest{ClientID: "client"})
+		r.NoError(err)
+		r.Equal([]byte("cipher"), rsp.Cipher)
+	})
+}
+
+func TestGrpcServer_HandleMessage(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{}}
+	req := &proto.HandleMessageRequest{ProjectID: 1, ProjectVersion: "v1", Data: "data", IdempotencyKey: "key", Signature: "0x01"}
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "clientByToken", func(string) (*clients.Client, error) {
+			return nil, errors.New(t.Name())
+		})
+		_, err := g.HandleMessage(withToken("token"), req)
+		r.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("EmptyData", func(t *testing.T) {
+		_, err := g.HandleMessage(context.Background(), &proto.HandleMessageRequest{ProjectID: 1, ProjectVersion: "v1"})
+		r.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "saveMessage", func(*clients.Client, string, *apitypes.HandleMessageReq) (string, error) {
+			return "", newRequestError(http.StatusRequestEntityTooLarge, errors.New(t.Name()))
+		})
+		_, err := g.HandleMessage(context.Background(), req)
+		r.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		var (
+			saved apitypes.HandleMessageReq
+			key   string
+		)
+		p.ApplyPrivateMethod(g.s, "saveMessage", func(_ *httpServer, _ *clients.Client, k string, req *apitypes.HandleMessageReq) (string, error) {
+			saved, key = *req, k
+			return "message", nil
+		})
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9002}})
+		rsp, err := g.HandleMessage(ctx, req)
+		r.NoError(err)
+		r.Equal("message", rsp.MessageID)
+		r.Equal("ip:127.0.0.1", key)
+		r.Equal(apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: "data", IdempotencyKey: "key", Signature: "0x01"}, saved)
+	})
+}
+
+func TestGrpcServer_QueryMessage(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{}}
+
+	t.Run("FailedToQuery", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "messageStateLog", func(*clients.Client, string) (*apitypes.QueryMessageStateLogRsp, error) {
+			return nil, newRequestError(http.StatusUnauthorized, errors.New(t.Name()))
+		})
+		_, err := g.QueryMessage(context.Background(), &proto.QueryMessageRequest{MessageID: "message"})
+		r.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		now := time.Now()
+		p.ApplyPrivateMethod(g.s, "messageStateLog", func(*clients.Client, string) (*apitypes.QueryMessageStateLogRsp, error) {
+			return &apitypes.QueryMessageStateLogRsp{
+				MessageID: "message",
+				States:    []*apitypes.StateLog{{State: "received", Time: now}, {State: "outputted", Time: now, Result: "0x02"}},
+				Inclusion: &apitypes.MessageInclusion{
+					TaskID:        3,
+					Root:          "0x03",
+					RootSignature: "0x04",
+					Proof:         []*apitypes.MerkleProofNode{{Hash: "0x05", Left: true}},
+					OutputTx:      "0x02",
+				},
+			}, nil
+		})
+		rsp, err := g.QueryMessage(context.Background(), &proto.QueryMessageRequest{MessageID: "message"})
+		r.NoError(err)
+		r.Equal("message", rsp.MessageID)
+		r.Len(rsp.States, 2)
+		r.Equal("outputted", rsp.States[1].State)
+		r.Equal("0x02", rsp.States[1].Result)
+		r.True(now.Equal(rsp.States[0].Time.AsTime()))
+		r.Equal(uint64(3), rsp.Inclusion.TaskID)
+		r.Equal("0x03", rsp.Inclusion.Root)
+		r.Equal("0x04", rsp.Inclusion.RootSignature)
+		r.Equal("0x02", rsp.Inclusion.OutputTx)
+		r.Len(rsp.Inclusion.Proof, 1)
+		r.Equal("0x05", rsp.Inclusion.Proof[0].Hash)
+		r.True(rsp.Inclusion.Proof[0].Left)
+	})
+}
+
+func TestGrpcServer_StreamMessageState(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{}}
+
+	t.Run("MessageNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "followMessageState", func(context.Context, *clients.Client, string, func(*apitypes.StateLog) error) error {
+			return newRequestError(http.StatusNotFound, errors.New(t.Name()))
+		})
+		err := g.StreamMessageState(&proto.QueryMessageRequest{MessageID: "message"}, &mockStateStream{ctx: context.Background()})
+		r.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "followMessageState", func(_ *httpServer, _ context.Context, _ *clients.Client, _ string, send func(*apitypes.StateLog) error) error {
+			for _, s := range []string{"received", "packed", "outputted"} {
+				if err := send(&apitypes.StateLog{State: s}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		stream := &mockStateStream{ctx: context.Background()}
+		r.NoError(g.StreamMessageState(&proto.QueryMessageRequest{MessageID: "message"}, stream))
+		states := []string{}
+		for _, l := range stream.sent {
+			states = append(states, l.State)
+		}
+		r.Equal([]string{"received", "packed", "outputted"}, states)
+	})
+}
+
+func TestGrpcServer_HandleMessageCipher(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{jwk: &ioconnect.JWK{}}}
+	patchClient := func(p *Patches) {
+		p.ApplyPrivateMethod(g.s, "clientByToken", func(string) (*clients.Client, error) { return &clients.Client{}, nil })
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+	}
+
+	t.Run("NoToken", func(t *testing.T) {
+		_, err := g.HandleMessageCipher(context.Background(), &proto.CipherRequest{})
+		r.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("FailedToDecrypt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchClient(p)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", nil, errors.New(t.Name()))
+		_, err := g.HandleMessageCipher(withToken("token"), &proto.CipherRequest{Cipher: []byte("cipher")})
+		r.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchClient(p)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", []byte("{"), nil)
+		_, err := g.HandleMessageCipher(withToken("token"), &proto.CipherRequest{Cipher: []byte("cipher")})
+		r.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("FailedToEncrypt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchClient(p)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", []byte(`{"projectID": 1, "projectVersion": "v1", "data": "data"}`), nil)
+		p.ApplyPrivateMethod(g.s, "saveMessage", func(*httpServer, *clients.Client, string, *apitypes.HandleMessageReq) (string, error) {
+			return "message", nil
+		})
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", nil, errors.New(t.Name()))
+		_, err := g.HandleMessageCipher(withToken("token"), &proto.CipherRequest{Cipher: []byte("cipher")})
+		r.Equal(codes.Internal, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchClient(p)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", []byte(`{"projectID": 1, "projectVersion": "v1", "data": "data"}`), nil)
+		var saved apitypes.HandleMessageReq
+		p.ApplyPrivateMethod(g.s, "saveMessage", func(_ *httpServer, _ *clients.Client, key string, req *apitypes.HandleMessageReq) (string, error) {
+			saved = *req
+			r.Equal("did", key)
+			return "message", nil
+		})
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			return json.Marshal(v)
+		})
+		rsp, err := g.HandleMessageCipher(withToken("token"), &proto.CipherRequest{Cipher: []byte("cipher")})
+		r.NoError(err)
+		r.Equal(apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: "data"}, saved)
+		r.JSONEq(`{"messageID": "message"}`, string(rsp.Cipher))
+	})
+}
+
+func TestGrpcServer_QueryMessageCipher(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{jwk: &ioconnect.JWK{}}}
+
+	t.Run("NoToken", func(t *testing.T) {
+		_, err := g.QueryMessageCipher(context.Background(), &proto.QueryMessageRequest{MessageID: "message"})
+		r.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "clientByToken", func(string) (*clients.Client, error) { return &clients.Client{}, nil })
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyPrivateMethod(g.s, "messageStateLog", func(*httpServer, *clients.Client, string) (*apitypes.QueryMessageStateLogRsp, error) {
+			return &apitypes.QueryMessageStateLogRsp{MessageID: "message"}, nil
+		})
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			return json.Marshal(v)
+		})
+		rsp, err := g.QueryMessageCipher(withToken("token"), &proto.QueryMessageRequest{MessageID: "message"})
+		r.NoError(err)
+		got := &apitypes.QueryMessageStateLogRsp{}
+		r.NoError(json.Unmarshal(rsp.Cipher, got))
+		r.Equal("message", got.MessageID)
+	})
+}
+
+func TestGrpcServer_StreamMessageStateCipher(t *testing.T) {
+	r := require.New(t)
+
+	g := &grpcServer{s: &httpServer{jwk: &ioconnect.JWK{}}}
+
+	t.Run("NoToken", func(t *testing.T) {
+		err := g.StreamMessageStateCipher(&proto.QueryMessageRequest{}, &mockCipherStream{ctx: context.Background()})
+		r.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(g.s, "clientByToken", func(string) (*clients.Client, error) { return &clients.Client{}, nil })
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyPrivateMethod(g.s, "followMessageState", func(_ *httpServer, _ context.Context, _ *clients.Client, _ string, send func(*apitypes.StateLog) error) error {
+			r.NoError(send(&apitypes.StateLog{State: "received"}))
+			return send(&apitypes.StateLog{State: "packed"})
+		})
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			return json.Marshal(v)
+		})
+		stream := &mockCipherStream{ctx: withToken("token")}
+		r.NoError(g.StreamMessageStateCipher(&proto.QueryMessageRequest{MessageID: "message"}, stream))
+		r.Len(stream.sent, 2)
+		l := &apitypes.StateLog{}
+		r.NoError(json.Unmarshal(stream.sent[1].Cipher, l))
+		r.Equal("packed", l.State)
+	})
+}
+
+func TestGrpcServer_DidDoc(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("JwkNotConfig", func(t *testing.T) {
+		g := &grpcServer{s: &httpServer{}}
+		_, err := g.DidDoc(context.Background(), &proto.DidDocRequest{})
+		r.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Doc", &ioconnect.Doc{ID: "did"})
+		g := &grpcServer{s: &httpServer{jwk: &ioconnect.JWK{}}}
+		rsp, err := g.DidDoc(context.Background(), &proto.DidDocRequest{})
+		r.NoError(err)
+		r.Contains(string(rsp.Document), `"id":"did"`)
+	})
+}
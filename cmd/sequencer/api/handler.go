@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+	"github.com/machinefi/sprout/cmd/sequencer/ratelimit"
+	"github.com/machinefi/sprout/metrics"
+	"github.com/machinefi/sprout/task"
+)
+
+// the handlers below are shared by the http, mqtt and grpc transports, so that they check requests the same way
+
+// requestError is a failed request with its http status, which is translated by the other transports
+type requestError struct {
+	status    int
+	err       error
+	fields    []*apitypes.FieldError // the invalid fields of message data
+	rejection *ratelimit.Rejection
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+func (e *requestError) Unwrap() error { return e.err }
+
+func newRequestError(status int, err error) *requestError {
+	return &requestError{status: status, err: err}
+}
+
+// statusOf returns the http status of err, 500 if err is not a request error
+func statusOf(err error) int {
+	e := &requestError{}
+	if errors.As(err, &e) {
+		return e.status
+	}
+	return http.StatusInternalServerError
+}
+
+// rateKey returns the rate limit key of the client, a client without token is limited by its ip
+func rateKey(client *clients.Client, ip string) string {
+	if client != nil {
+		return client.DID()
+	}
+	return "ip:" + ip
+}
+
+// checkMessage checks the message, including the binding tags of req which are not checked by the mqtt and grpc
+// transports, and returns the message to save. authorize checks the project permission of the authenticated client,
+// the client is nil if not authenticated
+func (s *httpServer) checkMessage(client *clients.Client, req *apitypes.HandleMessageReq, authorize func(*clients.Client, uint64) error) (*persistence.Message, error) {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return nil, newRequestError(http.StatusBadRequest, err)
+	}
+	if err := s.checkDataSize(req); err != nil {
+		return nil, newRequestError(http.StatusRequestEntityTooLarge, err)
+	}
+	if client != nil {
+		if err := authorize(client, req.ProjectID); err != nil {
+			return nil, newRequestError(http.StatusUnauthorized, err)
+		}
+	}
+	if fields := s.validateData(req); len(fields) > 0 {
+		return nil, &requestError{status: http.StatusBadRequest, err: errInvalidMessageData, fields: fields}
+	}
+	signature, err := s.verifySignature(client, req)
+	if err != nil {
+		return nil, newRequestError(http.StatusUnauthorized, err)
+	}
+
+	clientDID := ""
+	if client != nil {
+		clientDID = client.DID()
+	}
+	return &persistence.Message{
+		MessageID:       uuid.NewString(),
+		ClientID:        clientDID,
+		ProjectID:       req.ProjectID,
+		ProjectVersion:  req.ProjectVersion,
+		Data:            []byte(req.Data),
+		IdempotencyKey:  req.IdempotencyKey,
+		DeviceSignature: signature,
+	}, nil
+}
+
+// saveMessage checks and saves the message, and returns the message id. the client is nil if not authenticated
+func (s *httpServer) saveMessage(client *clients.Client, key string, req *apitypes.HandleMessageReq) (string, error) {
+	m, err := s.checkMessage(client, req, s.authorizeProject)
+	if err != nil {
+		return "", err
+	}
+	if rej := s.allowKey(key, client, req.ProjectID, 1); rej != nil {
+		return "", &requestError{status: http.StatusTooManyRequests, err: rej, rejection: rej}
+	}
+
+	id, err := s.p.Save(m, s.aggregation(req.ProjectID), s.privateKey)
+	if err != nil {
+		return "", err
+	}
+	metrics.AcceptedMessageNumMtc(clientLabel(client), req.ProjectID, 1)
+	return id, nil
+}
+
+// issueToken signs the credential token of the registered client, and returns the token encrypted for the client
+func (s *httpServer) issueToken(clientID string) ([]byte, error) {
+	client := s.clients.ClientByIoID(clientID)
+	if client == nil {
+		return nil, newRequestError(http.StatusForbidden, errors.New("client is not register to ioRegistry"))
+	}
+	token, err := s.jwk.SignToken(clientID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign token")
+	}
+	slog.Info("token signed", "token", token)
+	cipher, err := s.jwk.Encrypt([]byte(token), client.KeyAgreementKID())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt")
+	}
+	return cipher, nil
+}
+
+// messageStateLog returns the states of the message, and the inclusion proof once packed
+func (s *httpServer) messageStateLog(client *clients.Client, messageID string) (*apitypes.QueryMessageStateLogRsp, error) {
+	ms, err := s.p.FetchMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ms) == 0 {
+		return &apitypes.QueryMessageStateLogRsp{MessageID: messageID}, nil
+	}
+	m := ms[0]
+
+	if client != nil {
+		if err := s.authorizeMessage(client, m); err != nil {
+			return nil, newRequestError(http.StatusUnauthorized, err)
+		}
+	}
+
+	ss := []*apitypes.StateLog{
+		{
+			State: "received",
+			Time:  m.CreatedAt,
+		},
+	}
+	var inclusion *apitypes.MessageInclusion
+
+	if m.InternalTaskID != "" {
+		ts, err := s.p.FetchTask(m.InternalTaskID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ts) == 0 {
+			return nil, errors.New("cannot find task by internal task id")
+		}
+		ss = append(ss, &apitypes.StateLog{
+			State: task.StatePacked.String(),
+			Time:  ts[0].CreatedAt,
+		})
+		resp, err := http.Get(fmt.Sprintf("http://%s/%s/%d/%d", s.coordinatorAddress, "task", m.ProjectID, ts[0].ID))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		taskStateLog := &apitypes.QueryTaskStateLogRsp{}
+		if err := json.Unmarshal(body, &taskStateLog); err != nil {
+			return nil, err
+		}
+		ss = append(ss, taskStateLog.States...)
+
+		inclusion, err = messageInclusion(m, ts[0], taskStateLog.States)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &apitypes.QueryMessageStateLogRsp{MessageID: messageID, States: ss, Inclusion: inclusion}, nil
+}
+
+// followMessageState calls send with the states of the message: received, packed, and then the task states relayed
+// from the coordinator stream until the task is outputted or failed. it returns nil if ctx is done before packed
+func (s *httpServer) followMessageState(ctx context.Context, client *clients.Client, messageID string, send func(*apitypes.StateLog) error) error {
+	// subscribe before fetching, so the packing in between is not missed
+	packed, cancel := s.p.SubscribePacked(messageID)
+	defer cancel()
+
+	ms, err := s.p.FetchMessage(messageID)
+	if err != nil {
+		return err
+	}
+	if len(ms) == 0 {
+		return newRequestError(http.StatusNotFound, errors.Errorf("message %s not exist", messageID))
+	}
+	m := ms[0]
+
+	if client != nil {
+		if err := s.authorizeMessage(client, m); err != nil {
+			return newRequestError(http.StatusUnauthorized, err)
+		}
+	}
+
+	if err := send(&apitypes.StateLog{State: "received", Time: m.CreatedAt}); err != nil {
+		return err
+	}
+
+	t, err := s.waitPacked(ctx, m, packed)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if err := send(&apitypes.StateLog{State: task.StatePacked.String(), Time: t.CreatedAt}); err != nil {
+		return err
+	}
+
+	return s.followTaskStates(ctx, m.ProjectID, uint64(t.ID), send)
+}
@@ -0,0 +1,379 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+	"github.com/machinefi/sprout/cmd/sequencer/ratelimit"
+)
+
+func TestStatusOf(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(http.StatusInternalServerError, statusOf(errors.New("any")))
+	r.Equal(http.StatusNotFound, statusOf(newRequestError(http.StatusNotFound, errors.New("any"))))
+	r.Equal(http.StatusNotFound, statusOf(errors.Wrap(newRequestError(http.StatusNotFound, errors.New("any")), "wrapped")))
+}
+
+func TestRateKey(t *testing.T) {
+	r := require.New(t)
+
+	p := NewPatches()
+	defer p.Reset()
+
+	p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+	r.Equal("did", rateKey(&clients.Client{}, "127.0.0.1"))
+	r.Equal("ip:127.0.0.1", rateKey(nil, "127.0.0.1"))
+}
+
+func TestHttpServer_saveMessage(t *testing.T) {
+	r := require.New(t)
+
+	newServer := func() *httpServer {
+		return &httpServer{
+			aggregation: func(uint64) *persistence.Aggregation { return &persistence.Aggregation{Amount: 1} },
+			clients:     &clients.Manager{},
+		}
+	}
+	req := &apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: `{"temperature": 1}`}
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		for _, req := range []*apitypes.HandleMessageReq{
+			{ProjectVersion: "v1", Data: "data"},
+			{ProjectID: 1, Data: "data"},
+			{ProjectID: 1, ProjectVersion: "v1"},
+		} {
+			_, err := newServer().saveMessage(&clients.Client{}, "did", req)
+			r.Error(err)
+			r.Equal(http.StatusBadRequest, statusOf(err))
+		}
+	})
+
+	t.Run("DataTooLarge", func(t *testing.T) {
+		s := newServer()
+		s.maxDataSize = 1
+		_, err := s.saveMessage(&clients.Client{}, "did", req)
+		r.ErrorContains(err, "exceeds 1 bytes")
+		r.Equal(http.StatusRequestEntityTooLarge, statusOf(err))
+	})
+
+	t.Run("NoPermission", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", false, nil)
+		_, err := newServer().saveMessage(&clients.Client{}, "did", req)
+		r.ErrorContains(err, "no permission project 1 for did")
+		r.Equal(http.StatusUnauthorized, statusOf(err))
+	})
+
+	t.Run("InvalidData", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		s := newServer()
+		s.messageSchema = testMessageSchema
+		_, err := s.saveMessage(&clients.Client{}, "did", &apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: `{}`})
+		e := &requestError{}
+		r.ErrorAs(err, &e)
+		r.Equal(http.StatusBadRequest, e.status)
+		r.ErrorIs(err, errInvalidMessageData)
+		r.Len(e.fields, 1)
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		_, err := newServer().saveMessage(&clients.Client{}, "did", &apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: "data", Signature: "invalid"})
+		r.ErrorContains(err, "failed to decode message signature")
+		r.Equal(http.StatusUnauthorized, statusOf(err))
+	})
+
+	t.Run("IdempotencyKeyTooLong", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		_, err := newServer().saveMessage(&clients.Client{}, "did", &apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: "data", IdempotencyKey: strings.Repeat("a", 129)})
+		r.ErrorContains(err, "IdempotencyKey")
+		r.Equal(http.StatusBadRequest, statusOf(err))
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		limiter, err := ratelimit.NewLimiter(&ratelimit.Config{Client: &ratelimit.Limit{DailyQuota: 1}}, nil, nil)
+		r.NoError(err)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", "message", nil)
+
+		s := newServer()
+		s.limiter = limiter
+		id, err := s.saveMessage(&clients.Client{}, "did", req)
+		r.NoError(err)
+		r.Equal("message", id)
+
+		_, err = s.saveMessage(&clients.Client{}, "did", req)
+		r.ErrorContains(err, "exceeds daily quota")
+		e := &requestError{}
+		r.ErrorAs(err, &e)
+		r.Equal(http.StatusTooManyRequests, e.status)
+		r.NotNil(e.rejection)
+	})
+
+	t.Run("FailedToSave", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", "", errors.New(t.Name()))
+		_, err := newServer().saveMessage(&clients.Client{}, "did", req)
+		r.ErrorContains(err, t.Name())
+		r.Equal(http.StatusInternalServerError, statusOf(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		var saved persistence.Message
+		p.ApplyMethod(&persistence.Persistence{}, "Save", func(_ *persistence.Persistence, msg *persistence.Message, _ *persistence.Aggregation, _ *ecdsa.PrivateKey) (string, error) {
+			saved = *msg
+			return msg.MessageID, nil
+		})
+		id, err := newServer().saveMessage(&clients.Client{}, "did", req)
+		r.NoError(err)
+		r.Equal(saved.MessageID, id)
+		r.Equal("did", saved.ClientID)
+		r.Equal(uint64(1), saved.ProjectID)
+		r.Equal("v1", saved.ProjectVersion)
+	})
+}
+
+func TestHttpServer_issueToken(t *testing.T) {
+	r := require.New(t)
+
+	s := &httpServer{clients: &clients.Manager{}, jwk: &ioconnect.JWK{}}
+
+	t.Run("ClientNotRegistered", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Manager{}, "ClientByIoID", (*clients.Client)(nil))
+		_, err := s.issueToken("client")
+		r.ErrorContains(err, "client is not register to ioRegistry")
+		r.Equal(http.StatusForbidden, statusOf(err))
+	})
+
+	t.Run("FailedToSignToken", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Manager{}, "ClientByIoID", &clients.Client{})
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "SignToken", "", errors.New(t.Name()))
+		_, err := s.issueToken("client")
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToEncrypt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Manager{}, "ClientByIoID", &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "SignToken", "token", nil)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Encrypt", nil, errors.New(t.Name()))
+		_, err := s.issueToken("client")
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Manager{}, "ClientByIoID", &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "SignToken", "token", nil)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Encrypt", []byte("cipher"), nil)
+		cipher, err := s.issueToken("client")
+		r.NoError(err)
+		r.Equal([]byte("cipher"), cipher)
+	})
+}
+
+func TestHttpServer_messageStateLog(t *testing.T) {
+	r := require.New(t)
+
+	s := &httpServer{clients: &clients.Manager{}}
+
+	t.Run("FailedToFetchMessage", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", nil, errors.New(t.Name()))
+		_, err := s.messageStateLog(nil, "message")
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("MessageNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{}, nil)
+		rsp, err := s.messageStateLog(nil, "message")
+		r.NoError(err)
+		r.Equal(&apitypes.QueryMessageStateLogRsp{MessageID: "message"}, rsp)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ClientID: "other"}}, nil)
+		_, err := s.messageStateLog(&clients.Client{}, "message")
+		r.ErrorContains(err, "unmatched client DID")
+		r.Equal(http.StatusUnauthorized, statusOf(err))
+	})
+
+	t.Run("NotPacked", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message"}}, nil)
+		rsp, err := s.messageStateLog(nil, "message")
+		r.NoError(err)
+		r.Len(rsp.States, 1)
+		r.Equal("received", rsp.States[0].State)
+		r.Nil(rsp.Inclusion)
+	})
+
+	t.Run("TaskNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", InternalTaskID: "task"}}, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchTask", []*persistence.Task{}, nil)
+		_, err := s.messageStateLog(nil, "message")
+		r.ErrorContains(err, "cannot find task by internal task id")
+	})
+}
+
+func TestHttpServer_followMessageState(t *testing.T) {
+	r := require.New(t)
+
+	coordinator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/task/1/3/stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event:state\ndata:{\"state\":\"dispatched\"}\n\nevent:state\ndata:{\"state\":\"outputted\"}\n\n"))
+	}))
+	defer coordinator.Close()
+
+	s := &httpServer{coordinatorAddress: strings.TrimPrefix(coordinator.URL, "http://"), clients: &clients.Manager{}}
+	patchSubscribe := func(p *Patches) {
+		p.ApplyMethodReturn(&persistence.Persistence{}, "SubscribePacked", (<-chan *persistence.Task)(nil), func() {})
+	}
+
+	t.Run("FailedToFetchMessage", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchSubscribe(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", nil, errors.New(t.Name()))
+		r.ErrorContains(s.followMessageState(context.Background(), nil, "message", nil), t.Name())
+	})
+
+	t.Run("MessageNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchSubscribe(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{}, nil)
+		err := s.followMessageState(context.Background(), nil, "message", nil)
+		r.ErrorContains(err, "message message not exist")
+		r.Equal(http.StatusNotFound, statusOf(err))
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchSubscribe(p)
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ClientID: "other"}}, nil)
+		err := s.followMessageState(context.Background(), &clients.Client{}, "message", nil)
+		r.ErrorContains(err, "unmatched client DID")
+		r.Equal(http.StatusUnauthorized, statusOf(err))
+	})
+
+	t.Run("NotPackedBeforeDone", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchSubscribe(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message"}}, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		states := []string{}
+		r.NoError(s.followMessageState(ctx, nil, "message", func(l *apitypes.StateLog) error {
+			states = append(states, l.State)
+			return nil
+		}))
+		r.Equal([]string{"received"}, states)
+	})
+
+	t.Run("FailedToSend", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchSubscribe(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ProjectID: 1, InternalTaskID: "task"}}, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchTask", []*persistence.Task{{Model: gorm.Model{ID: 3}}}, nil)
+		r.ErrorContains(s.followMessageState(context.Background(), nil, "message", func(*apitypes.StateLog) error {
+			return errors.New(t.Name())
+		}), t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchSubscribe(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ProjectID: 1, InternalTaskID: "task"}}, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchTask", []*persistence.Task{{Model: gorm.Model{ID: 3}}}, nil)
+		states := []string{}
+		r.NoError(s.followMessageState(context.Background(), nil, "message", func(l *apitypes.StateLog) error {
+			states = append(states, l.State)
+			return nil
+		}))
+		r.Equal([]string{"received", "packed", "dispatched", "outputted"}, states)
+	})
+}
@@ -1,42 +1,69 @@
 package api
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
-	"github.com/google/uuid"
 	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/machinefi/sprout/apitypes"
 	"github.com/machinefi/sprout/clients"
 	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+	"github.com/machinefi/sprout/cmd/sequencer/ratelimit"
+	"github.com/machinefi/sprout/metrics"
+	"github.com/machinefi/sprout/project"
 	"github.com/machinefi/sprout/task"
 )
 
+const (
+	// the max amount of messages in a batch request
+	maxBatchMessages = 1000
+	// the header of idempotency key, the key is scoped to the client and the project
+	idempotencyKeyHeader = "Idempotency-Key"
+	// the default page size of listing messages and tasks
+	defaultListLimit = 100
+)
+
+var (
+	errInvalidMessageData = errors.New("invalid message data")
+	errUnsignedClient     = errors.New("message signature requires an authenticated client")
+)
+
+// MessageSchema returns the message schema declared by the project version, nil if not declared
+type MessageSchema func(projectID uint64, projectVersion string) *project.MessageSchema
+
 type httpServer struct {
 	engine             *gin.Engine
 	p                  *persistence.Persistence
 	coordinatorAddress string
-	aggregationAmount  uint
+	aggregation        persistence.AggregationPolicy
+	maxDataSize        int
+	messageSchema      MessageSchema
+	limiter            *ratelimit.Limiter
 	privateKey         *ecdsa.PrivateKey
 	jwk                *ioconnect.JWK
 	clients            *clients.Manager
 }
 
-func NewHttpServer(p *persistence.Persistence, aggregationAmount uint, coordinatorAddress string, sk *ecdsa.PrivateKey, jwk *ioconnect.JWK, clientMgr *clients.Manager) *httpServer {
+func NewHttpServer(p *persistence.Persistence, aggregation persistence.AggregationPolicy, maxDataSize int, messageSchema MessageSchema, limiter *ratelimit.Limiter, coordinatorAddress string, sk *ecdsa.PrivateKey, jwk *ioconnect.JWK, clientMgr *clients.Manager) *httpServer {
 	s := &httpServer{
 		engine:             gin.Default(),
 		p:                  p,
 		coordinatorAddress: coordinatorAddress,
-		aggregationAmount:  aggregationAmount,
+		aggregation:        aggregation,
+		maxDataSize:        maxDataSize,
+		messageSchema:      messageSchema,
+		limiter:            limiter,
 		privateKey:         sk,
 		jwk:                jwk,
 		clients:            clientMgr,
@@ -52,8 +79,13 @@ func NewHttpServer(p *persistence.Persistence, aggregationAmount uint, coordinat
 
 	s.engine.POST("/issue_vc", s.issueJWTCredential)
 	s.engine.POST("/message", s.verifyToken, s.handleMessage)
+	s.engine.POST("/messages", s.verifyToken, s.handleMessages)
 	s.engine.GET("/message/:id", s.verifyToken, s.queryStateLogByID)
+	s.engine.GET("/message/:id/stream", s.verifyToken, s.streamMessageState)
+	s.engine.GET("/messages", s.verifyToken, s.queryMessages)
+	s.engine.GET("/tasks", s.verifyToken, s.queryTasks)
 	s.engine.GET("/didDoc", s.didDoc)
+	s.engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	return s
 }
@@ -79,14 +111,9 @@ func (s *httpServer) verifyToken(c *gin.Context) {
 
 	tok = strings.TrimSpace(strings.Replace(tok, "Bearer", " ", 1))
 
-	clientID, err := s.jwk.VerifyToken(tok)
+	client, err := s.clientByToken(tok)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.Wrap(err, "invalid credential token")))
-		return
-	}
-	client := s.clients.ClientByIoID(clientID)
-	if client == nil {
-		c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.New("invalid credential token")))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, apitypes.NewErrRsp(err))
 		return
 	}
 
@@ -94,6 +121,19 @@ func (s *httpServer) verifyToken(c *gin.Context) {
 	c.Request = c.Request.WithContext(ctx)
 }
 
+// clientByToken returns the client of the credential token issued by sequencer
+func (s *httpServer) clientByToken(tok string) (*clients.Client, error) {
+	clientID, err := s.jwk.VerifyToken(tok)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid credential token")
+	}
+	client := s.clients.ClientByIoID(clientID)
+	if client == nil {
+		return nil, errors.New("invalid credential token")
+	}
+	return client, nil
+}
+
 func (s *httpServer) handleMessage(c *gin.Context) {
 	req := &apitypes.HandleMessageReq{}
 
@@ -120,34 +160,13 @@ func (s *httpServer) handleMessage(c *gin.Context) {
 		return
 	}
 
-	// validate project permission
-	if client != nil {
-		approved, err := s.clients.HasProjectPermission(client.DID(), req.ProjectID)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.Wrapf(err, "failed to check project %d permission for %s", req.ProjectID, client.DID())))
-			return
-		}
-		if !approved {
-			c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.Errorf("no permission project %d for %s", req.ProjectID, client.DID())))
-			return
-		}
-	}
-
-	clientDID := ""
-	if client != nil {
-		clientDID = client.DID()
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = c.GetHeader(idempotencyKeyHeader)
 	}
 
-	// execute task committing
-	id := uuid.NewString()
-	if err := s.p.Save(&persistence.Message{
-		MessageID:      id,
-		ClientID:       clientDID,
-		ProjectID:      req.ProjectID,
-		ProjectVersion: req.ProjectVersion,
-		Data:           []byte(req.Data),
-	}, s.aggregationAmount, s.privateKey); err != nil {
-		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+	id, err := s.saveMessage(client, rateKey(client, c.ClientIP()), req)
+	if err != nil {
+		writeError(c, err)
 		return
 	}
 
@@ -167,85 +186,417 @@ func (s *httpServer) handleMessage(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-func (s *httpServer) queryStateLogByID(c *gin.Context) {
-	messageID := c.Param("id")
+// checkDataSize checks the message data against the max data size, 0 means no limit
+func (s *httpServer) checkDataSize(req *apitypes.HandleMessageReq) error {
+	if s.maxDataSize > 0 && len(req.Data) > s.maxDataSize {
+		return errors.Errorf("message data size %d exceeds %d bytes", len(req.Data), s.maxDataSize)
+	}
+	return nil
+}
+
+// validateData validates the message data against the project message schema, and returns the field errors
+func (s *httpServer) validateData(req *apitypes.HandleMessageReq) []*apitypes.FieldError {
+	if s.messageSchema == nil {
+		return nil
+	}
+	schema := s.messageSchema(req.ProjectID, req.ProjectVersion)
+	if schema == nil {
+		return nil
+	}
+	errs := schema.Validate([]byte(req.Data))
+	fields := make([]*apitypes.FieldError, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, &apitypes.FieldError{Field: e.Field, Error: e.Error})
+	}
+	return fields
+}
 
-	ms, err := s.p.FetchMessage(messageID)
+// verifySignature verifies the device signature of message data by the client DID document, and returns the json of
+// the verified signature, nil if the message is unsigned
+func (s *httpServer) verifySignature(client *clients.Client, req *apitypes.HandleMessageReq) ([]byte, error) {
+	if req.Signature == "" {
+		return nil, nil
+	}
+	if client == nil {
+		return nil, errUnsignedClient
+	}
+	sig, err := hexutil.Decode(req.Signature)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
-		return
+		return nil, errors.Wrap(err, "failed to decode message signature")
+	}
+	ds, err := client.VerifySignature([]byte(req.Data), sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to verify message signature")
+	}
+	j, err := json.Marshal(ds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal message signature")
+	}
+	return j, nil
+}
+
+// splitMessages splits the batch payload, which is either a json array or newline delimited json
+func splitMessages(payload []byte) ([]json.RawMessage, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) > 0 && payload[0] == '[' {
+		items := []json.RawMessage{}
+		if err := json.Unmarshal(payload, &items); err != nil {
+			return nil, errors.Wrap(err, "failed to decode message array")
+		}
+		return items, nil
 	}
-	if len(ms) == 0 {
-		c.JSON(http.StatusOK, &apitypes.QueryMessageStateLogRsp{MessageID: messageID})
+
+	items := []json.RawMessage{}
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		items = append(items, line)
+	}
+	return items, nil
+}
+
+func (s *httpServer) handleMessages(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.Wrap(err, "failed to read request body")))
 		return
 	}
-	m := ms[0]
+	defer c.Request.Body.Close()
 
+	// decrypt did comm batch envelope
 	client := clients.ClientIDFrom(c.Request.Context())
 	if client != nil {
-		if m.ClientID != client.DID() {
-			c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.New("unmatched client DID")))
-			return
-		}
-		approved, err := s.clients.HasProjectPermission(client.DID(), m.ProjectID)
+		payload, err = s.jwk.Decrypt(payload, client.DID())
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.Wrapf(err, "failed to check project %d permission for %s", m.ProjectID, client.DID())))
+			c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(errors.Wrap(err, "failed to decrypt didcomm cipher data")))
 			return
 		}
-		if !approved {
-			c.JSON(http.StatusUnauthorized, apitypes.NewErrRsp(errors.Errorf("no permission project %d for %s", m.ProjectID, client.DID())))
-			return
+	}
+
+	items, err := splitMessages(payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(errors.New("empty message batch")))
+		return
+	}
+	if len(items) > maxBatchMessages {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(errors.Errorf("too many messages in batch, max %d", maxBatchMessages)))
+		return
+	}
+
+	// the project permission is checked once per project
+	permissions := map[uint64]error{}
+	authorize := func(client *clients.Client, projectID uint64) error {
+		err, ok := permissions[projectID]
+		if !ok {
+			err = s.authorizeProject(client, projectID)
+			permissions[projectID] = err
+		}
+		return err
+	}
+
+	results := make([]*apitypes.HandleMessagesItem, 0, len(items))
+	msgs := make([]*persistence.Message, 0, len(items))
+	saved := make([]*apitypes.HandleMessagesItem, 0, len(items)) // the results of msgs
+	for _, item := range items {
+		req := &apitypes.HandleMessageReq{}
+		if err := binding.JSON.BindBody(item, req); err != nil {
+			results = append(results, &apitypes.HandleMessagesItem{Error: err.Error()})
+			continue
+		}
+		m, err := s.checkMessage(client, req, authorize)
+		if err != nil {
+			result := &apitypes.HandleMessagesItem{Error: err.Error()}
+			if e := (&requestError{}); errors.As(err, &e) {
+				result.Fields = e.fields
+			}
+			results = append(results, result)
+			continue
 		}
+
+		msgs = append(msgs, m)
+		result := &apitypes.HandleMessagesItem{}
+		results = append(results, result)
+		saved = append(saved, result)
 	}
 
-	ss := []*apitypes.StateLog{
-		{
-			State: "received",
-			Time:  m.CreatedAt,
-		},
+	// the batch is rejected as a whole only if all the valid messages are rate limited
+	allowed, saved, rej := s.allowBatch(c, client, msgs, saved)
+	if rej != nil {
+		if len(allowed) == 0 {
+			rejectLimited(c, rej)
+			return
+		}
+		setRetryAfter(c, rej.RetryAfter)
 	}
 
-	if m.InternalTaskID != "" {
-		ts, err := s.p.FetchTask(m.InternalTaskID)
+	if len(allowed) > 0 {
+		ids, err := s.p.SaveBatch(allowed, s.aggregation, s.privateKey)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
 			return
 		}
-		if len(ts) == 0 {
-			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.New("cannot find task by internal task id")))
-			return
+		for i, id := range ids {
+			saved[i].MessageID = id
 		}
-		ss = append(ss, &apitypes.StateLog{
-			State: task.StatePacked.String(),
-			Time:  ts[0].CreatedAt,
-		})
-		resp, err := http.Get(fmt.Sprintf("http://%s/%s/%d/%d", s.coordinatorAddress, "task", m.ProjectID, ts[0].ID))
+		for _, m := range allowed {
+			metrics.AcceptedMessageNumMtc(clientLabel(client), m.ProjectID, 1)
+		}
+	}
+
+	response := &apitypes.HandleMessagesRsp{Messages: results}
+
+	if client != nil {
+		cipher, err := s.jwk.EncryptJSON(response, client.KeyAgreementKID())
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.Wrap(err, "failed to encrypt response when commit messages")))
 			return
 		}
-		defer resp.Body.Close()
+		c.Data(http.StatusOK, "application/octet-stream", cipher)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *httpServer) queryStateLogByID(c *gin.Context) {
+	client := clients.ClientIDFrom(c.Request.Context())
+	response, err := s.messageStateLog(client, c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if len(response.States) == 0 {
+		c.JSON(http.StatusOK, response)
+		return
+	}
 
-		body, err := io.ReadAll(resp.Body)
+	if client != nil {
+		slog.Info("encrypt response task query", "response", response)
+		cipher, err := s.jwk.EncryptJSON(response, client.KeyAgreementKID())
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.Wrap(err, "failed to encrypt response when query task")))
 			return
 		}
-		taskStateLog := &apitypes.QueryTaskStateLogRsp{}
-		if err := json.Unmarshal(body, &taskStateLog); err != nil {
-			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+		c.Data(http.StatusOK, "application/octet-stream", cipher)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// messageInclusion returns the inclusion proof of the packed message, nil if the task is committed without merkle root
+func messageInclusion(m *persistence.Message, t *persistence.Task, states []*apitypes.StateLog) (*apitypes.MessageInclusion, error) {
+	if t.MerkleRoot == "" {
+		return nil, nil
+	}
+	nodes, err := t.MessageProof(m.MessageID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build message merkle proof, message_id %s", m.MessageID)
+	}
+	proof := make([]*apitypes.MerkleProofNode, 0, len(nodes))
+	for _, n := range nodes {
+		proof = append(proof, &apitypes.MerkleProofNode{Hash: n.Hash.Hex(), Left: n.Left})
+	}
+	inclusion := &apitypes.MessageInclusion{
+		TaskID:        uint64(t.ID),
+		Root:          t.MerkleRoot,
+		RootSignature: t.RootSignature,
+		Proof:         proof,
+	}
+	for _, s := range states {
+		if s.State == task.StateOutputted.String() {
+			inclusion.OutputTx = s.Result
+		}
+	}
+	return inclusion, nil
+}
+
+// authorizeMessage checks the message is sent by the client to a permitted project
+func (s *httpServer) authorizeMessage(client *clients.Client, m *persistence.Message) error {
+	if m.ClientID != client.DID() {
+		return errors.New("unmatched client DID")
+	}
+	return s.authorizeProject(client, m.ProjectID)
+}
+
+// authorizeProject checks the client is permitted to send messages to the project
+func (s *httpServer) authorizeProject(client *clients.Client, projectID uint64) error {
+	approved, err := s.clients.HasProjectPermission(client.DID(), projectID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check project %d permission for %s", projectID, client.DID())
+	}
+	if !approved {
+		return errors.Errorf("no permission project %d for %s", projectID, client.DID())
+	}
+	return nil
+}
+
+// authorizeListing checks the client could list the messages of the project, a client is only allowed to list its own
+// messages of a permitted project, and an anonymous request is not allowed. returns the http status if not allowed
+func (s *httpServer) authorizeListing(client *clients.Client, clientID string, projectID uint64) (int, error) {
+	if client == nil {
+		return http.StatusUnauthorized, errors.New("credential token is required")
+	}
+	if clientID != "" && clientID != client.DID() {
+		return http.StatusUnauthorized, errors.New("unmatched client DID")
+	}
+	if projectID == 0 {
+		return http.StatusBadRequest, errors.New("project id is required")
+	}
+	approved, err := s.clients.HasProjectPermission(client.DID(), projectID)
+	if err != nil {
+		return http.StatusUnauthorized, errors.Wrapf(err, "failed to check project %d permission for %s", projectID, client.DID())
+	}
+	if !approved {
+		return http.StatusUnauthorized, errors.Errorf("no permission project %d for %s", projectID, client.DID())
+	}
+	return http.StatusOK, nil
+}
+
+func (s *httpServer) queryMessages(c *gin.Context) {
+	req := &apitypes.QueryMessagesReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+
+	client := clients.ClientIDFrom(c.Request.Context())
+	if code, err := s.authorizeListing(client, req.ClientID, req.ProjectID); err != nil {
+		c.JSON(code, apitypes.NewErrRsp(err))
+		return
+	}
+	req.ClientID = client.DID()
+
+	f := &persistence.MessageFilter{
+		ClientID:       req.ClientID,
+		ProjectID:      req.ProjectID,
+		ProjectVersion: req.ProjectVersion,
+		Since:          req.Since,
+		Until:          req.Until,
+		After:          uint(req.After),
+		Limit:          req.Limit,
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultListLimit
+	}
+	if req.Status != "" {
+		packed := req.Status == "packed"
+		f.Packed = &packed
+	}
+
+	ms, err := s.p.ListMessages(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+		return
+	}
+	data, err := s.p.MessageData(ms)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+		return
+	}
+
+	response := &apitypes.QueryMessagesRsp{Messages: make([]*apitypes.MessageItem, 0, len(ms))}
+	for i, m := range ms {
+		response.Messages = append(response.Messages, &apitypes.MessageItem{
+			ID:             uint64(m.ID),
+			MessageID:      m.MessageID,
+			ClientID:       m.ClientID,
+			ProjectID:      m.ProjectID,
+			ProjectVersion: m.ProjectVersion,
+			Data:           string(data[i]),
+			Packed:         m.InternalTaskID != "",
+			InternalTaskID: m.InternalTaskID,
+			CreatedAt:      m.CreatedAt,
+		})
+	}
+	if len(ms) == f.Limit {
+		response.Next = uint64(ms[len(ms)-1].ID)
+	}
+
+	s.writeResponse(c, client, response)
+}
+
+func (s *httpServer) queryTasks(c *gin.Context) {
+	req := &apitypes.QueryTasksReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, apitypes.NewErrRsp(err))
+		return
+	}
+
+	client := clients.ClientIDFrom(c.Request.Context())
+	if code, err := s.authorizeListing(client, req.ClientID, req.ProjectID); err != nil {
+		c.JSON(code, apitypes.NewErrRsp(err))
+		return
+	}
+	req.ClientID = client.DID()
+
+	f := &persistence.TaskFilter{
+		ClientID:  req.ClientID,
+		ProjectID: req.ProjectID,
+		Since:     req.Since,
+		Until:     req.Until,
+		After:     uint(req.After),
+		Limit:     req.Limit,
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultListLimit
+	}
+
+	ts, err := s.p.ListTasks(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+		return
+	}
+
+	response := &apitypes.QueryTasksRsp{Tasks: make([]*apitypes.TaskItem, 0, len(ts))}
+	for _, t := range ts {
+		messageIDs := []string{}
+		if err := json.Unmarshal(t.MessageIDs, &messageIDs); err != nil {
+			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.Wrapf(err, "failed to unmarshal task message ids, task_id %d", t.ID)))
 			return
 		}
-		ss = append(ss, taskStateLog.States...)
+		response.Tasks = append(response.Tasks, &apitypes.TaskItem{
+			TaskID:         uint64(t.ID),
+			ProjectID:      t.ProjectID,
+			InternalTaskID: t.InternalTaskID,
+			MessageIDs:     messageIDs,
+			CreatedAt:      t.CreatedAt,
+		})
 	}
+	if len(ts) == f.Limit {
+		response.Next = uint64(ts[len(ts)-1].ID)
+	}
+
+	s.writeResponse(c, client, response)
+}
 
-	response := &apitypes.QueryMessageStateLogRsp{MessageID: messageID, States: ss}
+// writeError writes the error response with the status of err
+func writeError(c *gin.Context, err error) {
+	e := &requestError{}
+	switch {
+	case !errors.As(err, &e):
+		c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(err))
+	case e.rejection != nil:
+		rejectLimited(c, e.rejection)
+	case len(e.fields) > 0:
+		c.JSON(e.status, &apitypes.ErrRsp{Error: e.Error(), Fields: e.fields})
+	default:
+		c.JSON(e.status, apitypes.NewErrRsp(e.err))
+	}
+}
 
+// writeResponse writes the response, which is encrypted for the client if the request is authenticated
+func (s *httpServer) writeResponse(c *gin.Context, client *clients.Client, response any) {
 	if client != nil {
-		slog.Info("encrypt response task query", "response", response)
 		cipher, err := s.jwk.EncryptJSON(response, client.KeyAgreementKID())
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.Wrap(err, "failed to encrypt response when query task")))
+			c.JSON(http.StatusInternalServerError, apitypes.NewErrRsp(errors.Wrap(err, "failed to encrypt response")))
 			return
 		}
 		c.Data(http.StatusOK, "application/octet-stream", cipher)
@@ -270,22 +621,9 @@ func (s *httpServer) issueJWTCredential(c *gin.Context) {
 		return
 	}
 
-	client := s.clients.ClientByIoID(req.ClientID)
-	if client == nil {
-		c.String(http.StatusForbidden, errors.Errorf("client is not register to ioRegistry").Error())
-		return
-	}
-
-	token, err := s.jwk.SignToken(req.ClientID)
-	if err != nil {
-		c.String(http.StatusInternalServerError, errors.Wrap(err, "failed to sign token").Error())
-		return
-	}
-	slog.Info("token signed", "token", token)
-
-	cipher, err := s.jwk.Encrypt([]byte(token), client.KeyAgreementKID())
+	cipher, err := s.issueToken(req.ClientID)
 	if err != nil {
-		c.String(http.StatusInternalServerError, errors.Wrap(err, "failed to encrypt").Error())
+		c.String(statusOf(err), err.Error())
 		return
 	}
 
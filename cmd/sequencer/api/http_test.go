@@ -2,14 +2,17 @@ package api
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
 	"github.com/pkg/errors"
@@ -19,8 +22,17 @@ import (
 	"github.com/machinefi/sprout/apitypes"
 	"github.com/machinefi/sprout/clients"
 	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+	"github.com/machinefi/sprout/project"
+	"github.com/machinefi/sprout/task"
+	"github.com/machinefi/sprout/util/merkle"
 )
 
+var testMessageSchema = func(uint64, string) *project.MessageSchema {
+	return &project.MessageSchema{Fields: map[string]*project.Field{
+		"temperature": {Type: project.FieldNumber, Required: true},
+	}}
+}
+
 func TestNewHttpServer(t *testing.T) {
 	r := require.New(t)
 	p := NewPatches()
@@ -32,8 +44,8 @@ func TestNewHttpServer(t *testing.T) {
 	p.ApplyMethodReturn(&ioconnect.JWK{}, "KeyAgreementKID", "KeyAgreementKID")
 	p.ApplyMethodReturn(&ioconnect.JWK{}, "Doc", nil)
 
-	s := NewHttpServer(nil, uint(1), "", nil, nil, nil)
-	r.Equal(uint(1), s.aggregationAmount)
+	s := NewHttpServer(nil, func(uint64) *persistence.Aggregation { return &persistence.Aggregation{Amount: 1} }, 0, nil, nil, "", nil, nil, nil)
+	r.Equal(uint(1), s.aggregation(1).Amount)
 }
 
 func TestHttpServer_Run(t *testing.T) {
@@ -131,7 +143,9 @@ func TestHttpServer_verifyToken(t *testing.T) {
 func TestHttpServer_handleMessage(t *testing.T) {
 	r := require.New(t)
 
-	s := &httpServer{}
+	s := &httpServer{
+		aggregation: func(uint64) *persistence.Aggregation { return &persistence.Aggregation{Amount: 1} },
+	}
 
 	t.Run("FailedToReadBody", func(t *testing.T) {
 		p := NewPatches()
@@ -246,7 +260,7 @@ func TestHttpServer_handleMessage(t *testing.T) {
 		p.ApplyMethodReturn(&clients.Client{}, "DID", "")
 		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", []byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data"}`), nil)
 		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
-		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", errors.New(t.Name()))
+		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", "", errors.New(t.Name()))
 		s.handleMessage(c)
 		r.Equal(http.StatusInternalServerError, w.Code)
 
@@ -269,12 +283,627 @@ func TestHttpServer_handleMessage(t *testing.T) {
 		p.ApplyMethodReturn(&clients.Client{}, "DID", "")
 		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", []byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data"}`), nil)
 		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
-		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", "id", nil)
 		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
 		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", []byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data"}`), nil)
 		s.handleMessage(c)
 		r.Equal(http.StatusOK, w.Code)
 	})
+
+	t.Run("DataTooLarge", func(t *testing.T) {
+		s := &httpServer{maxDataSize: 4}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data"}`)))
+
+		s.handleMessage(c)
+		r.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+		actualResponse := &apitypes.ErrRsp{}
+		err := json.Unmarshal(w.Body.Bytes(), &actualResponse)
+		r.NoError(err)
+		r.Contains(actualResponse.Error, "exceeds 4 bytes")
+	})
+
+	t.Run("InvalidData", func(t *testing.T) {
+		s := &httpServer{messageSchema: testMessageSchema}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"projectID": 123, "projectVersion": "v1", "data": "{\"temperature\":\"hot\"}"}`)))
+
+		s.handleMessage(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+
+		actualResponse := &apitypes.ErrRsp{}
+		err := json.Unmarshal(w.Body.Bytes(), &actualResponse)
+		r.NoError(err)
+		r.Equal(errInvalidMessageData.Error(), actualResponse.Error)
+		r.Equal([]*apitypes.FieldError{{Field: "temperature", Error: "expected number"}}, actualResponse.Fields)
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data", "signature": "0x01"}`)))
+
+		s.handleMessage(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+
+		actualResponse := &apitypes.ErrRsp{}
+		err := json.Unmarshal(w.Body.Bytes(), &actualResponse)
+		r.NoError(err)
+		r.Equal(errUnsignedClient.Error(), actualResponse.Error)
+	})
+
+	t.Run("IdempotencyKeyTooLong", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data"}`)))
+		c.Request.Header.Set(idempotencyKeyHeader, strings.Repeat("k", 129))
+
+		s.handleMessage(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Duplicated", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"projectID": 123, "projectVersion": "v1", "data": "some data"}`)))
+		c.Request.Header.Set(idempotencyKeyHeader, "key")
+
+		p.ApplyMethod(&persistence.Persistence{}, "Save", func(_ *persistence.Persistence, m *persistence.Message, _ *persistence.Aggregation, _ *ecdsa.PrivateKey) (string, error) {
+			r.Equal("key", m.IdempotencyKey)
+			return "origin", nil
+		})
+		s.handleMessage(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.HandleMessageRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Equal("origin", rsp.MessageID)
+	})
+}
+
+func TestHttpServer_verifySignature(t *testing.T) {
+	r := require.New(t)
+
+	s := &httpServer{}
+	req := &apitypes.HandleMessageReq{Data: "data", Signature: "0x0102"}
+
+	t.Run("Unsigned", func(t *testing.T) {
+		sig, err := s.verifySignature(nil, &apitypes.HandleMessageReq{})
+		r.NoError(err)
+		r.Nil(sig)
+	})
+
+	t.Run("UnsignedClient", func(t *testing.T) {
+		_, err := s.verifySignature(nil, req)
+		r.ErrorIs(err, errUnsignedClient)
+	})
+
+	t.Run("FailedToDecode", func(t *testing.T) {
+		_, err := s.verifySignature(&clients.Client{}, &apitypes.HandleMessageReq{Signature: "0102"})
+		r.ErrorContains(err, "failed to decode message signature")
+	})
+
+	t.Run("FailedToVerify", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "VerifySignature", nil, errors.New(t.Name()))
+		_, err := s.verifySignature(&clients.Client{}, req)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethod(&clients.Client{}, "VerifySignature", func(_ *clients.Client, data, sig []byte) (*task.DeviceSignature, error) {
+			r.Equal([]byte("data"), data)
+			r.Equal([]byte{1, 2}, sig)
+			return &task.DeviceSignature{KeyID: "key", Signature: sig}, nil
+		})
+		sig, err := s.verifySignature(&clients.Client{}, req)
+		r.NoError(err)
+		ds := &task.DeviceSignature{}
+		r.NoError(json.Unmarshal(sig, ds))
+		r.Equal("key", ds.KeyID)
+	})
+}
+
+func TestSplitMessages(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidArray", func(t *testing.T) {
+		_, err := splitMessages([]byte(`[{"projectID": 1}`))
+		r.Error(err)
+	})
+	t.Run("Array", func(t *testing.T) {
+		items, err := splitMessages([]byte(` [{"projectID": 1}, {"projectID": 2}]`))
+		r.NoError(err)
+		r.Len(items, 2)
+	})
+	t.Run("NDJSON", func(t *testing.T) {
+		items, err := splitMessages([]byte("{\"projectID\": 1}\n\n{\"projectID\": 2}\r\n"))
+		r.NoError(err)
+		r.Len(items, 2)
+		r.Equal(`{"projectID": 2}`, string(items[1]))
+	})
+}
+
+func TestHttpServer_handleMessages(t *testing.T) {
+	r := require.New(t)
+
+	s := &httpServer{
+		aggregation: func(uint64) *persistence.Aggregation { return &persistence.Aggregation{Amount: 1} },
+	}
+	batch := []byte(`[{"projectID": 1, "projectVersion": "v1", "data": "a"}, {"projectID": 1}, {"projectID": 2, "projectVersion": "v1", "data": "b"}, {"projectID": 1, "projectVersion": "v1", "data": "c"}]`)
+
+	t.Run("FailedToReadBody", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", nil)
+
+		p.ApplyFuncReturn(io.ReadAll, nil, errors.New(t.Name()))
+		s.handleMessages(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("FailedToDecrypt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(batch))
+
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", nil, errors.New(t.Name()))
+		s.handleMessages(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidBatch", func(t *testing.T) {
+		for _, body := range []string{"[", " ", "[]"} {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(body)))
+
+			s.handleMessages(c)
+			r.Equal(http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("TooManyMessages", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(bytes.Repeat([]byte("{}\n"), maxBatchMessages+1)))
+
+		s.handleMessages(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("FailedToSave", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(batch))
+
+		p.ApplyMethodReturn(&persistence.Persistence{}, "SaveBatch", nil, errors.New(t.Name()))
+		s.handleMessages(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(batch))
+
+		var saved []*persistence.Message
+		p.ApplyMethod(&persistence.Persistence{}, "SaveBatch", func(_ *persistence.Persistence, msgs []*persistence.Message, _ persistence.AggregationPolicy, _ *ecdsa.PrivateKey) ([]string, error) {
+			saved = msgs
+			ids := []string{}
+			for _, m := range msgs {
+				ids = append(ids, m.MessageID)
+			}
+			return ids, nil
+		})
+		s.handleMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.HandleMessagesRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Len(rsp.Messages, 4)
+		r.NotEmpty(rsp.Messages[0].MessageID)
+		r.NotEmpty(rsp.Messages[1].Error)
+		r.Empty(rsp.Messages[1].MessageID)
+		r.Len(saved, 3)
+		r.Equal(rsp.Messages[2].MessageID, saved[1].MessageID)
+		r.Equal([]byte("c"), saved[2].Data)
+	})
+
+	t.Run("DataTooLarge", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		s := &httpServer{aggregation: s.aggregation, maxDataSize: 1}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`[{"projectID": 1, "projectVersion": "v1", "data": "a"}, {"projectID": 1, "projectVersion": "v1", "data": "bb"}]`)))
+
+		var saved []*persistence.Message
+		p.ApplyMethod(&persistence.Persistence{}, "SaveBatch", func(_ *persistence.Persistence, msgs []*persistence.Message, _ persistence.AggregationPolicy, _ *ecdsa.PrivateKey) ([]string, error) {
+			saved = msgs
+			return []string{msgs[0].MessageID}, nil
+		})
+		s.handleMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.HandleMessagesRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Len(saved, 1)
+		r.NotEmpty(rsp.Messages[0].MessageID)
+		r.Contains(rsp.Messages[1].Error, "exceeds 1 bytes")
+	})
+
+	t.Run("InvalidData", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		s := &httpServer{aggregation: s.aggregation, messageSchema: testMessageSchema}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`[{"projectID": 1, "projectVersion": "v1", "data": "{\"temperature\":1}"}, {"projectID": 1, "projectVersion": "v1", "data": "{}"}]`)))
+
+		var saved []*persistence.Message
+		p.ApplyMethod(&persistence.Persistence{}, "SaveBatch", func(_ *persistence.Persistence, msgs []*persistence.Message, _ persistence.AggregationPolicy, _ *ecdsa.PrivateKey) ([]string, error) {
+			saved = msgs
+			return []string{msgs[0].MessageID}, nil
+		})
+		s.handleMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		rsp := &apitypes.HandleMessagesRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Len(saved, 1)
+		r.NotEmpty(rsp.Messages[0].MessageID)
+		r.Equal(errInvalidMessageData.Error(), rsp.Messages[1].Error)
+		r.Equal([]*apitypes.FieldError{{Field: "temperature", Error: "is required"}}, rsp.Messages[1].Fields)
+	})
+
+	t.Run("SuccessWithClient", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("cipher")))
+
+		checked := 0
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "Decrypt", batch, nil)
+		p.ApplyMethod(&clients.Manager{}, "HasProjectPermission", func(_ *clients.Manager, _ string, projectID uint64) (bool, error) {
+			checked++
+			return projectID == 1, nil
+		})
+		var saved []*persistence.Message
+		p.ApplyMethod(&persistence.Persistence{}, "SaveBatch", func(_ *persistence.Persistence, msgs []*persistence.Message, _ persistence.AggregationPolicy, _ *ecdsa.PrivateKey) ([]string, error) {
+			saved = msgs
+			ids := []string{}
+			for _, m := range msgs {
+				ids = append(ids, m.MessageID)
+			}
+			return ids, nil
+		})
+		var rsp *apitypes.HandleMessagesRsp
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			rsp = v.(*apitypes.HandleMessagesRsp)
+			return []byte("cipher"), nil
+		})
+		s.handleMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+		r.Equal("cipher", w.Body.String())
+
+		r.Equal(2, checked)
+		r.Len(saved, 2)
+		r.Equal("did", saved[0].ClientID)
+		r.Contains(rsp.Messages[2].Error, "no permission project 2")
+	})
+}
+
+// patchListingClient authenticates the listing request as client did permitted to the project, the response is plain
+// json for checking
+func patchListingClient(p *Patches) {
+	p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+	p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+	p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+	p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+	p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+		return json.Marshal(v)
+	})
+}
+
+func TestHttpServer_queryMessages(t *testing.T) {
+	r := require.New(t)
+
+	s := &httpServer{}
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?status=any", nil)
+
+		s.queryMessages(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("UnmatchedClient", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1&clientID=other", nil)
+
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		s.queryMessages(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("ProjectRequired", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages", nil)
+
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		s.queryMessages(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("NoPermissionProject", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1", nil)
+
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", false, nil)
+		s.queryMessages(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+
+		actualResponse := &apitypes.ErrRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), &actualResponse))
+		r.Contains(actualResponse.Error, "no permission project")
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1", nil)
+
+		p.ApplyMethod(&persistence.Persistence{}, "ListMessages", func(*persistence.Persistence, *persistence.MessageFilter) ([]*persistence.Message, error) {
+			panic(errors.New(t.Name()))
+		})
+		s.queryMessages(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("FailedToList", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1", nil)
+
+		patchListingClient(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "ListMessages", nil, errors.New(t.Name()))
+		s.queryMessages(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("FailedToFetchData", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1", nil)
+
+		patchListingClient(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "ListMessages", []*persistence.Message{{}}, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "MessageData", nil, errors.New(t.Name()))
+		s.queryMessages(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1&status=unpacked&since=2024-01-01T00:00:00Z&limit=2", nil)
+
+		patchListingClient(p)
+		var filter persistence.MessageFilter
+		p.ApplyMethod(&persistence.Persistence{}, "ListMessages", func(_ *persistence.Persistence, f *persistence.MessageFilter) ([]*persistence.Message, error) {
+			filter = *f
+			return []*persistence.Message{
+				{Model: gorm.Model{ID: 1}, MessageID: "a"},
+				{Model: gorm.Model{ID: 2}, MessageID: "b", InternalTaskID: "task"},
+			}, nil
+		})
+		p.ApplyMethodReturn(&persistence.Persistence{}, "MessageData", [][]byte{[]byte("a"), []byte("b")}, nil)
+		s.queryMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		r.Equal(uint64(1), filter.ProjectID)
+		r.False(*filter.Packed)
+		r.Equal(2024, filter.Since.Year())
+		rsp := &apitypes.QueryMessagesRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Len(rsp.Messages, 2)
+		r.Equal("b", rsp.Messages[1].Data)
+		r.True(rsp.Messages[1].Packed)
+		r.Equal(uint64(2), rsp.Next)
+	})
+
+	t.Run("SuccessWithClient", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/messages?projectID=1", nil)
+
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		var filter persistence.MessageFilter
+		p.ApplyMethod(&persistence.Persistence{}, "ListMessages", func(_ *persistence.Persistence, f *persistence.MessageFilter) ([]*persistence.Message, error) {
+			filter = *f
+			return []*persistence.Message{}, nil
+		})
+		p.ApplyMethodReturn(&persistence.Persistence{}, "MessageData", [][]byte{}, nil)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", []byte("cipher"), nil)
+		s.queryMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+		r.Equal("cipher", w.Body.String())
+		r.Equal("did", filter.ClientID)
+		r.Equal(defaultListLimit, filter.Limit)
+	})
+}
+
+func TestHttpServer_queryTasks(t *testing.T) {
+	r := require.New(t)
+
+	s := &httpServer{}
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/tasks?limit=0x", nil)
+
+		s.queryTasks(c)
+		r.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("NoPermissionProject", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/tasks?projectID=1", nil)
+
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", false, errors.New(t.Name()))
+		s.queryTasks(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/tasks?projectID=1", nil)
+
+		p.ApplyMethod(&persistence.Persistence{}, "ListTasks", func(*persistence.Persistence, *persistence.TaskFilter) ([]*persistence.Task, error) {
+			panic(errors.New(t.Name()))
+		})
+		s.queryTasks(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("FailedToList", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/tasks?projectID=1", nil)
+
+		patchListingClient(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "ListTasks", nil, errors.New(t.Name()))
+		s.queryTasks(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("InvalidMessageIDs", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/tasks?projectID=1", nil)
+
+		patchListingClient(p)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "ListTasks", []*persistence.Task{{MessageIDs: []byte("{")}}, nil)
+		s.queryTasks(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/tasks?projectID=1&limit=1", nil)
+
+		patchListingClient(p)
+		var filter persistence.TaskFilter
+		p.ApplyMethod(&persistence.Persistence{}, "ListTasks", func(_ *persistence.Persistence, f *persistence.TaskFilter) ([]*persistence.Task, error) {
+			filter = *f
+			return []*persistence.Task{
+				{Model: gorm.Model{ID: 3}, ProjectID: 1, MessageIDs: []byte(`["a","b"]`)},
+			}, nil
+		})
+		s.queryTasks(c)
+		r.Equal(http.StatusOK, w.Code)
+		r.Equal("did", filter.ClientID)
+
+		rsp := &apitypes.QueryTasksRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.Len(rsp.Tasks, 1)
+		r.Equal([]string{"a", "b"}, rsp.Tasks[0].MessageIDs)
+		r.Equal(uint64(3), rsp.Next)
+	})
 }
 
 func TestHttpServer_queryStateLogByID(t *testing.T) {
@@ -288,6 +917,7 @@ func TestHttpServer_queryStateLogByID(t *testing.T) {
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
 
 		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", nil, errors.New(t.Name()))
 		s.queryStateLogByID(c)
@@ -305,6 +935,7 @@ func TestHttpServer_queryStateLogByID(t *testing.T) {
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
 		c.Params = append(c.Params, gin.Param{Key: "id", Value: "some_message_id"})
 
 		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{}, nil)
@@ -621,6 +1252,41 @@ func TestHttpServer_queryStateLogByID(t *testing.T) {
 	})
 }
 
+func TestMessageInclusion(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("NotCommitted", func(t *testing.T) {
+		inclusion, err := messageInclusion(&persistence.Message{}, &persistence.Task{}, nil)
+		r.NoError(err)
+		r.Nil(inclusion)
+	})
+
+	t.Run("FailedToBuildProof", func(t *testing.T) {
+		_, err := messageInclusion(&persistence.Message{MessageID: "m1"}, &persistence.Task{MerkleRoot: "0x01", MessageIDs: []byte("{")}, nil)
+		r.ErrorContains(err, "failed to build message merkle proof")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		tree := merkle.New([][]byte{[]byte("a"), []byte("b")})
+		leaves, err := json.Marshal(tree.Leaves())
+		r.NoError(err)
+
+		inclusion, err := messageInclusion(
+			&persistence.Message{MessageID: "m2"},
+			&persistence.Task{Model: gorm.Model{ID: 1}, MessageIDs: []byte(`["m1","m2"]`), MerkleLeaves: leaves, MerkleRoot: tree.Root().Hex(), RootSignature: "sig"},
+			[]*apitypes.StateLog{{State: task.StateProved.String()}, {State: task.StateOutputted.String(), Result: "tx"}},
+		)
+		r.NoError(err)
+		r.Equal(uint64(1), inclusion.TaskID)
+		r.Equal(tree.Root().Hex(), inclusion.Root)
+		r.Equal("sig", inclusion.RootSignature)
+		r.Equal("tx", inclusion.OutputTx)
+		r.Len(inclusion.Proof, 1)
+		r.Equal(common.HexToHash(inclusion.Proof[0].Hash), tree.Proof(1)[0].Hash)
+		r.True(inclusion.Proof[0].Left)
+	})
+}
+
 func TestHttpServer_issueJWTCredential(t *testing.T) {
 	r := require.New(t)
 
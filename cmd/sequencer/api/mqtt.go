@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+)
+
+const (
+	mqttQoS = 1
+	// the max duration of publishing the state updates of a message
+	maxStateTracking = time.Hour
+)
+
+type MqttConfig struct {
+	Broker           string // e.g. ssl://localhost:8883
+	ClientID         string
+	Username         string
+	Password         string
+	TopicPrefix      string
+	Insecure         bool // allows a broker without tls, the tokens in message payloads are plaintext on the wire then
+	MaxStateTrackers int  // the max messages whose states are replied concurrently, state replies are disabled if 0
+}
+
+// mqttServer ingests the messages published by devices, and shares the checks of messages with the http server
+type mqttServer struct {
+	s        *httpServer
+	client   mqtt.Client
+	prefix   string
+	trackers chan struct{} // the semaphore of message state trackers, nil if state replies are disabled
+}
+
+// secureBroker returns whether the broker is connected over tls
+func secureBroker(broker string) bool {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "ssl", "tls", "mqtts", "wss":
+		return true
+	default:
+		return false
+	}
+}
+
+// NewMqttServer returns the mqtt server, the broker must be connected over tls unless insecure is configured, since
+// the credential tokens are sent in message payloads
+func NewMqttServer(s *httpServer, conf *MqttConfig) (*mqttServer, error) {
+	if !conf.Insecure && !secureBroker(conf.Broker) {
+		return nil, errors.Errorf("mqtt broker %s is not connected over tls", conf.Broker)
+	}
+	m := &mqttServer{s: s, prefix: conf.TopicPrefix}
+	if conf.MaxStateTrackers > 0 {
+		m.trackers = make(chan struct{}, conf.MaxStateTrackers)
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(conf.Broker).
+		SetClientID(conf.ClientID).
+		SetUsername(conf.Username).
+		SetPassword(conf.Password).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(m.subscribe)
+	m.client = mqtt.NewClient(opts)
+	return m, nil
+}
+
+// Run connects to the broker, the messages topic is subscribed on every connection
+func (m *mqttServer) Run() error {
+	if t := m.client.Connect(); t.Wait() && t.Error() != nil {
+		return errors.Wrap(t.Error(), "failed to connect mqtt broker")
+	}
+	return nil
+}
+
+func (m *mqttServer) subscribe(c mqtt.Client) {
+	topic := m.prefix + "/+/+/messages"
+	if t := c.Subscribe(topic, mqttQoS, m.handleMessage); t.Wait() && t.Error() != nil {
+		slog.Error("failed to subscribe mqtt topic", "topic", topic, "error", t.Error())
+		return
+	}
+	slog.Info("mqtt topic subscribed", "topic", topic)
+}
+
+// parseTopic returns the project id and version of {prefix}/{projectID}/{projectVersion}/messages
+func (m *mqttServer) parseTopic(topic string) (uint64, string, error) {
+	parts := strings.Split(strings.TrimPrefix(topic, m.prefix+"/"), "/")
+	if len(parts) != 3 || parts[2] != "messages" || parts[1] == "" {
+		return 0, "", errors.Errorf("invalid message topic %s", topic)
+	}
+	projectID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "invalid project id of topic %s", topic)
+	}
+	return projectID, parts[1], nil
+}
+
+func (m *mqttServer) replyTopic(projectID uint64, projectVersion string, client *clients.Client) string {
+	return fmt.Sprintf("%s/%d/%s/replies/%s", m.prefix, projectID, projectVersion, client.DID())
+}
+
+// handleMessage saves the message as the http api and replies the message id, the message without valid token is
+// dropped since there is no client to reply to
+func (m *mqttServer) handleMessage(_ mqtt.Client, msg mqtt.Message) {
+	projectID, projectVersion, err := m.parseTopic(msg.Topic())
+	if err != nil {
+		slog.Error("failed to parse mqtt message topic", "error", err)
+		return
+	}
+	req := &apitypes.MQTTMessageReq{}
+	if err := json.Unmarshal(msg.Payload(), req); err != nil {
+		slog.Error("failed to unmarshal mqtt message", "topic", msg.Topic(), "error", err)
+		return
+	}
+	client, err := m.s.clientByToken(req.Token)
+	if err != nil {
+		slog.Error("failed to authenticate mqtt message", "topic", msg.Topic(), "error", err)
+		return
+	}
+
+	topic := m.replyTopic(projectID, projectVersion, client)
+	id, err := m.s.saveMessage(client, client.DID(), &apitypes.HandleMessageReq{
+		ProjectID:      projectID,
+		ProjectVersion: projectVersion,
+		Data:           req.Data,
+		IdempotencyKey: req.IdempotencyKey,
+		Signature:      req.Signature,
+	})
+	reply := &apitypes.MQTTReply{RequestID: req.RequestID, MessageID: id}
+	if err != nil {
+		reply.Error = err.Error()
+		if e := (&requestError{}); errors.As(err, &e) {
+			reply.Fields = e.fields
+		}
+	}
+	if err := m.publish(topic, client, reply); err != nil {
+		slog.Error("failed to publish mqtt reply", "topic", topic, "error", err)
+		return
+	}
+	if reply.MessageID != "" {
+		m.trackStates(topic, client, reply)
+	}
+}
+
+// trackStates publishes the states of the message in background, the states are not published if state replies are
+// disabled or too many messages are tracked, the client could still query the message by id
+func (m *mqttServer) trackStates(topic string, client *clients.Client, reply *apitypes.MQTTReply) {
+	if m.trackers == nil {
+		return
+	}
+	select {
+	case m.trackers <- struct{}{}:
+	default:
+		slog.Warn("too many mqtt message states tracked", "message_id", reply.MessageID, "max", cap(m.trackers))
+		return
+	}
+	go func() {
+		defer func() { <-m.trackers }()
+		m.publishStates(topic, client, reply)
+	}()
+}
+
+// publishStates publishes the states of the message until the task is outputted or failed
+func (m *mqttServer) publishStates(topic string, client *clients.Client, reply *apitypes.MQTTReply) {
+	ctx, cancel := context.WithTimeout(context.Background(), maxStateTracking)
+	defer cancel()
+
+	send := func(l *apitypes.StateLog) error {
+		return m.publish(topic, client, &apitypes.MQTTReply{RequestID: reply.RequestID, MessageID: reply.MessageID, State: l})
+	}
+	if err := m.s.followMessageState(ctx, client, reply.MessageID, send); err != nil {
+		slog.Error("failed to publish mqtt message states", "message_id", reply.MessageID, "error", err)
+		if err := m.publish(topic, client, &apitypes.MQTTReply{RequestID: reply.RequestID, MessageID: reply.MessageID, Error: err.Error()}); err != nil {
+			slog.Error("failed to publish mqtt reply", "topic", topic, "error", err)
+		}
+	}
+}
+
+// publish publishes the reply encrypted for the client
+func (m *mqttServer) publish(topic string, client *clients.Client, reply *apitypes.MQTTReply) error {
+	cipher, err := m.s.jwk.EncryptJSON(reply, client.KeyAgreementKID())
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt mqtt reply")
+	}
+	if t := m.client.Publish(topic, mqttQoS, false, cipher); t.Wait() && t.Error() != nil {
+		return errors.Wrapf(t.Error(), "failed to publish to topic %s", topic)
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+)
+
+type mockMqttToken struct {
+	mqtt.Token
+	err error
+}
+
+func (t *mockMqttToken) Wait() bool   { return true }
+func (t *mockMqttToken) Error() error { return t.err }
+
+type mockMqttClient struct {
+	mqtt.Client
+	err       error
+	published map[string][]byte
+}
+
+func (c *mockMqttClient) Connect() mqtt.Token { return &mockMqttToken{err: c.err} }
+
+func (c *mockMqttClient) Subscribe(string, byte, mqtt.MessageHandler) mqtt.Token {
+	return &mockMqttToken{err: c.err}
+}
+
+func (c *mockMqttClient) Publish(topic string, _ byte, _ bool, payload any) mqtt.Token {
+	if c.err == nil {
+		c.published[topic] = payload.([]byte)
+	}
+	return &mockMqttToken{err: c.err}
+}
+
+type mockMqttMessage struct {
+	mqtt.Message
+	topic   string
+	payload []byte
+}
+
+func (m *mockMqttMessage) Topic() string   { return m.topic }
+func (m *mockMqttMessage) Payload() []byte { return m.payload }
+
+func TestNewMqttServer(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InsecureBroker", func(t *testing.T) {
+		_, err := NewMqttServer(&httpServer{}, &MqttConfig{Broker: "tcp://localhost:1883"})
+		r.ErrorContains(err, "not connected over tls")
+	})
+
+	t.Run("AllowedInsecureBroker", func(t *testing.T) {
+		m, err := NewMqttServer(&httpServer{}, &MqttConfig{Broker: "tcp://localhost:1883", Insecure: true})
+		r.NoError(err)
+		r.Nil(m.trackers)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		m, err := NewMqttServer(&httpServer{}, &MqttConfig{Broker: "ssl://localhost:8883", MaxStateTrackers: 10})
+		r.NoError(err)
+		r.Equal(10, cap(m.trackers))
+	})
+}
+
+func TestMqttServer_Run(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("FailedToConnect", func(t *testing.T) {
+		m := &mqttServer{client: &mockMqttClient{err: errors.New(t.Name())}}
+		r.ErrorContains(m.Run(), t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		m := &mqttServer{client: &mockMqttClient{}}
+		r.NoError(m.Run())
+	})
+}
+
+func TestMqttServer_parseTopic(t *testing.T) {
+	r := require.New(t)
+
+	m := &mqttServer{prefix: "w3bstream"}
+
+	t.Run("InvalidTopic", func(t *testing.T) {
+		for _, topic := range []string{"w3bstream/1/v1", "w3bstream/1/v1/replies", "w3bstream/1//messages", "w3bstream/1/v1/messages/a"} {
+			_, _, err := m.parseTopic(topic)
+			r.ErrorContains(err, "invalid message topic", topic)
+		}
+	})
+
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		_, _, err := m.parseTopic("w3bstream/a/v1/messages")
+		r.ErrorContains(err, "invalid project id")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		projectID, projectVersion, err := m.parseTopic("w3bstream/1/v1/messages")
+		r.NoError(err)
+		r.Equal(uint64(1), projectID)
+		r.Equal("v1", projectVersion)
+	})
+}
+
+func TestMqttServer_handleMessage(t *testing.T) {
+	r := require.New(t)
+
+	newServer := func() (*mqttServer, *mockMqttClient) {
+		c := &mockMqttClient{published: map[string][]byte{}}
+		return &mqttServer{s: &httpServer{jwk: &ioconnect.JWK{}}, client: c, prefix: "w3bstream", trackers: make(chan struct{}, 1)}, c
+	}
+
+	t.Run("InvalidTopic", func(t *testing.T) {
+		m, c := newServer()
+		m.handleMessage(nil, &mockMqttMessage{topic: "w3bstream/a/v1/messages"})
+		r.Empty(c.published)
+	})
+
+	t.Run("InvalidPayload", func(t *testing.T) {
+		m, c := newServer()
+		m.handleMessage(nil, &mockMqttMessage{topic: "w3bstream/1/v1/messages", payload: []byte("{")})
+		r.Empty(c.published)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		m, c := newServer()
+		p.ApplyPrivateMethod(m.s, "clientByToken", func(string) (*clients.Client, error) {
+			return nil, errors.New(t.Name())
+		})
+		m.handleMessage(nil, &mockMqttMessage{topic: "w3bstream/1/v1/messages", payload: []byte(`{"token": "token"}`)})
+		r.Empty(c.published)
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		m, c := newServer()
+		p.ApplyPrivateMethod(m.s, "clientByToken", func(string) (*clients.Client, error) { return &clients.Client{}, nil })
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			return json.Marshal(v)
+		})
+
+		for topic, payload := range map[string]string{
+			"w3bstream/1/v1/messages": `{"token": "token", "requestID": "req", "data": ""}`,
+			"w3bstream/0/v1/messages": `{"token": "token", "requestID": "req", "data": "data"}`,
+		} {
+			m.handleMessage(nil, &mockMqttMessage{topic: topic, payload: []byte(payload)})
+			reply := &apitypes.MQTTReply{}
+			r.NoError(json.Unmarshal(c.published[strings.Replace(topic, "messages", "replies/did", 1)], reply))
+			r.Equal("req", reply.RequestID)
+			r.Empty(reply.MessageID)
+			r.NotEmpty(reply.Error)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		m, c := newServer()
+		p.ApplyPrivateMethod(m.s, "clientByToken", func(string) (*clients.Client, error) { return &clients.Client{}, nil })
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyPrivateMethod(m.s, "saveMessage", func(*clients.Client, string, *apitypes.HandleMessageReq) (string, error) {
+			return "", &requestError{err: errInvalidMessageData, fields: []*apitypes.FieldError{{Field: "a", Error: "required"}}}
+		})
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			return json.Marshal(v)
+		})
+		p.ApplyPrivateMethod(m, "publishStates", func(string, *clients.Client, *apitypes.MQTTReply) {
+			panic("should not publish states of rejected message")
+		})
+
+		m.handleMessage(nil, &mockMqttMessage{topic: "w3bstream/1/v1/messages", payload: []byte(`{"token": "token", "requestID": "req"}`)})
+		reply := &apitypes.MQTTReply{}
+		r.NoError(json.Unmarshal(c.published["w3bstream/1/v1/replies/did"], reply))
+		r.Equal(&apitypes.MQTTReply{RequestID: "req", Error: errInvalidMessageData.Error(), Fields: []*apitypes.FieldError{{Field: "a", Error: "required"}}}, reply)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		m, c := newServer()
+		p.ApplyPrivateMethod(m.s, "clientByToken", func(string) (*clients.Client, error) { return &clients.Client{}, nil })
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		var (
+			saved apitypes.HandleMessageReq
+			key   string
+		)
+		p.ApplyPrivateMethod(m.s, "saveMessage", func(_ *httpServer, _ *clients.Client, k string, req *apitypes.HandleMessageReq) (string, error) {
+			saved, key = *req, k
+			return "message", nil
+		})
+		p.ApplyMethod(&ioconnect.JWK{}, "EncryptJSON", func(_ *ioconnect.JWK, v any, _ string) ([]byte, error) {
+			return json.Marshal(v)
+		})
+		tracked := make(chan string, 1)
+		p.ApplyPrivateMethod(m, "publishStates", func(_ *mqttServer, _ string, _ *clients.Client, reply *apitypes.MQTTReply) {
+			tracked <- reply.MessageID
+		})
+
+		m.handleMessage(nil, &mockMqttMessage{topic: "w3bstream/1/v1/messages", payload: []byte(`{"token": "token", "requestID": "req", "data": "data", "signature": "0x01"}`)})
+		r.Equal(apitypes.HandleMessageReq{ProjectID: 1, ProjectVersion: "v1", Data: "data", Signature: "0x01"}, saved)
+		r.Equal("did", key)
+		reply := &apitypes.MQTTReply{}
+		r.NoError(json.Unmarshal(c.published["w3bstream/1/v1/replies/did"], reply))
+		r.Equal(&apitypes.MQTTReply{RequestID: "req", MessageID: "message"}, reply)
+
+		select {
+		case id := <-tracked:
+			r.Equal("message", id)
+		case <-time.After(time.Second):
+			r.Fail("states not published")
+		}
+	})
+}
+
+func TestMqttServer_trackStates(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Disabled", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		m := &mqttServer{}
+		p.ApplyPrivateMethod(m, "publishStates", func(*mqttServer, string, *clients.Client, *apitypes.MQTTReply) {
+			panic(errors.New(t.Name()))
+		})
+		m.trackStates("topic", nil, &apitypes.MQTTReply{MessageID: "message"})
+	})
+
+	t.Run("TooManyTracked", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		m := &mqttServer{trackers: make(chan struct{}, 1)}
+		tracked := make(chan string, 2)
+		release := make(chan struct{})
+		p.ApplyPrivateMethod(m, "publishStates", func(_ *mqttServer, _ string, _ *clients.Client, reply *apitypes.MQTTReply) {
+			tracked <- reply.MessageID
+			<-release
+		})
+
+		m.trackStates("topic", nil, &apitypes.MQTTReply{MessageID: "first"})
+		r.Equal("first", <-tracked)
+		m.trackStates("topic", nil, &apitypes.MQTTReply{MessageID: "second"})
+		close(release)
+
+		// the tracker is released once the first is done
+		r.Eventually(func() bool { return len(m.trackers) == 0 }, time.Second, time.Millisecond)
+		m.trackStates("topic", nil, &apitypes.MQTTReply{MessageID: "third"})
+		r.Equal("third", <-tracked)
+		r.Empty(tracked)
+	})
+}
+
+func TestMqttServer_publish(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("FailedToEncrypt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", nil, errors.New(t.Name()))
+		m := &mqttServer{s: &httpServer{jwk: &ioconnect.JWK{}}, client: &mockMqttClient{}}
+		r.ErrorContains(m.publish("topic", &clients.Client{}, &apitypes.MQTTReply{}), t.Name())
+	})
+
+	t.Run("FailedToPublish", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", []byte("cipher"), nil)
+		m := &mqttServer{s: &httpServer{jwk: &ioconnect.JWK{}}, client: &mockMqttClient{err: errors.New(t.Name())}}
+		r.ErrorContains(m.publish("topic", &clients.Client{}, &apitypes.MQTTReply{}), t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", []byte("cipher"), nil)
+		c := &mockMqttClient{published: map[string][]byte{}}
+		m := &mqttServer{s: &httpServer{jwk: &ioconnect.JWK{}}, client: c}
+		r.NoError(m.publish("topic", &clients.Client{}, &apitypes.MQTTReply{}))
+		r.Equal([]byte("cipher"), c.published["topic"])
+	})
+}
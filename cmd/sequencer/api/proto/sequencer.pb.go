@@ -0,0 +1,1097 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.2
+// source: proto/sequencer.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type IssueVCRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClientID string `protobuf:"bytes,1,opt,name=clientID,proto3" json:"clientID,omitempty"`
+}
+
+func (x *IssueVCRequest) Reset() {
+	*x = IssueVCRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IssueVCRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueVCRequest) ProtoMessage() {}
+
+func (x *IssueVCRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueVCRequest.ProtoReflect.Descriptor instead.
+func (*IssueVCRequest) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *IssueVCRequest) GetClientID() string {
+	if x != nil {
+		return x.ClientID
+	}
+	return ""
+}
+
+type IssueVCResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the credential token encrypted for the client
+	Cipher []byte `protobuf:"bytes,1,opt,name=cipher,proto3" json:"cipher,omitempty"`
+}
+
+func (x *IssueVCResponse) Reset() {
+	*x = IssueVCResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IssueVCResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueVCResponse) ProtoMessage() {}
+
+func (x *IssueVCResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueVCResponse.ProtoReflect.Descriptor instead.
+func (*IssueVCResponse) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *IssueVCResponse) GetCipher() []byte {
+	if x != nil {
+		return x.Cipher
+	}
+	return nil
+}
+
+type HandleMessageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProjectID      uint64 `protobuf:"varint,1,opt,name=projectID,proto3" json:"projectID,omitempty"`
+	ProjectVersion string `protobuf:"bytes,2,opt,name=projectVersion,proto3" json:"projectVersion,omitempty"`
+	Data           string `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	IdempotencyKey string `protobuf:"bytes,4,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
+	Signature      string `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *HandleMessageRequest) Reset() {
+	*x = HandleMessageRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HandleMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandleMessageRequest) ProtoMessage() {}
+
+func (x *HandleMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandleMessageRequest.ProtoReflect.Descriptor instead.
+func (*HandleMessageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HandleMessageRequest) GetProjectID() uint64 {
+	if x != nil {
+		return x.ProjectID
+	}
+	return 0
+}
+
+func (x *HandleMessageRequest) GetProjectVersion() string {
+	if x != nil {
+		return x.ProjectVersion
+	}
+	return ""
+}
+
+func (x *HandleMessageRequest) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *HandleMessageRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *HandleMessageRequest) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+type HandleMessageResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MessageID string `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"`
+}
+
+func (x *HandleMessageResponse) Reset() {
+	*x = HandleMessageResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HandleMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandleMessageResponse) ProtoMessage() {}
+
+func (x *HandleMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandleMessageResponse.ProtoReflect.Descriptor instead.
+func (*HandleMessageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HandleMessageResponse) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+type QueryMessageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MessageID string `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"`
+}
+
+func (x *QueryMessageRequest) Reset() {
+	*x = QueryMessageRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryMessageRequest) ProtoMessage() {}
+
+func (x *QueryMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryMessageRequest.ProtoReflect.Descriptor instead.
+func (*QueryMessageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QueryMessageRequest) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+type StateLog struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State   string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Time    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Comment string                 `protobuf:"bytes,3,opt,name=comment,proto3" json:"comment,omitempty"`
+	Result  string                 `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+}
+
+func (x *StateLog) Reset() {
+	*x = StateLog{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StateLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StateLog) ProtoMessage() {}
+
+func (x *StateLog) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StateLog.ProtoReflect.Descriptor instead.
+func (*StateLog) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StateLog) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *StateLog) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *StateLog) GetComment() string {
+	if x != nil {
+		return x.Comment
+	}
+	return ""
+}
+
+func (x *StateLog) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+type MerkleProofNode struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Left bool   `protobuf:"varint,2,opt,name=left,proto3" json:"left,omitempty"`
+}
+
+func (x *MerkleProofNode) Reset() {
+	*x = MerkleProofNode{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MerkleProofNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleProofNode) ProtoMessage() {}
+
+func (x *MerkleProofNode) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleProofNode.ProtoReflect.Descriptor instead.
+func (*MerkleProofNode) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *MerkleProofNode) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *MerkleProofNode) GetLeft() bool {
+	if x != nil {
+		return x.Left
+	}
+	return false
+}
+
+type MessageInclusion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TaskID        uint64             `protobuf:"varint,1,opt,name=taskID,proto3" json:"taskID,omitempty"`
+	Root          string             `protobuf:"bytes,2,opt,name=root,proto3" json:"root,omitempty"`
+	RootSignature string             `protobuf:"bytes,3,opt,name=rootSignature,proto3" json:"rootSignature,omitempty"`
+	Proof         []*MerkleProofNode `protobuf:"bytes,4,rep,name=proof,proto3" json:"proof,omitempty"`
+	OutputTx      string             `protobuf:"bytes,5,opt,name=outputTx,proto3" json:"outputTx,omitempty"`
+}
+
+func (x *MessageInclusion) Reset() {
+	*x = MessageInclusion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MessageInclusion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageInclusion) ProtoMessage() {}
+
+func (x *MessageInclusion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageInclusion.ProtoReflect.Descriptor instead.
+func (*MessageInclusion) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *MessageInclusion) GetTaskID() uint64 {
+	if x != nil {
+		return x.TaskID
+	}
+	return 0
+}
+
+func (x *MessageInclusion) GetRoot() string {
+	if x != nil {
+		return x.Root
+	}
+	return ""
+}
+
+func (x *MessageInclusion) GetRootSignature() string {
+	if x != nil {
+		return x.RootSignature
+	}
+	return ""
+}
+
+func (x *MessageInclusion) GetProof() []*MerkleProofNode {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *MessageInclusion) GetOutputTx() string {
+	if x != nil {
+		return x.OutputTx
+	}
+	return ""
+}
+
+type QueryMessageResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MessageID string            `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"`
+	States    []*StateLog       `protobuf:"bytes,2,rep,name=states,proto3" json:"states,omitempty"`
+	Inclusion *MessageInclusion `protobuf:"bytes,3,opt,name=inclusion,proto3" json:"inclusion,omitempty"`
+}
+
+func (x *QueryMessageResponse) Reset() {
+	*x = QueryMessageResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryMessageResponse) ProtoMessage() {}
+
+func (x *QueryMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryMessageResponse.ProtoReflect.Descriptor instead.
+func (*QueryMessageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QueryMessageResponse) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *QueryMessageResponse) GetStates() []*StateLog {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *QueryMessageResponse) GetInclusion() *MessageInclusion {
+	if x != nil {
+		return x.Inclusion
+	}
+	return nil
+}
+
+type DidDocRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DidDocRequest) Reset() {
+	*x = DidDocRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DidDocRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DidDocRequest) ProtoMessage() {}
+
+func (x *DidDocRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DidDocRequest.ProtoReflect.Descriptor instead.
+func (*DidDocRequest) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{9}
+}
+
+type DidDocResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the json encoded did document of the sequencer
+	Document []byte `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+}
+
+func (x *DidDocResponse) Reset() {
+	*x = DidDocResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DidDocResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DidDocResponse) ProtoMessage() {}
+
+func (x *DidDocResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DidDocResponse.ProtoReflect.Descriptor instead.
+func (*DidDocResponse) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DidDocResponse) GetDocument() []byte {
+	if x != nil {
+		return x.Document
+	}
+	return nil
+}
+
+type CipherRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the didcomm cipher of the json request
+	Cipher []byte `protobuf:"bytes,1,opt,name=cipher,proto3" json:"cipher,omitempty"`
+}
+
+func (x *CipherRequest) Reset() {
+	*x = CipherRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CipherRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CipherRequest) ProtoMessage() {}
+
+func (x *CipherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CipherRequest.ProtoReflect.Descriptor instead.
+func (*CipherRequest) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CipherRequest) GetCipher() []byte {
+	if x != nil {
+		return x.Cipher
+	}
+	return nil
+}
+
+type CipherResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the didcomm cipher of the json response
+	Cipher []byte `protobuf:"bytes,1,opt,name=cipher,proto3" json:"cipher,omitempty"`
+}
+
+func (x *CipherResponse) Reset() {
+	*x = CipherResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_sequencer_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CipherResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CipherResponse) ProtoMessage() {}
+
+func (x *CipherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_sequencer_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CipherResponse.ProtoReflect.Descriptor instead.
+func (*CipherResponse) Descriptor() ([]byte, []int) {
+	return file_proto_sequencer_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CipherResponse) GetCipher() []byte {
+	if x != nil {
+		return x.Cipher
+	}
+	return nil
+}
+
+var File_proto_sequencer_proto protoreflect.FileDescriptor
+
+var file_proto_sequencer_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65,
+	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63,
+	0x65, 0x72, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0x2c, 0x0a, 0x0e, 0x49, 0x73, 0x73, 0x75, 0x65, 0x56, 0x43, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49,
+	0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49,
+	0x44, 0x22, 0x29, 0x0a, 0x0f, 0x49, 0x73, 0x73, 0x75, 0x65, 0x56, 0x43, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x22, 0xb6, 0x01, 0x0a,
+	0x14, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63,
+	0x74, 0x49, 0x44, 0x12, 0x26, 0x0a, 0x0e, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x70, 0x72, 0x6f,
+	0x6a, 0x65, 0x63, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x64,
+	0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12,
+	0x26, 0x0a, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74,
+	0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x35, 0x0a, 0x15, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x4d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1c,
+	0x0a, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x22, 0x33, 0x0a, 0x13,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49,
+	0x44, 0x22, 0x82, 0x01, 0x0a, 0x08, 0x53, 0x74, 0x61, 0x74, 0x65, 0x4c, 0x6f, 0x67, 0x12, 0x14,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04,
+	0x74, 0x69, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x39, 0x0a, 0x0f, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65,
+	0x50, 0x72, 0x6f, 0x6f, 0x66, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a,
+	0x04, 0x6c, 0x65, 0x66, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x6c, 0x65, 0x66,
+	0x74, 0x22, 0xb2, 0x01, 0x0a, 0x10, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x6e, 0x63,
+	0x6c, 0x75, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x73, 0x6b, 0x49, 0x44,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x74, 0x61, 0x73, 0x6b, 0x49, 0x44, 0x12, 0x12,
+	0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f,
+	0x6f, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x72, 0x6f, 0x6f, 0x74, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x6f, 0x6f, 0x74, 0x53,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x30, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x6f,
+	0x66, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e,
+	0x63, 0x65, 0x72, 0x2e, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x4e,
+	0x6f, 0x64, 0x65, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x6f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x54, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x54, 0x78, 0x22, 0x9c, 0x01, 0x0a, 0x14, 0x51, 0x75, 0x65, 0x72, 0x79,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x1c, 0x0a, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x12, 0x2b, 0x0a,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x4c,
+	0x6f, 0x67, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x12, 0x39, 0x0a, 0x09, 0x69, 0x6e,
+	0x63, 0x6c, 0x75, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e,
+	0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x49, 0x6e, 0x63, 0x6c, 0x75, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x69, 0x6e, 0x63, 0x6c,
+	0x75, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x0f, 0x0a, 0x0d, 0x44, 0x69, 0x64, 0x44, 0x6f, 0x63, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2c, 0x0a, 0x0e, 0x44, 0x69, 0x64, 0x44, 0x6f, 0x63,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x6f, 0x63, 0x75,
+	0x6d, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x64, 0x6f, 0x63, 0x75,
+	0x6d, 0x65, 0x6e, 0x74, 0x22, 0x27, 0x0a, 0x0d, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x22, 0x28, 0x0a,
+	0x0e, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x32, 0xf4, 0x04, 0x0a, 0x09, 0x53, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x72, 0x12, 0x40, 0x0a, 0x07, 0x49, 0x73, 0x73, 0x75, 0x65, 0x56, 0x43,
+	0x12, 0x19, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x49, 0x73, 0x73,
+	0x75, 0x65, 0x56, 0x43, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x65,
+	0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x49, 0x73, 0x73, 0x75, 0x65, 0x56, 0x43, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x52, 0x0a, 0x0d, 0x48, 0x61, 0x6e, 0x64, 0x6c,
+	0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1f, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x72, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x73, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4f, 0x0a, 0x0c, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1e, 0x2e, 0x73, 0x65,
+	0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x73, 0x65,
+	0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x12,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x12, 0x1e, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x13, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x4c, 0x6f, 0x67, 0x30, 0x01, 0x12, 0x3d, 0x0a, 0x06, 0x44, 0x69, 0x64,
+	0x44, 0x6f, 0x63, 0x12, 0x18, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e,
+	0x44, 0x69, 0x64, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e,
+	0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x44, 0x69, 0x64, 0x44, 0x6f, 0x63,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a, 0x0a, 0x13, 0x48, 0x61, 0x6e, 0x64,
+	0x6c, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x12,
+	0x18, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x43, 0x69, 0x70, 0x68,
+	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x73, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4f, 0x0a, 0x12, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x12, 0x1e, 0x2e, 0x73, 0x65, 0x71,
+	0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x73, 0x65, 0x71,
+	0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x57, 0x0a, 0x18, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x43, 0x69, 0x70, 0x68, 0x65,
+	0x72, 0x12, 0x1e, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x19, 0x2e, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x72, 0x2e, 0x43, 0x69,
+	0x70, 0x68, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x42, 0x09,
+	0x5a, 0x07, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_proto_sequencer_proto_rawDescOnce sync.Once
+	file_proto_sequencer_proto_rawDescData = file_proto_sequencer_proto_rawDesc
+)
+
+func file_proto_sequencer_proto_rawDescGZIP() []byte {
+	file_proto_sequencer_proto_rawDescOnce.Do(func() {
+		file_proto_sequencer_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_sequencer_proto_rawDescData)
+	})
+	return file_proto_sequencer_proto_rawDescData
+}
+
+var file_proto_sequencer_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proto_sequencer_proto_goTypes = []any{
+	(*IssueVCRequest)(nil),        // 0: sequencer.IssueVCRequest
+	(*IssueVCResponse)(nil),       // 1: sequencer.IssueVCResponse
+	(*HandleMessageRequest)(nil),  // 2: sequencer.HandleMessageRequest
+	(*HandleMessageResponse)(nil), // 3: sequencer.HandleMessageResponse
+	(*QueryMessageRequest)(nil),   // 4: sequencer.QueryMessageRequest
+	(*StateLog)(nil),              // 5: sequencer.StateLog
+	(*MerkleProofNode)(nil),       // 6: sequencer.MerkleProofNode
+	(*MessageInclusion)(nil),      // 7: sequencer.MessageInclusion
+	(*QueryMessageResponse)(nil),  // 8: sequencer.QueryMessageResponse
+	(*DidDocRequest)(nil),         // 9: sequencer.DidDocRequest
+	(*DidDocResponse)(nil),        // 10: sequencer.DidDocResponse
+	(*CipherRequest)(nil),         // 11: sequencer.CipherRequest
+	(*CipherResponse)(nil),        // 12: sequencer.CipherResponse
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_proto_sequencer_proto_depIdxs = []int32{
+	13, // 0: sequencer.StateLog.time:type_name -> google.protobuf.Timestamp
+	6,  // 1: sequencer.MessageInclusion.proof:type_name -> sequencer.MerkleProofNode
+	5,  // 2: sequencer.QueryMessageResponse.states:type_name -> sequencer.StateLog
+	7,  // 3: sequencer.QueryMessageResponse.inclusion:type_name -> sequencer.MessageInclusion
+	0,  // 4: sequencer.Sequencer.IssueVC:input_type -> sequencer.IssueVCRequest
+	2,  // 5: sequencer.Sequencer.HandleMessage:input_type -> sequencer.HandleMessageRequest
+	4,  // 6: sequencer.Sequencer.QueryMessage:input_type -> sequencer.QueryMessageRequest
+	4,  // 7: sequencer.Sequencer.StreamMessageState:input_type -> sequencer.QueryMessageRequest
+	9,  // 8: sequencer.Sequencer.DidDoc:input_type -> sequencer.DidDocRequest
+	11, // 9: sequencer.Sequencer.HandleMessageCipher:input_type -> sequencer.CipherRequest
+	4,  // 10: sequencer.Sequencer.QueryMessageCipher:input_type -> sequencer.QueryMessageRequest
+	4,  // 11: sequencer.Sequencer.StreamMessageStateCipher:input_type -> sequencer.QueryMessageRequest
+	1,  // 12: sequencer.Sequencer.IssueVC:output_type -> sequencer.IssueVCResponse
+	3,  // 13: sequencer.Sequencer.HandleMessage:output_type -> sequencer.HandleMessageResponse
+	8,  // 14: sequencer.Sequencer.QueryMessage:output_type -> sequencer.QueryMessageResponse
+	5,  // 15: sequencer.Sequencer.StreamMessageState:output_type -> sequencer.StateLog
+	10, // 16: sequencer.Sequencer.DidDoc:output_type -> sequencer.DidDocResponse
+	12, // 17: sequencer.Sequencer.HandleMessageCipher:output_type -> sequencer.CipherResponse
+	12, // 18: sequencer.Sequencer.QueryMessageCipher:output_type -> sequencer.CipherResponse
+	12, // 19: sequencer.Sequencer.StreamMessageStateCipher:output_type -> sequencer.CipherResponse
+	12, // [12:20] is the sub-list for method output_type
+	4,  // [4:12] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_proto_sequencer_proto_init() }
+func file_proto_sequencer_proto_init() {
+	if File_proto_sequencer_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proto_sequencer_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*IssueVCRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*IssueVCResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*HandleMessageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*HandleMessageResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*QueryMessageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*StateLog); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*MerkleProofNode); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*MessageInclusion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*QueryMessageResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*DidDocRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*DidDocResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*CipherRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_sequencer_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*CipherResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_sequencer_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_sequencer_proto_goTypes,
+		DependencyIndexes: file_proto_sequencer_proto_depIdxs,
+		MessageInfos:      file_proto_sequencer_proto_msgTypes,
+	}.Build()
+	File_proto_sequencer_proto = out.File
+	file_proto_sequencer_proto_rawDesc = nil
+	file_proto_sequencer_proto_goTypes = nil
+	file_proto_sequencer_proto_depIdxs = nil
+}
@@ -0,0 +1,418 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v5.27.2
+// source: proto/sequencer.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SequencerClient is the client API for Sequencer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SequencerClient interface {
+	IssueVC(ctx context.Context, in *IssueVCRequest, opts ...grpc.CallOption) (*IssueVCResponse, error)
+	HandleMessage(ctx context.Context, in *HandleMessageRequest, opts ...grpc.CallOption) (*HandleMessageResponse, error)
+	QueryMessage(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (*QueryMessageResponse, error)
+	StreamMessageState(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (Sequencer_StreamMessageStateClient, error)
+	DidDoc(ctx context.Context, in *DidDocRequest, opts ...grpc.CallOption) (*DidDocResponse, error)
+	// the didcomm envelopes of the above for an authenticated client, as the http api: the request is the json of
+	// apitypes.HandleMessageReq encrypted for the sequencer, and the responses are the json of apitypes.HandleMessageRsp,
+	// apitypes.QueryMessageStateLogRsp and apitypes.StateLog encrypted for the client
+	HandleMessageCipher(ctx context.Context, in *CipherRequest, opts ...grpc.CallOption) (*CipherResponse, error)
+	QueryMessageCipher(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (*CipherResponse, error)
+	StreamMessageStateCipher(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (Sequencer_StreamMessageStateCipherClient, error)
+}
+
+type sequencerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSequencerClient(cc grpc.ClientConnInterface) SequencerClient {
+	return &sequencerClient{cc}
+}
+
+func (c *sequencerClient) IssueVC(ctx context.Context, in *IssueVCRequest, opts ...grpc.CallOption) (*IssueVCResponse, error) {
+	out := new(IssueVCResponse)
+	err := c.cc.Invoke(ctx, "/sequencer.Sequencer/IssueVC", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) HandleMessage(ctx context.Context, in *HandleMessageRequest, opts ...grpc.CallOption) (*HandleMessageResponse, error) {
+	out := new(HandleMessageResponse)
+	err := c.cc.Invoke(ctx, "/sequencer.Sequencer/HandleMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) QueryMessage(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (*QueryMessageResponse, error) {
+	out := new(QueryMessageResponse)
+	err := c.cc.Invoke(ctx, "/sequencer.Sequencer/QueryMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) StreamMessageState(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (Sequencer_StreamMessageStateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Sequencer_ServiceDesc.Streams[0], "/sequencer.Sequencer/StreamMessageState", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sequencerStreamMessageStateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Sequencer_StreamMessageStateClient interface {
+	Recv() (*StateLog, error)
+	grpc.ClientStream
+}
+
+type sequencerStreamMessageStateClient struct {
+	grpc.ClientStream
+}
+
+func (x *sequencerStreamMessageStateClient) Recv() (*StateLog, error) {
+	m := new(StateLog)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sequencerClient) DidDoc(ctx context.Context, in *DidDocRequest, opts ...grpc.CallOption) (*DidDocResponse, error) {
+	out := new(DidDocResponse)
+	err := c.cc.Invoke(ctx, "/sequencer.Sequencer/DidDoc", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) HandleMessageCipher(ctx context.Context, in *CipherRequest, opts ...grpc.CallOption) (*CipherResponse, error) {
+	out := new(CipherResponse)
+	err := c.cc.Invoke(ctx, "/sequencer.Sequencer/HandleMessageCipher", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) QueryMessageCipher(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (*CipherResponse, error) {
+	out := new(CipherResponse)
+	err := c.cc.Invoke(ctx, "/sequencer.Sequencer/QueryMessageCipher", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) StreamMessageStateCipher(ctx context.Context, in *QueryMessageRequest, opts ...grpc.CallOption) (Sequencer_StreamMessageStateCipherClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Sequencer_ServiceDesc.Streams[1], "/sequencer.Sequencer/StreamMessageStateCipher", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sequencerStreamMessageStateCipherClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Sequencer_StreamMessageStateCipherClient interface {
+	Recv() (*CipherResponse, error)
+	grpc.ClientStream
+}
+
+type sequencerStreamMessageStateCipherClient struct {
+	grpc.ClientStream
+}
+
+func (x *sequencerStreamMessageStateCipherClient) Recv() (*CipherResponse, error) {
+	m := new(CipherResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SequencerServer is the server API for Sequencer service.
+// All implementations must embed UnimplementedSequencerServer
+// for forward compatibility
+type SequencerServer interface {
+	IssueVC(context.Context, *IssueVCRequest) (*IssueVCResponse, error)
+	HandleMessage(context.Context, *HandleMessageRequest) (*HandleMessageResponse, error)
+	QueryMessage(context.Context, *QueryMessageRequest) (*QueryMessageResponse, error)
+	StreamMessageState(*QueryMessageRequest, Sequencer_StreamMessageStateServer) error
+	DidDoc(context.Context, *DidDocRequest) (*DidDocResponse, error)
+	// the didcomm envelopes of the above for an authenticated client, as the http api: the request is the json of
+	// apitypes.HandleMessageReq encrypted for the sequencer, and the responses are the json of apitypes.HandleMessageRsp,
+	// apitypes.QueryMessageStateLogRsp and apitypes.StateLog encrypted for the client
+	HandleMessageCipher(context.Context, *CipherRequest) (*CipherResponse, error)
+	QueryMessageCipher(context.Context, *QueryMessageRequest) (*CipherResponse, error)
+	StreamMessageStateCipher(*QueryMessageRequest, Sequencer_StreamMessageStateCipherServer) error
+	mustEmbedUnimplementedSequencerServer()
+}
+
+// UnimplementedSequencerServer must be embedded to have forward compatible implementations.
+type UnimplementedSequencerServer struct {
+}
+
+func (UnimplementedSequencerServer) IssueVC(context.Context, *IssueVCRequest) (*IssueVCResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueVC not implemented")
+}
+func (UnimplementedSequencerServer) HandleMessage(context.Context, *HandleMessageRequest) (*HandleMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleMessage not implemented")
+}
+func (UnimplementedSequencerServer) QueryMessage(context.Context, *QueryMessageRequest) (*QueryMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryMessage not implemented")
+}
+func (UnimplementedSequencerServer) StreamMessageState(*QueryMessageRequest, Sequencer_StreamMessageStateServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMessageState not implemented")
+}
+func (UnimplementedSequencerServer) DidDoc(context.Context, *DidDocRequest) (*DidDocResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DidDoc not implemented")
+}
+func (UnimplementedSequencerServer) HandleMessageCipher(context.Context, *CipherRequest) (*CipherResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleMessageCipher not implemented")
+}
+func (UnimplementedSequencerServer) QueryMessageCipher(context.Context, *QueryMessageRequest) (*CipherResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryMessageCipher not implemented")
+}
+func (UnimplementedSequencerServer) StreamMessageStateCipher(*QueryMessageRequest, Sequencer_StreamMessageStateCipherServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMessageStateCipher not implemented")
+}
+func (UnimplementedSequencerServer) mustEmbedUnimplementedSequencerServer() {}
+
+// UnsafeSequencerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SequencerServer will
+// result in compilation errors.
+type UnsafeSequencerServer interface {
+	mustEmbedUnimplementedSequencerServer()
+}
+
+func RegisterSequencerServer(s grpc.ServiceRegistrar, srv SequencerServer) {
+	s.RegisterService(&Sequencer_ServiceDesc, srv)
+}
+
+func _Sequencer_IssueVC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueVCRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).IssueVC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sequencer.Sequencer/IssueVC",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).IssueVC(ctx, req.(*IssueVCRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_HandleMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandleMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).HandleMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sequencer.Sequencer/HandleMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).HandleMessage(ctx, req.(*HandleMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_QueryMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).QueryMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sequencer.Sequencer/QueryMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).QueryMessage(ctx, req.(*QueryMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_StreamMessageState_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryMessageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SequencerServer).StreamMessageState(m, &sequencerStreamMessageStateServer{stream})
+}
+
+type Sequencer_StreamMessageStateServer interface {
+	Send(*StateLog) error
+	grpc.ServerStream
+}
+
+type sequencerStreamMessageStateServer struct {
+	grpc.ServerStream
+}
+
+func (x *sequencerStreamMessageStateServer) Send(m *StateLog) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Sequencer_DidDoc_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DidDocRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).DidDoc(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sequencer.Sequencer/DidDoc",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).DidDoc(ctx, req.(*DidDocRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_HandleMessageCipher_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CipherRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).HandleMessageCipher(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sequencer.Sequencer/HandleMessageCipher",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).HandleMessageCipher(ctx, req.(*CipherRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_QueryMessageCipher_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).QueryMessageCipher(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sequencer.Sequencer/QueryMessageCipher",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).QueryMessageCipher(ctx, req.(*QueryMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_StreamMessageStateCipher_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryMessageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SequencerServer).StreamMessageStateCipher(m, &sequencerStreamMessageStateCipherServer{stream})
+}
+
+type Sequencer_StreamMessageStateCipherServer interface {
+	Send(*CipherResponse) error
+	grpc.ServerStream
+}
+
+type sequencerStreamMessageStateCipherServer struct {
+	grpc.ServerStream
+}
+
+func (x *sequencerStreamMessageStateCipherServer) Send(m *CipherResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Sequencer_ServiceDesc is the grpc.ServiceDesc for Sequencer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sequencer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sequencer.Sequencer",
+	HandlerType: (*SequencerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IssueVC",
+			Handler:    _Sequencer_IssueVC_Handler,
+		},
+		{
+			MethodName: "HandleMessage",
+			Handler:    _Sequencer_HandleMessage_Handler,
+		},
+		{
+			MethodName: "QueryMessage",
+			Handler:    _Sequencer_QueryMessage_Handler,
+		},
+		{
+			MethodName: "DidDoc",
+			Handler:    _Sequencer_DidDoc_Handler,
+		},
+		{
+			MethodName: "HandleMessageCipher",
+			Handler:    _Sequencer_HandleMessageCipher_Handler,
+		},
+		{
+			MethodName: "QueryMessageCipher",
+			Handler:    _Sequencer_QueryMessageCipher_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMessageState",
+			Handler:       _Sequencer_StreamMessageState_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamMessageStateCipher",
+			Handler:       _Sequencer_StreamMessageStateCipher_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/sequencer.proto",
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+	"github.com/machinefi/sprout/cmd/sequencer/ratelimit"
+	"github.com/machinefi/sprout/metrics"
+)
+
+// the metrics label of clients without token
+const anonymousClient = "anonymous"
+
+func clientLabel(client *clients.Client) string {
+	if client == nil {
+		return anonymousClient
+	}
+	return client.DID()
+}
+
+// allow takes n messages of the project from the rate limits, a client without token is limited by its ip
+func (s *httpServer) allow(c *gin.Context, client *clients.Client, projectID uint64, n int) *ratelimit.Rejection {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.allowKey(rateKey(client, c.ClientIP()), client, projectID, n)
+}
+
+// allowKey takes n messages of the project from the rate limits of the key
+func (s *httpServer) allowKey(key string, client *clients.Client, projectID uint64, n int) *ratelimit.Rejection {
+	if s.limiter == nil {
+		return nil
+	}
+	rej := s.limiter.Allow(key, projectID, uint64(n))
+	if rej != nil {
+		metrics.RejectedMessageNumMtc(clientLabel(client), projectID, rej.Reason, n)
+	}
+	return rej
+}
+
+// allowBatch rate limits the messages by project, and returns the allowed messages with their results. the results
+// of the rejected messages are filled with the rejection, and the returned rejection is the one of the longest retry
+func (s *httpServer) allowBatch(c *gin.Context, client *clients.Client, msgs []*persistence.Message, results []*apitypes.HandleMessagesItem) ([]*persistence.Message, []*apitypes.HandleMessagesItem, *ratelimit.Rejection) {
+	if s.limiter == nil {
+		return msgs, results, nil
+	}
+	projectIDs, counts := []uint64{}, map[uint64]int{}
+	for _, m := range msgs {
+		if _, ok := counts[m.ProjectID]; !ok {
+			projectIDs = append(projectIDs, m.ProjectID)
+		}
+		counts[m.ProjectID]++
+	}
+	rejections := map[uint64]*ratelimit.Rejection{}
+	var longest *ratelimit.Rejection
+	for _, pid := range projectIDs {
+		if rej := s.allow(c, client, pid, counts[pid]); rej != nil {
+			rejections[pid] = rej
+			if longest == nil || rej.RetryAfter > longest.RetryAfter {
+				longest = rej
+			}
+		}
+	}
+	if len(rejections) == 0 {
+		return msgs, results, nil
+	}
+
+	allowed := make([]*persistence.Message, 0, len(msgs))
+	allowedResults := make([]*apitypes.HandleMessagesItem, 0, len(msgs))
+	for i, m := range msgs {
+		if rej, ok := rejections[m.ProjectID]; ok {
+			results[i].Error = rej.Error()
+			continue
+		}
+		allowed = append(allowed, m)
+		allowedResults = append(allowedResults, results[i])
+	}
+	return allowed, allowedResults, longest
+}
+
+// setRetryAfter sets the Retry-After header in seconds, rounded up
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10))
+}
+
+func rejectLimited(c *gin.Context, rej *ratelimit.Rejection) {
+	setRetryAfter(c, rej.RetryAfter)
+	c.JSON(http.StatusTooManyRequests, apitypes.NewErrRsp(rej))
+}
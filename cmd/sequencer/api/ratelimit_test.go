@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+	"github.com/machinefi/sprout/cmd/sequencer/ratelimit"
+)
+
+func TestHttpServer_allow(t *testing.T) {
+	r := require.New(t)
+
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{Client: &ratelimit.Limit{DailyQuota: 1}}, nil, nil)
+	r.NoError(err)
+	s := &httpServer{limiter: limiter}
+
+	newContext := func(ip string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		c.Request.RemoteAddr = ip + ":1234"
+		return c
+	}
+
+	t.Run("NoLimiter", func(t *testing.T) {
+		r.Nil((&httpServer{}).allow(newContext("127.0.0.1"), nil, 1, 10))
+	})
+
+	t.Run("AnonymousByIP", func(t *testing.T) {
+		r.Nil(s.allow(newContext("127.0.0.1"), nil, 1, 1))
+		r.NotNil(s.allow(newContext("127.0.0.1"), nil, 1, 1))
+		r.Nil(s.allow(newContext("127.0.0.2"), nil, 1, 1))
+	})
+
+	t.Run("ClientByDID", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		r.Nil(s.allow(newContext("127.0.0.3"), &clients.Client{}, 1, 1))
+		rej := s.allow(newContext("127.0.0.4"), &clients.Client{}, 1, 1)
+		r.NotNil(rej)
+		r.Equal(ratelimit.ReasonQuotaExceeded, rej.Reason)
+	})
+}
+
+func TestHttpServer_allowBatch(t *testing.T) {
+	r := require.New(t)
+
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{Projects: map[string]*ratelimit.Limit{"2": {DailyQuota: 1}}}, nil, nil)
+	r.NoError(err)
+	s := &httpServer{limiter: limiter}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	msgs := []*persistence.Message{{ProjectID: 1}, {ProjectID: 2}, {ProjectID: 1}, {ProjectID: 2}}
+	results := []*apitypes.HandleMessagesItem{{}, {}, {}, {}}
+
+	t.Run("NoLimiter", func(t *testing.T) {
+		allowed, allowedResults, rej := (&httpServer{}).allowBatch(c, nil, msgs, results)
+		r.Nil(rej)
+		r.Equal(msgs, allowed)
+		r.Equal(results, allowedResults)
+	})
+
+	t.Run("PartiallyRejected", func(t *testing.T) {
+		allowed, allowedResults, rej := s.allowBatch(c, nil, msgs, results)
+		r.NotNil(rej)
+		r.Equal([]*persistence.Message{msgs[0], msgs[2]}, allowed)
+		r.Equal([]*apitypes.HandleMessagesItem{results[0], results[2]}, allowedResults)
+		r.Contains(results[1].Error, "exceeds daily quota")
+		r.Contains(results[3].Error, "exceeds daily quota")
+	})
+}
+
+func TestHttpServer_rateLimited(t *testing.T) {
+	r := require.New(t)
+
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{Project: &ratelimit.Limit{Rate: 1}}, nil, nil)
+	r.NoError(err)
+	s := &httpServer{
+		aggregation: func(uint64) *persistence.Aggregation { return &persistence.Aggregation{Amount: 1} },
+		limiter:     limiter,
+	}
+
+	t.Run("Message", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&persistence.Persistence{}, "Save", "id", nil)
+		for i, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"projectID": 1, "projectVersion": "v1", "data": "a"}`)))
+
+			s.handleMessage(c)
+			r.Equal(code, w.Code, i)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethod(&persistence.Persistence{}, "SaveBatch", func(_ *persistence.Persistence, msgs []*persistence.Message, _ persistence.AggregationPolicy, _ *ecdsa.PrivateKey) ([]string, error) {
+			ids := []string{}
+			for _, m := range msgs {
+				ids = append(ids, m.MessageID)
+			}
+			return ids, nil
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`[{"projectID": 2, "projectVersion": "v1", "data": "a"}, {"projectID": 1, "projectVersion": "v1", "data": "b"}]`)))
+		s.handleMessages(c)
+		r.Equal(http.StatusOK, w.Code)
+		r.Equal("1", w.Header().Get("Retry-After"))
+
+		rsp := &apitypes.HandleMessagesRsp{}
+		r.NoError(json.Unmarshal(w.Body.Bytes(), rsp))
+		r.NotEmpty(rsp.Messages[0].MessageID)
+		r.Contains(rsp.Messages[1].Error, "exceeds rate limit")
+
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`[{"projectID": 1, "projectVersion": "v1", "data": "c"}]`)))
+		s.handleMessages(c)
+		r.Equal(http.StatusTooManyRequests, w.Code)
+		r.NotEmpty(w.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+)
+
+const (
+	// the interval of rechecking whether the message is packed, in case the packed notification is lost
+	packedRecheckInterval = 10 * time.Second
+	// the max size of a server sent event from coordinator
+	maxEventSize = 1 << 20
+)
+
+var errStreamClosed = errors.New("stream closed by client")
+
+// streamMessageState pushes the message states as server sent events: received, packed, and then the task states
+// relayed from the coordinator stream until the task is outputted or failed. the events for a DIDComm client are
+// encrypted and base64 encoded
+func (s *httpServer) streamMessageState(c *gin.Context) {
+	client := clients.ClientIDFrom(c.Request.Context())
+	started := false
+	err := s.followMessageState(c.Request.Context(), client, c.Param("id"), func(l *apitypes.StateLog) error {
+		if !started {
+			c.Header("Cache-Control", "no-cache")
+			started = true
+		}
+		return s.sendState(c, client, l)
+	})
+	switch {
+	case err == nil:
+	case !started:
+		writeError(c, err)
+	default:
+		s.sendError(c, err)
+	}
+}
+
+// followTaskStates relays the task states from the coordinator stream to fn until the stream ends
+func (s *httpServer) followTaskStates(ctx context.Context, projectID, taskID uint64, fn func(*apitypes.StateLog) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("http://%s/task/%d/%d/stream", s.coordinatorAddress, projectID, taskID), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to new coordinator stream request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect coordinator stream")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to connect coordinator stream, status %d", resp.StatusCode)
+	}
+
+	return readEvents(resp.Body, func(event string, data []byte) error {
+		if event != apitypes.StateLogEvent {
+			return nil
+		}
+		l := &apitypes.StateLog{}
+		if err := json.Unmarshal(data, l); err != nil {
+			return errors.Wrap(err, "failed to unmarshal coordinator state log")
+		}
+		return fn(l)
+	})
+}
+
+// waitPacked returns the task which packs the message, nil if ctx is done before packed
+func (s *httpServer) waitPacked(ctx context.Context, m *persistence.Message, packed <-chan *persistence.Task) (*persistence.Task, error) {
+	ticker := time.NewTicker(packedRecheckInterval)
+	defer ticker.Stop()
+
+	internalTaskID := m.InternalTaskID
+	for internalTaskID == "" {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case t, ok := <-packed:
+			if !ok {
+				// dropped as slow subscriber, rely on rechecking
+				packed = nil
+				continue
+			}
+			return t, nil
+		case <-ticker.C:
+			ms, err := s.p.FetchMessage(m.MessageID)
+			if err != nil {
+				return nil, err
+			}
+			if len(ms) > 0 {
+				internalTaskID = ms[0].InternalTaskID
+			}
+		}
+	}
+
+	ts, err := s.p.FetchTask(internalTaskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ts) == 0 {
+		return nil, errors.New("cannot find task by internal task id")
+	}
+	return ts[0], nil
+}
+
+// sendState sends the state log event, which is encrypted for the client if the request is authenticated
+func (s *httpServer) sendState(c *gin.Context, client *clients.Client, l *apitypes.StateLog) error {
+	if c.Request.Context().Err() != nil {
+		return errStreamClosed
+	}
+	if client != nil {
+		cipher, err := s.jwk.EncryptJSON(l, client.KeyAgreementKID())
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt state log")
+		}
+		c.SSEvent(apitypes.StateLogEvent, base64.StdEncoding.EncodeToString(cipher))
+	} else {
+		c.SSEvent(apitypes.StateLogEvent, l)
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// sendError sends the error event unless the client is gone
+func (s *httpServer) sendError(c *gin.Context, err error) {
+	if errors.Is(err, errStreamClosed) || c.Request.Context().Err() != nil {
+		return
+	}
+	c.SSEvent(apitypes.ErrorEvent, apitypes.NewErrRsp(err))
+	c.Writer.Flush()
+}
+
+// readEvents reads the server sent events, and calls fn with each event until fn returns error or the stream ends
+func readEvents(r io.Reader, fn func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+
+	event, data := "", [][]byte{}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			if len(data) > 0 {
+				if err := fn(event, bytes.Join(data, []byte("\n"))); err != nil {
+					return err
+				}
+			}
+			event, data = "", [][]byte{}
+			continue
+		}
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "event":
+			event = string(value)
+		case "data":
+			data = append(data, bytes.Clone(value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read server sent events")
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/machinefi/ioconnect-go/pkg/ioconnect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/machinefi/sprout/apitypes"
+	"github.com/machinefi/sprout/clients"
+	"github.com/machinefi/sprout/cmd/sequencer/persistence"
+)
+
+func TestReadEvents(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Events", func(t *testing.T) {
+		events := []string{}
+		err := readEvents(strings.NewReader("event:state\ndata:a\ndata: b\n\n: comment\n\nevent:error\ndata:c\n\n"), func(event string, data []byte) error {
+			events = append(events, event+"="+string(data))
+			return nil
+		})
+		r.NoError(err)
+		r.Equal([]string{"state=a\nb", "error=c"}, events)
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		err := readEvents(strings.NewReader("event:state\ndata:a\n\nevent:state\ndata:b\n\n"), func(string, []byte) error {
+			return errors.New(t.Name())
+		})
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("EventTooLarge", func(t *testing.T) {
+		err := readEvents(strings.NewReader("data:"+strings.Repeat("a", maxEventSize)+"\n\n"), func(string, []byte) error {
+			return nil
+		})
+		r.ErrorContains(err, "failed to read server sent events")
+	})
+}
+
+func TestHttpServer_streamMessageState(t *testing.T) {
+	r := require.New(t)
+
+	coordinator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/task/1/3/stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event:state\ndata:{\"state\":\"dispatched\"}\n\nevent:state\ndata:{\"state\":\"outputted\"}\n\n"))
+	}))
+	defer coordinator.Close()
+
+	s := &httpServer{
+		coordinatorAddress: strings.TrimPrefix(coordinator.URL, "http://"),
+	}
+	newContext := func() (*gin.Context, *httptest.ResponseRecorder, context.CancelFunc) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		ctx, cancel := context.WithCancel(context.Background())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		c.Params = gin.Params{{Key: "id", Value: "message"}}
+		return c, w, cancel
+	}
+	patchSubscribe := func(p *Patches, ch <-chan *persistence.Task) {
+		p.ApplyMethodReturn(&persistence.Persistence{}, "SubscribePacked", ch, func() {})
+	}
+
+	t.Run("FailedToFetchMessage", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		patchSubscribe(p, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", nil, errors.New(t.Name()))
+		s.streamMessageState(c)
+		r.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("MessageNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		patchSubscribe(p, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{}, nil)
+		s.streamMessageState(c)
+		r.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("UnmatchedClient", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		patchSubscribe(p, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{ClientID: "other"}}, nil)
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		s.streamMessageState(c)
+		r.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("ClientGoneBeforePacked", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+
+		patchSubscribe(p, make(chan *persistence.Task))
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message"}}, nil)
+		go cancel()
+		s.streamMessageState(c)
+		r.LessOrEqual(strings.Count(w.Body.String(), "event:"+apitypes.StateLogEvent), 1)
+		r.NotContains(w.Body.String(), "event:"+apitypes.ErrorEvent)
+	})
+
+	t.Run("FailedToFetchTask", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		patchSubscribe(p, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", InternalTaskID: "task"}}, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchTask", []*persistence.Task{}, nil)
+		s.streamMessageState(c)
+		r.Contains(w.Body.String(), "event:"+apitypes.ErrorEvent)
+		r.Contains(w.Body.String(), "cannot find task")
+	})
+
+	t.Run("CoordinatorUnavailable", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		ch := make(chan *persistence.Task, 1)
+		ch <- &persistence.Task{Model: gorm.Model{ID: 4}}
+		patchSubscribe(p, ch)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ProjectID: 1}}, nil)
+		s.streamMessageState(c)
+		r.Contains(w.Body.String(), "event:"+apitypes.ErrorEvent)
+		r.Contains(w.Body.String(), "status 404")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		ch := make(chan *persistence.Task, 1)
+		ch <- &persistence.Task{Model: gorm.Model{ID: 3}}
+		patchSubscribe(p, ch)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ProjectID: 1}}, nil)
+		s.streamMessageState(c)
+		r.Equal(http.StatusOK, w.Code)
+
+		body := w.Body.String()
+		r.Equal(4, strings.Count(body, "event:"+apitypes.StateLogEvent))
+		for _, state := range []string{"received", "packed", "dispatched", "outputted"} {
+			r.Contains(body, `"state":"`+state+`"`)
+		}
+	})
+
+	t.Run("SuccessWithClient", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		patchSubscribe(p, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ClientID: "did", ProjectID: 1, InternalTaskID: "task"}}, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchTask", []*persistence.Task{{Model: gorm.Model{ID: 3}}}, nil)
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", []byte("cipher"), nil)
+		s.streamMessageState(c)
+
+		body := w.Body.String()
+		r.Equal(4, strings.Count(body, "data:"+base64.StdEncoding.EncodeToString([]byte("cipher"))))
+		r.NotContains(body, "received")
+	})
+
+	t.Run("FailedToEncrypt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		c, w, cancel := newContext()
+		defer cancel()
+
+		patchSubscribe(p, nil)
+		p.ApplyMethodReturn(&persistence.Persistence{}, "FetchMessage", []*persistence.Message{{MessageID: "message", ClientID: "did", ProjectID: 1}}, nil)
+		p.ApplyFuncReturn(clients.ClientIDFrom, &clients.Client{})
+		p.ApplyMethodReturn(&clients.Client{}, "DID", "did")
+		p.ApplyMethodReturn(&clients.Client{}, "KeyAgreementKID", "")
+		p.ApplyMethodReturn(&clients.Manager{}, "HasProjectPermission", true, nil)
+		p.ApplyMethodReturn(&ioconnect.JWK{}, "EncryptJSON", nil, errors.New(t.Name()))
+		s.streamMessageState(c)
+		r.Contains(w.Body.String(), "event:"+apitypes.ErrorEvent)
+		r.Contains(w.Body.String(), t.Name())
+	})
+}
@@ -0,0 +1,150 @@
+package persistence
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Aggregation is the policy of packing messages into tasks
+type Aggregation struct {
+	Amount        uint          // the max amount of messages packed into one task
+	MaxLatency    time.Duration // the max time a partial batch waits before packed, 0 means waiting until the batch is full
+	AcrossClients bool          // whether messages of different clients are packed into the same task
+}
+
+// AggregationPolicy returns the aggregation of the project
+type AggregationPolicy func(projectID uint64) *Aggregation
+
+// AggregationConfig is a partial aggregation, an empty field falls back to the lower priority one
+type AggregationConfig struct {
+	Amount        uint   `json:"amount,omitempty"`
+	MaxLatency    string `json:"maxLatency,omitempty"` // e.g. 30s
+	AcrossClients *bool  `json:"acrossClients,omitempty"`
+}
+
+func (c *AggregationConfig) apply(ag *Aggregation) error {
+	if c.Amount > 0 {
+		ag.Amount = c.Amount
+	}
+	if c.MaxLatency != "" {
+		latency, err := time.ParseDuration(c.MaxLatency)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse max latency %s", c.MaxLatency)
+		}
+		ag.MaxLatency = latency
+	}
+	if c.AcrossClients != nil {
+		ag.AcrossClients = *c.AcrossClients
+	}
+	return nil
+}
+
+// ProjectAggregation returns the aggregation defined by the project itself, e.g. by the project file or the project
+// contract attributes. returns nil if the project defines none
+type ProjectAggregation func(projectID uint64) (*AggregationConfig, error)
+
+// Aggregations resolves the aggregation of projects, the priority is the override of the aggregation file, then the
+// one defined by the project, then the default. the resolved aggregations are cached until the project is updated
+type Aggregations struct {
+	defaultAggregation *Aggregation
+	overrides          map[uint64]*AggregationConfig
+	projectAggregation ProjectAggregation // optional
+
+	mux   sync.Mutex
+	cache map[uint64]*Aggregation
+}
+
+func (a *Aggregations) resolve(projectID uint64) (*Aggregation, bool) {
+	ag := *a.defaultAggregation
+	cacheable := true
+	if a.projectAggregation != nil {
+		c, err := a.projectAggregation(projectID)
+		if err != nil {
+			// fall back without caching, the project aggregation will be fetched again next time
+			slog.Error("failed to get project aggregation", "project_id", projectID, "error", err)
+			cacheable = false
+		} else if c != nil {
+			if err := c.apply(&ag); err != nil {
+				slog.Error("invalid project aggregation", "project_id", projectID, "error", err)
+			}
+		}
+	}
+	if c, ok := a.overrides[projectID]; ok {
+		// validated when loaded
+		_ = c.apply(&ag)
+	}
+	return &ag, cacheable
+}
+
+// Of returns the aggregation of the project
+func (a *Aggregations) Of(projectID uint64) *Aggregation {
+	a.mux.Lock()
+	ag, ok := a.cache[projectID]
+	a.mux.Unlock()
+	if ok {
+		return ag
+	}
+
+	ag, cacheable := a.resolve(projectID)
+	if cacheable {
+		a.mux.Lock()
+		a.cache[projectID] = ag
+		a.mux.Unlock()
+	}
+	return ag
+}
+
+// Refresh drops the cached aggregation of the project
+func (a *Aggregations) Refresh(projectID uint64) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	delete(a.cache, projectID)
+}
+
+// Watch refreshes the aggregation of the updated projects, this func will block caller
+func (a *Aggregations) Watch(projectNotification <-chan uint64) {
+	for pid := range projectNotification {
+		a.Refresh(pid)
+	}
+}
+
+func NewAggregations(defaultAggregation *Aggregation, overrides map[string]*AggregationConfig, projectAggregation ProjectAggregation) (*Aggregations, error) {
+	a := &Aggregations{
+		defaultAggregation: defaultAggregation,
+		overrides:          map[uint64]*AggregationConfig{},
+		projectAggregation: projectAggregation,
+		cache:              map[uint64]*Aggregation{},
+	}
+	for k, c := range overrides {
+		projectID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid project id %s in aggregation config", k)
+		}
+		if err := c.apply(&Aggregation{}); err != nil {
+			return nil, errors.Wrapf(err, "invalid aggregation of project %d", projectID)
+		}
+		a.overrides[projectID] = c
+	}
+	return a, nil
+}
+
+// LoadAggregationOverrides loads the aggregation file, which is a json object from project id to aggregation config,
+// e.g. {"1": {"amount": 10, "maxLatency": "30s", "acrossClients": true}}
+func LoadAggregationOverrides(path string) (map[string]*AggregationConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read aggregation file %s", path)
+	}
+	overrides := map[string]*AggregationConfig{}
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode aggregation file %s", path)
+	}
+	return overrides, nil
+}
@@ -0,0 +1,122 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAggregations(t *testing.T) {
+	r := require.New(t)
+
+	def := &Aggregation{Amount: 10, MaxLatency: time.Minute}
+
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		_, err := NewAggregations(def, map[string]*AggregationConfig{"any": {}}, nil)
+		r.Error(err)
+	})
+	t.Run("InvalidMaxLatency", func(t *testing.T) {
+		_, err := NewAggregations(def, map[string]*AggregationConfig{"1": {MaxLatency: "any"}}, nil)
+		r.Error(err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		across := true
+		a, err := NewAggregations(def, map[string]*AggregationConfig{
+			"1": {Amount: 5},
+			"2": {MaxLatency: "1s", AcrossClients: &across},
+		}, nil)
+		r.NoError(err)
+		r.Equal(&Aggregation{Amount: 5, MaxLatency: time.Minute}, a.Of(1))
+		r.Equal(&Aggregation{Amount: 10, MaxLatency: time.Second, AcrossClients: true}, a.Of(2))
+		r.Equal(def, a.Of(3))
+	})
+}
+
+func TestAggregations_Of(t *testing.T) {
+	r := require.New(t)
+
+	def := &Aggregation{Amount: 1}
+	fetched := 0
+	var projectErr error
+	projectAggregation := func(projectID uint64) (*AggregationConfig, error) {
+		fetched++
+		if projectErr != nil {
+			return nil, projectErr
+		}
+		across := true
+		switch projectID {
+		case 1:
+			return &AggregationConfig{Amount: 20, MaxLatency: "1m", AcrossClients: &across}, nil
+		case 2:
+			return &AggregationConfig{MaxLatency: "any"}, nil
+		}
+		return nil, nil
+	}
+	a, err := NewAggregations(def, map[string]*AggregationConfig{"1": {Amount: 5}}, projectAggregation)
+	r.NoError(err)
+
+	t.Run("OverrideProject", func(t *testing.T) {
+		r.Equal(&Aggregation{Amount: 5, MaxLatency: time.Minute, AcrossClients: true}, a.Of(1))
+	})
+	t.Run("InvalidProjectAggregation", func(t *testing.T) {
+		r.Equal(def, a.Of(2))
+	})
+	t.Run("Cached", func(t *testing.T) {
+		n := fetched
+		a.Of(1)
+		r.Equal(n, fetched)
+
+		a.Refresh(1)
+		a.Of(1)
+		r.Equal(n+1, fetched)
+	})
+	t.Run("NotCachedIfFailed", func(t *testing.T) {
+		projectErr = errors.New(t.Name())
+		defer func() { projectErr = nil }()
+
+		r.Equal(def, a.Of(3))
+		r.NotContains(a.cache, uint64(3))
+	})
+	t.Run("Watch", func(t *testing.T) {
+		a.Of(4)
+		r.Contains(a.cache, uint64(4))
+
+		n := make(chan uint64)
+		go a.Watch(n)
+		n <- 4
+		n <- 4 // make sure the first notification was handled
+		close(n)
+
+		a.mux.Lock()
+		defer a.mux.Unlock()
+		r.NotContains(a.cache, uint64(4))
+	})
+}
+
+func TestLoadAggregationOverrides(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("FailedToRead", func(t *testing.T) {
+		_, err := LoadAggregationOverrides(filepath.Join(t.TempDir(), "none.json"))
+		r.Error(err)
+	})
+	t.Run("FailedToDecode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "aggregation.json")
+		r.NoError(os.WriteFile(path, []byte("{"), 0600))
+		_, err := LoadAggregationOverrides(path)
+		r.Error(err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "aggregation.json")
+		r.NoError(os.WriteFile(path, []byte(`{"1": {"amount": 10, "maxLatency": "30s", "acrossClients": true}}`), 0600))
+		overrides, err := LoadAggregationOverrides(path)
+		r.NoError(err)
+		r.Equal(uint(10), overrides["1"].Amount)
+		r.Equal("30s", overrides["1"].MaxLatency)
+		r.True(*overrides["1"].AcrossClients)
+	})
+}
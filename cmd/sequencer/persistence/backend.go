@@ -0,0 +1,31 @@
+package persistence
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SQLiteScheme is the dsn prefix of the embedded sqlite backend, e.g. sqlite:./sequencer.db, the dsn without it is
+// a postgres endpoint
+const SQLiteScheme = "sqlite:"
+
+// backend is the database specific part of the persistence, the messages are deduplicated and aggregated the same way
+// on every backend
+type backend interface {
+	// dialector returns the gorm dialector of the database
+	dialector() gorm.Dialector
+	// prepare configures the opened database
+	prepare(db *gorm.DB) error
+	// notifyPackedTx notifies the packed task, the notification is only delivered after tx committed
+	notifyPackedTx(tx *gorm.DB, t *Task) error
+	// listen calls publish with the internal task id of notified packed tasks, this func will block caller
+	listen(db *gorm.DB, publish func(internalTaskID string)) error
+}
+
+func newBackend(dsn string) backend {
+	if strings.HasPrefix(dsn, SQLiteScheme) {
+		return &sqliteBackend{dsn: strings.TrimPrefix(dsn, SQLiteScheme)}
+	}
+	return &postgresBackend{dsn: dsn}
+}
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InlineDataSize is the max size of message data stored in the message table, the larger data is stored in the blob
+// table and referenced by its hash
+const InlineDataSize = 4096
+
+// Blob is the content addressed message data, the same data submitted repeatedly is stored once
+type Blob struct {
+	gorm.Model
+	Hash string `gorm:"uniqueIndex:blob_hash,not null"`
+	Data []byte `gorm:"not null"`
+}
+
+func blobHash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// storedMessageTx returns the message to be stored, the data exceeding the inline size is saved as blob and the
+// returned copy references it. m is not modified so the transaction could be retried
+func (p *Persistence) storedMessageTx(tx *gorm.DB, m *Message) (*Message, error) {
+	if len(m.Data) <= InlineDataSize {
+		return m, nil
+	}
+	b := &Blob{Hash: blobHash(m.Data), Data: m.Data}
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "hash"}}, DoNothing: true}).Create(b).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create message blob, message_id %s", m.MessageID)
+	}
+	stored := *m
+	stored.Data = nil
+	stored.DataHash = b.Hash
+	return &stored, nil
+}
+
+// messageDataTx returns the data of messages in order, the data stored as blob is rehydrated
+func (p *Persistence) messageDataTx(tx *gorm.DB, messages []*Message) ([][]byte, error) {
+	hashes := make([]string, 0)
+	for _, m := range messages {
+		if m.DataHash != "" {
+			hashes = append(hashes, m.DataHash)
+		}
+	}
+	blobs := map[string][]byte{}
+	if len(hashes) > 0 {
+		bs := []*Blob{}
+		if err := tx.Where("hash IN ?", hashes).Find(&bs).Error; err != nil {
+			return nil, errors.Wrap(err, "failed to fetch message blobs")
+		}
+		for _, b := range bs {
+			blobs[b.Hash] = b.Data
+		}
+	}
+
+	data := make([][]byte, 0, len(messages))
+	for _, m := range messages {
+		if m.DataHash == "" {
+			data = append(data, m.Data)
+			continue
+		}
+		d, ok := blobs[m.DataHash]
+		if !ok {
+			return nil, errors.Errorf("message blob not exist, message_id %s hash %s", m.MessageID, m.DataHash)
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
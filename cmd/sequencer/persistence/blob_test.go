@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestPersistence_storedMessageTx(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{}
+	tx := &gorm.DB{Statement: &gorm.Statement{}}
+	large := bytes.Repeat([]byte("a"), InlineDataSize+1)
+
+	t.Run("Inline", func(t *testing.T) {
+		m := &Message{Data: []byte("data")}
+		stored, err := ps.storedMessageTx(tx, m)
+		r.NoError(err)
+		r.Equal(m, stored)
+	})
+
+	t.Run("FailedToCreateBlob", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Clauses", tx)
+		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{Error: errors.New(t.Name())})
+		_, err := ps.storedMessageTx(tx, &Message{Data: large})
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		var blob *Blob
+		p.ApplyMethodReturn(&gorm.DB{}, "Clauses", tx)
+		p.ApplyMethod(&gorm.DB{}, "Create", func(_ *gorm.DB, v any) *gorm.DB {
+			blob = v.(*Blob)
+			return &gorm.DB{}
+		})
+		m := &Message{MessageID: "id", Data: large}
+		stored, err := ps.storedMessageTx(tx, m)
+		r.NoError(err)
+		r.Equal(large, blob.Data)
+		r.Equal(blobHash(large), blob.Hash)
+		r.Equal("id", stored.MessageID)
+		r.Empty(stored.Data)
+		r.Equal(blob.Hash, stored.DataHash)
+		r.Equal(large, m.Data)
+		r.Empty(m.DataHash)
+	})
+}
+
+func TestPersistence_messageDataTx(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{}
+	tx := &gorm.DB{Statement: &gorm.Statement{}}
+	messages := []*Message{{Data: []byte("a")}, {DataHash: "hash"}}
+
+	t.Run("Inline", func(t *testing.T) {
+		data, err := ps.messageDataTx(tx, messages[:1])
+		r.NoError(err)
+		r.Equal([][]byte{[]byte("a")}, data)
+	})
+
+	t.Run("FailedToFetchBlobs", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", tx)
+		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{Error: errors.New(t.Name())})
+		_, err := ps.messageDataTx(tx, messages)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("BlobNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", tx)
+		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{})
+		_, err := ps.messageDataTx(tx, messages)
+		r.ErrorContains(err, "message blob not exist")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", tx)
+		p.ApplyMethod(&gorm.DB{}, "Find", func(_ *gorm.DB, dest any, _ ...any) *gorm.DB {
+			*dest.(*[]*Blob) = []*Blob{{Hash: "hash", Data: []byte("b")}}
+			return &gorm.DB{}
+		})
+		data, err := ps.messageDataTx(tx, messages)
+		r.NoError(err)
+		r.Equal([][]byte{[]byte("a"), []byte("b")}, data)
+	})
+}
@@ -0,0 +1,104 @@
+package persistence
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/machinefi/sprout/datasource"
+)
+
+// taskPackedChannel is the postgres notification channel of packed tasks, the payload is the internal task id. the
+// notification is only delivered after the packing transaction committed
+const taskPackedChannel = "sequencer_task_packed"
+
+func (p *Persistence) notifyPackedTx(tx *gorm.DB, t *Task) error {
+	return p.backend.notifyPackedTx(tx, t)
+}
+
+// SubscribePacked returns the channel of the task which packs the message, and the func to cancel the subscription.
+// the channel is closed if the subscriber could not keep up
+func (p *Persistence) SubscribePacked(messageID string) (<-chan *Task, func()) {
+	return p.packed.Subscribe(messageID)
+}
+
+// publishPacked publishes the task to the subscribers of its messages
+func (p *Persistence) publishPacked(internalTaskID string) {
+	if p.packed.Len() == 0 {
+		return
+	}
+	ts, err := p.FetchTask(internalTaskID)
+	if err != nil {
+		slog.Error("failed to fetch packed task", "internal_task_id", internalTaskID, "error", err)
+		return
+	}
+	if len(ts) == 0 {
+		return
+	}
+	messageIDs := []string{}
+	if err := json.Unmarshal(ts[0].MessageIDs, &messageIDs); err != nil {
+		slog.Error("failed to unmarshal task message ids", "internal_task_id", internalTaskID, "error", err)
+		return
+	}
+	for _, id := range messageIDs {
+		p.packed.Publish(id, ts[0])
+	}
+}
+
+// RunListener listens the packed task notifications and publishes them to the subscribers, the notifications are
+// lost while reconnecting so subscribers should recheck the message periodically. this func will block caller
+func (p *Persistence) RunListener() error {
+	return p.backend.listen(p.db, p.publishPacked)
+}
+
+// postgresBackend notifies packed tasks by postgres LISTEN/NOTIFY
+type postgresBackend struct {
+	dsn string
+}
+
+func (b *postgresBackend) dialector() gorm.Dialector {
+	return postgres.Open(b.dsn)
+}
+
+func (b *postgresBackend) prepare(*gorm.DB) error {
+	return nil
+}
+
+// notifyPackedTx notifies the internal task id to the sequencer listeners, and the project id to the coordinator
+// datasource. the same project notified repeatedly in a transaction is delivered once by postgres
+func (b *postgresBackend) notifyPackedTx(tx *gorm.DB, t *Task) error {
+	if err := tx.Exec("SELECT pg_notify(?, ?)", taskPackedChannel, t.InternalTaskID).Error; err != nil {
+		return errors.Wrap(err, "failed to notify packed task")
+	}
+	if err := tx.Exec("SELECT pg_notify(?, ?)", datasource.TaskPackedChannel, strconv.FormatUint(t.ProjectID, 10)).Error; err != nil {
+		return errors.Wrap(err, "failed to notify packed task of project")
+	}
+	return nil
+}
+
+func (b *postgresBackend) listen(_ *gorm.DB, publish func(string)) error {
+	l := pq.NewListener(b.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("postgres listener event", "event", ev, "error", err)
+		}
+	})
+	defer l.Close()
+
+	if err := l.Listen(taskPackedChannel); err != nil {
+		return errors.Wrap(err, "failed to listen packed task notification")
+	}
+	for n := range l.Notify {
+		// nil after reconnected
+		if n == nil {
+			continue
+		}
+		publish(n.Extra)
+	}
+	return nil
+}
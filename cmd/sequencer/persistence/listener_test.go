@@ -0,0 +1,135 @@
+package persistence
+
+import (
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/machinefi/sprout/datasource"
+	"github.com/machinefi/sprout/util/broker"
+)
+
+func TestPersistence_notifyPackedTx(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{backend: &postgresBackend{}}
+
+	t.Run("FailedToNotify", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Exec", &gorm.DB{Error: errors.New(t.Name())})
+		r.ErrorContains(ps.notifyPackedTx(&gorm.DB{}, &Task{InternalTaskID: "task"}), t.Name())
+	})
+
+	t.Run("FailedToNotifyProject", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodSeq(&gorm.DB{}, "Exec", []OutputCell{
+			{Values: Params{&gorm.DB{}}},
+			{Values: Params{&gorm.DB{Error: errors.New(t.Name())}}},
+		})
+		r.ErrorContains(ps.notifyPackedTx(&gorm.DB{}, &Task{InternalTaskID: "task", ProjectID: 1}), t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		notified := map[string]any{}
+		p.ApplyMethod(&gorm.DB{}, "Exec", func(_ *gorm.DB, _ string, values ...any) *gorm.DB {
+			notified[values[0].(string)] = values[1]
+			return &gorm.DB{}
+		})
+		r.NoError(ps.notifyPackedTx(&gorm.DB{}, &Task{InternalTaskID: "task", ProjectID: 1}))
+		r.Equal(map[string]any{taskPackedChannel: "task", datasource.TaskPackedChannel: "1"}, notified)
+	})
+}
+
+func TestPersistence_publishPacked(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("NoSubscriber", func(t *testing.T) {
+		ps := &Persistence{packed: broker.New[string, *Task](1)}
+		ps.publishPacked("task")
+	})
+
+	t.Run("FailedToFetchTask", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		ps := &Persistence{packed: broker.New[string, *Task](1)}
+		ch, cancel := ps.SubscribePacked("m1")
+		defer cancel()
+
+		p.ApplyMethodReturn(ps, "FetchTask", nil, errors.New(t.Name()))
+		ps.publishPacked("task")
+		r.Len(ch, 0)
+	})
+
+	t.Run("InvalidMessageIDs", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		ps := &Persistence{packed: broker.New[string, *Task](1)}
+		ch, cancel := ps.SubscribePacked("m1")
+		defer cancel()
+
+		p.ApplyMethodReturn(ps, "FetchTask", []*Task{{MessageIDs: []byte("{")}}, nil)
+		ps.publishPacked("task")
+		r.Len(ch, 0)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		ps := &Persistence{packed: broker.New[string, *Task](1)}
+		ch, cancel := ps.SubscribePacked("m2")
+		defer cancel()
+
+		p.ApplyMethodReturn(ps, "FetchTask", []*Task{{InternalTaskID: "task", MessageIDs: []byte(`["m1","m2"]`)}}, nil)
+		ps.publishPacked("task")
+		r.Equal("task", (<-ch).InternalTaskID)
+	})
+}
+
+func TestPersistence_RunListener(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{backend: &postgresBackend{}, packed: broker.New[string, *Task](1)}
+
+	t.Run("FailedToListen", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyFuncReturn(pq.NewListener, &pq.Listener{})
+		p.ApplyMethodReturn(&pq.Listener{}, "Close", nil)
+		p.ApplyMethodReturn(&pq.Listener{}, "Listen", errors.New(t.Name()))
+		r.ErrorContains(ps.RunListener(), t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		notify := make(chan *pq.Notification, 2)
+		notify <- nil
+		notify <- &pq.Notification{Extra: "task"}
+		close(notify)
+		published := ""
+		p.ApplyFuncReturn(pq.NewListener, &pq.Listener{Notify: notify})
+		p.ApplyMethodReturn(&pq.Listener{}, "Close", nil)
+		p.ApplyMethodReturn(&pq.Listener{}, "Listen", nil)
+		p.ApplyPrivateMethod(ps, "publishPacked", func(_ *Persistence, id string) {
+			published = id
+		})
+		r.NoError(ps.RunListener())
+		r.Equal("task", published)
+	})
+}
@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/machinefi/sprout/util/merkle"
+)
+
+// signRoot signs keccak256(task id || project id || root), the ids are 8 bytes big endian
+func (t *Task) signRoot(sk *ecdsa.PrivateKey, root common.Hash) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := binary.Write(buf, binary.BigEndian, uint64(t.ID)); err != nil {
+		return "", err
+	}
+	if err := binary.Write(buf, binary.BigEndian, t.ProjectID); err != nil {
+		return "", err
+	}
+	buf.Write(root.Bytes())
+
+	sig, err := crypto.Sign(crypto.Keccak256(buf.Bytes()), sk)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(sig), nil
+}
+
+// commitMessagesTx builds the merkle tree over the ordered message data of the task, signs the root, and stores the
+// root with the leaf hashes in one update, the inclusion proof of a message is built from the leaves by MessageProof
+func (p *Persistence) commitMessagesTx(tx *gorm.DB, t *Task, data [][]byte, sk *ecdsa.PrivateKey) error {
+	tree := merkle.New(data)
+	root := tree.Root()
+	sig, err := t.signRoot(sk, root)
+	if err != nil {
+		return errors.Wrap(err, "failed to sign task merkle root")
+	}
+	leaves, err := json.Marshal(tree.Leaves())
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal task merkle leaves")
+	}
+	if err := tx.Model(t).Updates(map[string]any{
+		"merkle_root":    root.Hex(),
+		"root_signature": sig,
+		"merkle_leaves":  datatypes.JSON(leaves),
+	}).Error; err != nil {
+		return errors.Wrap(err, "failed to update task merkle root")
+	}
+	return nil
+}
+
+// MessageProof returns the inclusion proof of the message to the task merkle root
+func (t *Task) MessageProof(messageID string) ([]*merkle.ProofNode, error) {
+	ids := []string{}
+	if err := json.Unmarshal(t.MessageIDs, &ids); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal task message ids")
+	}
+	leaves := []common.Hash{}
+	if err := json.Unmarshal(t.MerkleLeaves, &leaves); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal task merkle leaves")
+	}
+	if len(leaves) != len(ids) {
+		return nil, errors.Errorf("task has %d merkle leaves for %d messages", len(leaves), len(ids))
+	}
+	for i, id := range ids {
+		if id == messageID {
+			return merkle.FromLeaves(leaves).Proof(i), nil
+		}
+	}
+	return nil, errors.Errorf("message %s is not packed in the task", messageID)
+}
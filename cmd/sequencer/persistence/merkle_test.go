@@ -0,0 +1,151 @@
+package persistence
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/machinefi/sprout/util/merkle"
+)
+
+func TestTask_signRoot(t *testing.T) {
+	r := require.New(t)
+
+	task := &Task{ProjectID: 2}
+	task.ID = 1
+	root := merkle.New([][]byte{[]byte("a")}).Root()
+
+	t.Run("FailedToSign", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyFuncReturn(crypto.Sign, nil, errors.New(t.Name()))
+
+		_, err := task.signRoot(nil, root)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		sk, err := crypto.GenerateKey()
+		r.NoError(err)
+
+		sig, err := task.signRoot(sk, root)
+		r.NoError(err)
+
+		msg := append(common.LeftPadBytes([]byte{1}, 8), common.LeftPadBytes([]byte{2}, 8)...)
+		pk, err := crypto.SigToPub(crypto.Keccak256(append(msg, root.Bytes()...)), hexutil.MustDecode(sig))
+		r.NoError(err)
+		r.Equal(crypto.PubkeyToAddress(sk.PublicKey), crypto.PubkeyToAddress(*pk))
+	})
+}
+
+func TestPersistence_commitMessagesTx(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{}
+	data := [][]byte{[]byte("a"), []byte("b")}
+
+	t.Run("FailedToSignRoot", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(&Task{}, "signRoot", func(*ecdsa.PrivateKey, common.Hash) (string, error) {
+			return "", errors.New(t.Name())
+		})
+
+		err := ps.commitMessagesTx(&gorm.DB{}, &Task{}, data, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToMarshalLeaves", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(&Task{}, "signRoot", func(*ecdsa.PrivateKey, common.Hash) (string, error) { return "", nil })
+		p.ApplyFuncReturn(json.Marshal, nil, errors.New(t.Name()))
+
+		err := ps.commitMessagesTx(&gorm.DB{}, &Task{}, data, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToUpdateRoot", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(&Task{}, "signRoot", func(*ecdsa.PrivateKey, common.Hash) (string, error) { return "", nil })
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", &gorm.DB{})
+		p.ApplyMethodReturn(&gorm.DB{}, "Updates", &gorm.DB{Error: errors.New(t.Name())})
+
+		err := ps.commitMessagesTx(&gorm.DB{}, &Task{}, data, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		updates := map[string]any{}
+		p.ApplyPrivateMethod(&Task{}, "signRoot", func(*ecdsa.PrivateKey, common.Hash) (string, error) { return "sig", nil })
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", &gorm.DB{})
+		p.ApplyMethod(&gorm.DB{}, "Updates", func(_ *gorm.DB, v any) *gorm.DB {
+			updates = v.(map[string]any)
+			return &gorm.DB{}
+		})
+
+		err := ps.commitMessagesTx(&gorm.DB{}, &Task{}, data, nil)
+		r.NoError(err)
+		r.Equal(merkle.New(data).Root().Hex(), updates["merkle_root"])
+		r.Equal("sig", updates["root_signature"])
+		leaves := []common.Hash{}
+		r.NoError(json.Unmarshal(updates["merkle_leaves"].(datatypes.JSON), &leaves))
+		r.Equal(merkle.New(data).Leaves(), leaves)
+	})
+}
+
+func TestTask_MessageProof(t *testing.T) {
+	r := require.New(t)
+
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	tree := merkle.New(data)
+	leaves, err := json.Marshal(tree.Leaves())
+	r.NoError(err)
+	ids := datatypes.JSON(`["m1","m2","m3"]`)
+
+	t.Run("InvalidMessageIDs", func(t *testing.T) {
+		_, err := (&Task{MessageIDs: datatypes.JSON("{"), MerkleLeaves: leaves}).MessageProof("m1")
+		r.ErrorContains(err, "failed to unmarshal task message ids")
+	})
+
+	t.Run("InvalidLeaves", func(t *testing.T) {
+		_, err := (&Task{MessageIDs: ids, MerkleLeaves: datatypes.JSON("{")}).MessageProof("m1")
+		r.ErrorContains(err, "failed to unmarshal task merkle leaves")
+	})
+
+	t.Run("LeavesMismatch", func(t *testing.T) {
+		_, err := (&Task{MessageIDs: datatypes.JSON(`["m1"]`), MerkleLeaves: leaves}).MessageProof("m1")
+		r.ErrorContains(err, "task has 3 merkle leaves for 1 messages")
+	})
+
+	t.Run("MessageNotInTask", func(t *testing.T) {
+		_, err := (&Task{MessageIDs: ids, MerkleLeaves: leaves}).MessageProof("other")
+		r.ErrorContains(err, "message other is not packed in the task")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		tk := &Task{MessageIDs: ids, MerkleLeaves: leaves}
+		for i, id := range []string{"m1", "m2", "m3"} {
+			proof, err := tk.MessageProof(id)
+			r.NoError(err)
+			r.True(merkle.Verify(tree.Root(), data[i], proof))
+		}
+	})
+}
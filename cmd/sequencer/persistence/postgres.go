@@ -5,34 +5,46 @@ import (
 	"crypto/ecdsa"
 	"encoding/binary"
 	"encoding/json"
+	stderrors "errors"
+	"log/slog"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"gorm.io/datatypes"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
+
+	"github.com/machinefi/sprout/util/broker"
 )
 
 type Message struct {
 	gorm.Model
 	MessageID      string `gorm:"index:message_id,not null"`
-	ClientID       string `gorm:"index:message_fetch,not null,default:''"`
-	ProjectID      uint64 `gorm:"index:message_fetch,not null"`
+	ClientID       string `gorm:"index:message_fetch,not null,default:'';uniqueIndex:message_idempotency,priority:1"`
+	ProjectID      uint64 `gorm:"index:message_fetch,not null;uniqueIndex:message_idempotency,priority:2"`
 	ProjectVersion string `gorm:"index:message_fetch,not null,default:'0.0'"`
 	Data           []byte `gorm:"size:4096"`
+	DataHash       string `gorm:"not null;default:''"` // the blob hash if data exceeds InlineDataSize, Data is empty then
 	InternalTaskID string `gorm:"index:internal_task_id,not null,default:''"`
+	IdempotencyKey string `gorm:"not null;default:'';uniqueIndex:message_idempotency,priority:3,where:idempotency_key <> ''"`
+	// the json of task.DeviceSignature if the message is signed by the device, verified before saving
+	DeviceSignature datatypes.JSON
 }
 
 type Task struct {
 	gorm.Model
 	ProjectID      uint64         `gorm:"index:task_fetch,not null"`
-	InternalTaskID string         `gorm:"index:internal_task_id,not null"`
+	InternalTaskID string         `gorm:"index:task_internal_task_id,not null"`
 	MessageIDs     datatypes.JSON `gorm:"not null"`
 	Signature      string         `gorm:"not null,default:''"`
+	MerkleRoot     string         `gorm:"not null;default:''"` // the merkle root over the ordered message data
+	RootSignature  string         `gorm:"not null;default:''"`
+	// the json of the merkle leaf hashes ordered as MessageIDs, the message proofs are built from them on read
+	MerkleLeaves datatypes.JSON
 }
 
 func (t *Task) sign(sk *ecdsa.PrivateKey, projectID uint64, clientID string, messages ...[]byte) (string, error) {
@@ -60,38 +72,90 @@ func (t *Task) sign(sk *ecdsa.PrivateKey, projectID uint64, clientID string, mes
 }
 
 type Persistence struct {
-	db *gorm.DB
+	db                *gorm.DB
+	backend           backend
+	idempotencyWindow time.Duration
+	packed            *broker.Broker[string, *Task] // the packed task of message id
+}
+
+// transaction runs fc in a transaction, and retries once if a message with the same idempotency key is submitted
+// concurrently, the retry finds the original message then
+func (p *Persistence) transaction(fc func(tx *gorm.DB) error) error {
+	err := p.db.Transaction(fc)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		err = p.db.Transaction(fc)
+	}
+	return err
+}
+
+// dedupTx returns the id of the original message if m duplicates a message submitted within the idempotency window,
+// the key of an expired original message is released for reuse
+func (p *Persistence) dedupTx(tx *gorm.DB, m *Message) (string, error) {
+	if m.IdempotencyKey == "" {
+		return "", nil
+	}
+	origin := &Message{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(
+			"client_id = ? AND project_id = ? AND idempotency_key = ?",
+			m.ClientID, m.ProjectID, m.IdempotencyKey,
+		).First(origin).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "failed to fetch message by idempotency key")
+	}
+	if time.Since(origin.CreatedAt) < p.idempotencyWindow {
+		return origin.MessageID, nil
+	}
+	if err := tx.Model(origin).Update("idempotency_key", "").Error; err != nil {
+		return "", errors.Wrap(err, "failed to release expired idempotency key")
+	}
+	return "", nil
 }
 
 func (p *Persistence) createMessageTx(tx *gorm.DB, m *Message) error {
+	m, err := p.storedMessageTx(tx, m)
+	if err != nil {
+		return err
+	}
 	if err := tx.Create(m).Error; err != nil {
 		return errors.Wrap(err, "failed to create message")
 	}
 	return nil
 }
 
-func (p *Persistence) aggregateTaskTx(tx *gorm.DB, amount int, m *Message, sk *ecdsa.PrivateKey) error {
-	messages := make([]*Message, 0)
-	if amount == 0 {
-		amount = 1
+// fetchUnpackedMessagesTx locks the oldest unpacked messages, the messages of all clients are fetched if across clients
+func (p *Persistence) fetchUnpackedMessagesTx(tx *gorm.DB, amount int, projectID uint64, projectVersion, clientID string, acrossClients bool) ([]*Message, error) {
+	query, args := "project_id = ? AND project_version = ? AND internal_task_id = ?", []any{projectID, projectVersion, ""}
+	if !acrossClients {
+		query, args = query+" AND client_id = ?", append(args, clientID)
 	}
 
+	messages := make([]*Message, 0)
 	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 		Order("created_at").
-		Where(
-			"project_id = ? AND project_version = ? AND client_id = ? AND internal_task_id = ?",
-			m.ProjectID, m.ProjectVersion, m.ClientID, "",
-		).Limit(amount).Find(&messages).Error; err != nil {
-		return errors.Wrap(err, "failed to fetch unpacked messages")
+		Where(query, args...).
+		Limit(amount).Find(&messages).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to fetch unpacked messages")
 	}
+	return messages, nil
+}
 
-	// no enough message for pack Task
-	if len(messages) < amount {
-		return nil
+// taskClientID returns the client of the messages, or empty if the messages are of several clients
+func taskClientID(messages []*Message) string {
+	for _, m := range messages[1:] {
+		if m.ClientID != messages[0].ClientID {
+			return ""
+		}
 	}
+	return messages[0].ClientID
+}
 
+// packTaskTx packs the messages into a task, the task of several clients is signed with an empty client id
+func (p *Persistence) packTaskTx(tx *gorm.DB, messages []*Message, projectID uint64, sk *ecdsa.PrivateKey) error {
 	taskID := uuid.NewString()
-	messageIDs := make([]string, 0, amount)
+	messageIDs := make([]string, 0, len(messages))
 	for _, v := range messages {
 		messageIDs = append(messageIDs, v.MessageID)
 	}
@@ -105,19 +169,19 @@ func (p *Persistence) aggregateTaskTx(tx *gorm.DB, amount int, m *Message, sk *e
 
 	t := &Task{
 		InternalTaskID: taskID,
-		ProjectID:      m.ProjectID,
+		ProjectID:      projectID,
 		MessageIDs:     messageIDsJson,
 	}
 
 	if err := tx.Create(t).Error; err != nil {
 		return errors.Wrap(err, "failed to create Task")
 	}
-	data := make([][]byte, 0, len(messages))
-	for _, v := range messages {
-		data = append(data, v.Data)
+	data, err := p.messageDataTx(tx, messages)
+	if err != nil {
+		return err
 	}
 
-	sig, err := t.sign(sk, m.ProjectID, m.ClientID, data...)
+	sig, err := t.sign(sk, projectID, taskClientID(messages), data...)
 	if err != nil {
 		return errors.Wrap(err, "failed to sign Task")
 	}
@@ -125,20 +189,246 @@ func (p *Persistence) aggregateTaskTx(tx *gorm.DB, amount int, m *Message, sk *e
 	if err := tx.Model(t).Update("signature", sig).Where("id = ?", t.ID).Error; err != nil {
 		return errors.Wrap(err, "failed to update Task sign")
 	}
+	if err := p.commitMessagesTx(tx, t, data, sk); err != nil {
+		return err
+	}
 
-	return nil
+	return p.notifyPackedTx(tx, t)
+}
+
+// aggregateTaskTx packs a task if there are enough unpacked messages in the group of m, and returns whether packed
+func (p *Persistence) aggregateTaskTx(tx *gorm.DB, ag *Aggregation, m *Message, sk *ecdsa.PrivateKey) (bool, error) {
+	amount := int(ag.Amount)
+	if amount == 0 {
+		amount = 1
+	}
+
+	messages, err := p.fetchUnpackedMessagesTx(tx, amount, m.ProjectID, m.ProjectVersion, m.ClientID, ag.AcrossClients)
+	if err != nil {
+		return false, err
+	}
+
+	// no enough message for pack Task
+	if len(messages) < amount {
+		return false, nil
+	}
+
+	if err := p.packTaskTx(tx, messages, m.ProjectID, sk); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// partialBatch is the unpacked messages of a (project, version, client) tuple, the client is ignored if the project
+// aggregates across clients
+type partialBatch struct {
+	ProjectID      uint64
+	ProjectVersion string
+	ClientID       string
+	OldestAt       aggregatedTime
+}
+
+// flushTx packs the partial batch if its oldest message is still created before the deadline, the batch may be
+// packed by Save concurrently after it was listed
+func (p *Persistence) flushTx(tx *gorm.DB, ag *Aggregation, b *partialBatch, deadline time.Time, sk *ecdsa.PrivateKey) (bool, error) {
+	amount := int(ag.Amount)
+	if amount == 0 {
+		amount = 1
+	}
+
+	messages, err := p.fetchUnpackedMessagesTx(tx, amount, b.ProjectID, b.ProjectVersion, b.ClientID, ag.AcrossClients)
+	if err != nil {
+		return false, err
+	}
+	if len(messages) == 0 || messages[0].CreatedAt.After(deadline) {
+		return false, nil
+	}
+
+	if err := p.packTaskTx(tx, messages, b.ProjectID, sk); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Flush packs the partial batches whose oldest message waits longer than the max latency of the project, and
+// returns the number of packed tasks. a failed batch doesn't stop flushing the others, the errors are joined
+func (p *Persistence) Flush(policy AggregationPolicy, sk *ecdsa.PrivateKey) (int, error) {
+	batches := make([]*partialBatch, 0)
+	if err := p.db.Model(&Message{}).
+		Select("project_id, project_version, client_id, MIN(created_at) AS oldest_at").
+		Where("internal_task_id = ?", "").
+		Group("project_id, project_version, client_id").
+		Find(&batches).Error; err != nil {
+		return 0, errors.Wrap(err, "failed to fetch partial batches")
+	}
+
+	now := time.Now()
+	packed := 0
+	errs := make([]error, 0)
+	merged := map[partialBatch]bool{} // (project, version) of the projects aggregating across clients
+	for _, b := range batches {
+		ag := policy(b.ProjectID)
+		if ag == nil || ag.MaxLatency <= 0 {
+			continue
+		}
+		deadline := now.Add(-ag.MaxLatency)
+		if ag.AcrossClients {
+			key := partialBatch{ProjectID: b.ProjectID, ProjectVersion: b.ProjectVersion}
+			if merged[key] {
+				continue
+			}
+			merged[key] = true
+			// the oldest message of the project version decides
+			for _, other := range batches {
+				if other.ProjectID == b.ProjectID && other.ProjectVersion == b.ProjectVersion && other.OldestAt.Before(b.OldestAt.Time) {
+					b = other
+				}
+			}
+		}
+		if b.OldestAt.After(deadline) {
+			continue
+		}
+		if err := p.db.Transaction(func(tx *gorm.DB) error {
+			ok, err := p.flushTx(tx, ag, b, deadline, sk)
+			if ok {
+				packed++
+			}
+			return err
+		}); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to flush partial batch of project %d client %s", b.ProjectID, b.ClientID))
+		}
+	}
+	return packed, stderrors.Join(errs...)
+}
+
+// RunFlusher flushes partial batches periodically, this func will block caller
+func (p *Persistence) RunFlusher(interval time.Duration, policy AggregationPolicy, sk *ecdsa.PrivateKey) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		packed, err := p.Flush(policy, sk)
+		if err != nil {
+			slog.Error("failed to flush partial batches", "error", err)
+		}
+		if packed > 0 {
+			slog.Debug("partial batches flushed", "tasks", packed)
+		}
+	}
 }
 
-func (p *Persistence) Save(msg *Message, aggregationAmount uint, sk *ecdsa.PrivateKey) error {
-	return p.db.Transaction(func(tx *gorm.DB) error {
+// Save saves the message and packs a task if enough messages, and returns the message id. the id of the original
+// message is returned if msg duplicates one submitted with the same idempotency key
+func (p *Persistence) Save(msg *Message, aggregation *Aggregation, sk *ecdsa.PrivateKey) (string, error) {
+	var id string
+	err := p.transaction(func(tx *gorm.DB) error {
+		origin, err := p.dedupTx(tx, msg)
+		if err != nil {
+			return err
+		}
+		if origin != "" {
+			id = origin
+			return nil
+		}
+		id = msg.MessageID
 		if err := p.createMessageTx(tx, msg); err != nil {
 			return err
 		}
-		if err := p.aggregateTaskTx(tx, int(aggregationAmount), msg, sk); err != nil {
+		if _, err := p.aggregateTaskTx(tx, aggregation, msg, sk); err != nil {
 			return err
 		}
 		return nil
 	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// SaveBatch saves the messages in one transaction, and packs tasks once per affected group. returns the message ids
+// in order, a duplicate message gets the id of its original message
+func (p *Persistence) SaveBatch(msgs []*Message, policy AggregationPolicy, sk *ecdsa.PrivateKey) ([]string, error) {
+	type group struct {
+		projectID      uint64
+		projectVersion string
+		clientID       string
+	}
+	type idempotency struct {
+		clientID  string
+		projectID uint64
+		key       string
+	}
+
+	ids := make([]string, len(msgs))
+	err := p.transaction(func(tx *gorm.DB) error {
+		created := make([]*Message, 0, len(msgs))
+		keys := map[idempotency]string{} // the messages with idempotency key in this batch
+		for i, m := range msgs {
+			ids[i] = m.MessageID
+			if m.IdempotencyKey == "" {
+				created = append(created, m)
+				continue
+			}
+			k := idempotency{clientID: m.ClientID, projectID: m.ProjectID, key: m.IdempotencyKey}
+			if id, ok := keys[k]; ok {
+				ids[i] = id
+				continue
+			}
+			origin, err := p.dedupTx(tx, m)
+			if err != nil {
+				return err
+			}
+			if origin != "" {
+				ids[i] = origin
+				keys[k] = origin
+				continue
+			}
+			keys[k] = m.MessageID
+			created = append(created, m)
+		}
+		if len(created) == 0 {
+			return nil
+		}
+
+		stored := make([]*Message, 0, len(created))
+		for _, m := range created {
+			sm, err := p.storedMessageTx(tx, m)
+			if err != nil {
+				return err
+			}
+			stored = append(stored, sm)
+		}
+		if err := tx.Create(stored).Error; err != nil {
+			return errors.Wrap(err, "failed to create messages")
+		}
+		aggregated := map[group]bool{}
+		for _, m := range created {
+			ag := policy(m.ProjectID)
+			g := group{projectID: m.ProjectID, projectVersion: m.ProjectVersion, clientID: m.ClientID}
+			if ag.AcrossClients {
+				g.clientID = ""
+			}
+			if aggregated[g] {
+				continue
+			}
+			aggregated[g] = true
+			// the batch may fill more than one task
+			for {
+				packed, err := p.aggregateTaskTx(tx, ag, m, sk)
+				if err != nil {
+					return err
+				}
+				if !packed {
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
 func (p *Persistence) FetchMessage(messageID string) ([]*Message, error) {
@@ -150,6 +440,102 @@ func (p *Persistence) FetchMessage(messageID string) ([]*Message, error) {
 	return ms, nil
 }
 
+// MessageFilter filters the listed messages, the zero value fields are ignored
+type MessageFilter struct {
+	ClientID       string
+	ProjectID      uint64
+	ProjectVersion string
+	Since          time.Time
+	Until          time.Time
+	Packed         *bool
+	After          uint // the messages with id greater than After
+	Limit          int
+}
+
+// messageQuery builds the query of the filter, the times are compared in utc since sqlite compares them as text
+func (p *Persistence) messageQuery(f *MessageFilter) *gorm.DB {
+	q := p.db.Model(&Message{}).Where("id > ?", f.After)
+	if f.ClientID != "" {
+		q = q.Where("client_id = ?", f.ClientID)
+	}
+	if f.ProjectID != 0 {
+		q = q.Where("project_id = ?", f.ProjectID)
+	}
+	if f.ProjectVersion != "" {
+		q = q.Where("project_version = ?", f.ProjectVersion)
+	}
+	if !f.Since.IsZero() {
+		q = q.Where("created_at >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		q = q.Where("created_at < ?", f.Until.UTC())
+	}
+	if f.Packed != nil {
+		if *f.Packed {
+			q = q.Where("internal_task_id <> ?", "")
+		} else {
+			q = q.Where("internal_task_id = ?", "")
+		}
+	}
+	return q
+}
+
+// ListMessages lists the messages matching the filter in ascending id order
+func (p *Persistence) ListMessages(f *MessageFilter) ([]*Message, error) {
+	ms := []*Message{}
+	if err := p.messageQuery(f).Order("id").Limit(f.Limit).Find(&ms).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list messages")
+	}
+	return ms, nil
+}
+
+// CountMessages counts the messages matching the filter, the pagination fields are ignored
+func (p *Persistence) CountMessages(f *MessageFilter) (uint64, error) {
+	var n int64
+	if err := p.messageQuery(f).Count(&n).Error; err != nil {
+		return 0, errors.Wrap(err, "failed to count messages")
+	}
+	return uint64(n), nil
+}
+
+// MessageData returns the data of messages in order, the data stored as blob is rehydrated
+func (p *Persistence) MessageData(ms []*Message) ([][]byte, error) {
+	return p.messageDataTx(p.db, ms)
+}
+
+// TaskFilter filters the listed tasks, the zero value fields are ignored
+type TaskFilter struct {
+	ClientID  string // the tasks containing messages of the client
+	ProjectID uint64
+	Since     time.Time
+	Until     time.Time
+	After     uint
+	Limit     int
+}
+
+// ListTasks lists the tasks matching the filter in ascending id order
+func (p *Persistence) ListTasks(f *TaskFilter) ([]*Task, error) {
+	q := p.db.Where("id > ?", f.After)
+	if f.ClientID != "" {
+		q = q.Where("internal_task_id IN (?)", p.db.Model(&Message{}).Select("internal_task_id").Where("client_id = ? AND internal_task_id <> ?", f.ClientID, ""))
+	}
+	if f.ProjectID != 0 {
+		q = q.Where("project_id = ?", f.ProjectID)
+	}
+	if !f.Since.IsZero() {
+		q = q.Where("created_at >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		q = q.Where("created_at < ?", f.Until.UTC())
+	}
+
+	ts := []*Task{}
+	if err := q.Order("id").Limit(f.Limit).Find(&ts).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list tasks")
+	}
+	return ts, nil
+}
+
 func (p *Persistence) FetchTask(internalTaskID string) ([]*Task, error) {
 	ts := []*Task{}
 	if err := p.db.Where("internal_task_id = ?", internalTaskID).Find(&ts).Error; err != nil {
@@ -159,15 +545,28 @@ func (p *Persistence) FetchTask(internalTaskID string) ([]*Task, error) {
 	return ts, nil
 }
 
-func NewPersistence(pgEndpoint string) (*Persistence, error) {
-	db, err := gorm.Open(postgres.Open(pgEndpoint), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
+// NewPersistence creates the persistence on the database of dsn, which is a postgres endpoint or an embedded sqlite
+// database prefixed by SQLiteScheme. a message duplicating the idempotency key of another message submitted within the
+// idempotency window is not saved again
+func NewPersistence(dsn string, idempotencyWindow time.Duration) (*Persistence, error) {
+	b := newBackend(dsn)
+	db, err := gorm.Open(b.dialector(), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to connect postgres")
+		return nil, errors.Wrap(err, "failed to connect database")
+	}
+	if err := b.prepare(db); err != nil {
+		return nil, err
 	}
-	if err := db.AutoMigrate(&Message{}, &Task{}); err != nil {
+	if err := db.AutoMigrate(&Message{}, &Task{}, &Blob{}); err != nil {
 		return nil, errors.Wrap(err, "failed to migrate model")
 	}
-	return &Persistence{db}, nil
+	return &Persistence{
+		db:                db,
+		backend:           b,
+		idempotencyWindow: idempotencyWindow,
+		packed:            broker.New[string, *Task](1),
+	}, nil
 }
@@ -3,16 +3,20 @@ package persistence
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"database/sql"
 	"encoding/binary"
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/agiledragon/gomonkey/v2"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
+
+	tasktype "github.com/machinefi/sprout/task"
 )
 
 func TestNewPersistence(t *testing.T) {
@@ -23,7 +27,7 @@ func TestNewPersistence(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyFuncReturn(gorm.Open, nil, errors.New(t.Name()))
-		_, err := NewPersistence("")
+		_, err := NewPersistence("", 0)
 		r.ErrorContains(err, t.Name())
 	})
 
@@ -33,7 +37,7 @@ func TestNewPersistence(t *testing.T) {
 
 		p.ApplyFuncReturn(gorm.Open, &gorm.DB{}, nil)
 		p.ApplyMethodReturn(&gorm.DB{}, "AutoMigrate", errors.New(t.Name()))
-		_, err := NewPersistence("")
+		_, err := NewPersistence("", 0)
 		r.ErrorContains(err, t.Name())
 	})
 
@@ -43,7 +47,7 @@ func TestNewPersistence(t *testing.T) {
 
 		p.ApplyFuncReturn(gorm.Open, &gorm.DB{}, nil)
 		p.ApplyMethodReturn(&gorm.DB{}, "AutoMigrate", nil)
-		_, err := NewPersistence("")
+		_, err := NewPersistence("", 0)
 		r.NoError(err)
 	})
 }
@@ -91,6 +95,142 @@ func TestPersistence_FetchMessage(t *testing.T) {
 	})
 }
 
+func TestPersistence_ListMessages(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{
+		db: &gorm.DB{
+			Statement: &gorm.Statement{},
+		},
+	}
+	packed := true
+
+	t.Run("FailedToQuery", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Order", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Limit", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{Error: errors.New(t.Name())})
+		_, err := ps.ListMessages(&MessageFilter{})
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		conditions := []string{}
+		p.ApplyMethod(&gorm.DB{}, "Where", func(_ *gorm.DB, query any, _ ...any) *gorm.DB {
+			conditions = append(conditions, query.(string))
+			return ps.db
+		})
+		p.ApplyMethodReturn(&gorm.DB{}, "Order", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Limit", ps.db)
+		p.ApplyMethod(&gorm.DB{}, "Find", func(_ *gorm.DB, dest any, _ ...any) *gorm.DB {
+			*dest.(*[]*Message) = []*Message{{}, {}}
+			return &gorm.DB{}
+		})
+		ms, err := ps.ListMessages(&MessageFilter{
+			ClientID:       "did",
+			ProjectID:      1,
+			ProjectVersion: "v1",
+			Since:          time.Now(),
+			Until:          time.Now(),
+			Packed:         &packed,
+			Limit:          2,
+		})
+		r.NoError(err)
+		r.Len(ms, 2)
+		r.Equal([]string{
+			"id > ?", "client_id = ?", "project_id = ?", "project_version = ?",
+			"created_at >= ?", "created_at < ?", "internal_task_id <> ?",
+		}, conditions)
+	})
+}
+
+func TestPersistence_CountMessages(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{
+		db: &gorm.DB{
+			Statement: &gorm.Statement{},
+		},
+	}
+
+	t.Run("FailedToCount", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Count", &gorm.DB{Error: errors.New(t.Name())})
+		_, err := ps.CountMessages(&MessageFilter{ClientID: "did"})
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethod(&gorm.DB{}, "Count", func(_ *gorm.DB, n *int64) *gorm.DB {
+			*n = 3
+			return &gorm.DB{}
+		})
+		n, err := ps.CountMessages(&MessageFilter{ProjectID: 1, Since: time.Now()})
+		r.NoError(err)
+		r.Equal(uint64(3), n)
+	})
+}
+
+func TestPersistence_ListTasks(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{
+		db: &gorm.DB{
+			Statement: &gorm.Statement{},
+		},
+	}
+
+	t.Run("FailedToQuery", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Order", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Limit", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{Error: errors.New(t.Name())})
+		_, err := ps.ListTasks(&TaskFilter{})
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		conditions := []string{}
+		p.ApplyMethod(&gorm.DB{}, "Where", func(_ *gorm.DB, query any, _ ...any) *gorm.DB {
+			conditions = append(conditions, query.(string))
+			return ps.db
+		})
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Select", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Order", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Limit", ps.db)
+		p.ApplyMethod(&gorm.DB{}, "Find", func(_ *gorm.DB, dest any, _ ...any) *gorm.DB {
+			*dest.(*[]*Task) = []*Task{{}}
+			return &gorm.DB{}
+		})
+		ts, err := ps.ListTasks(&TaskFilter{ClientID: "did", ProjectID: 1, Since: time.Now()})
+		r.NoError(err)
+		r.Len(ts, 1)
+		r.Equal([]string{
+			"id > ?", "client_id = ? AND internal_task_id <> ?", "internal_task_id IN (?)", "project_id = ?", "created_at >= ?",
+		}, conditions)
+	})
+}
+
 func TestPersistence_FetchTask(t *testing.T) {
 	r := require.New(t)
 
@@ -144,7 +284,18 @@ func TestPersistence_createMessageTx(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{Error: errors.New(t.Name())})
-		err := ps.createMessageTx(&gorm.DB{}, nil)
+		err := ps.createMessageTx(&gorm.DB{}, &Message{})
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToStoreBlob", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(ps, "storedMessageTx", func(*Persistence, *gorm.DB, *Message) (*Message, error) {
+			return nil, errors.New(t.Name())
+		})
+		err := ps.createMessageTx(&gorm.DB{}, &Message{})
 		r.ErrorContains(err, t.Name())
 	})
 
@@ -153,7 +304,7 @@ func TestPersistence_createMessageTx(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{})
-		err := ps.createMessageTx(&gorm.DB{}, nil)
+		err := ps.createMessageTx(&gorm.DB{}, &Message{})
 		r.NoError(err)
 	})
 }
@@ -161,7 +312,7 @@ func TestPersistence_createMessageTx(t *testing.T) {
 func TestPersistence_aggregateTaskTx(t *testing.T) {
 	r := require.New(t)
 
-	ps := &Persistence{}
+	ps := &Persistence{backend: &postgresBackend{}}
 
 	t.Run("FailedToFetch", func(t *testing.T) {
 		p := NewPatches()
@@ -173,7 +324,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 		p.ApplyMethodReturn(&gorm.DB{}, "Limit", ps.db)
 		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{Error: errors.New(t.Name())})
 
-		err := ps.aggregateTaskTx(&gorm.DB{}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -201,7 +352,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 				return &gorm.DB{Error: nil}
 			},
 		)
-		err := ps.aggregateTaskTx(&gorm.DB{}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -236,7 +387,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 		p.ApplyMethodReturn(&gorm.DB{}, "Model", ps.db)
 		p.ApplyMethodReturn(&gorm.DB{}, "Update", &gorm.DB{Error: errors.New(t.Name())})
 
-		err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -272,7 +423,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 		p.ApplyMethodReturn(&gorm.DB{}, "Update", &gorm.DB{Error: nil})
 		p.ApplyFuncReturn(json.Marshal, nil, errors.New(t.Name()))
 
-		err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -309,7 +460,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 		p.ApplyFuncReturn(json.Marshal, []byte(""), nil)
 		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{Error: errors.New(t.Name())})
 
-		err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -353,7 +504,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 			},
 		)
 
-		err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -404,7 +555,7 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 			},
 		)
 
-		err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -454,8 +605,10 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 				return "", nil
 			},
 		)
+		p.ApplyPrivateMethod(ps, "commitMessagesTx", func(*gorm.DB, *Task, []*Message, [][]byte, *ecdsa.PrivateKey) error { return nil })
+		p.ApplyMethodReturn(&gorm.DB{}, "Exec", &gorm.DB{})
 
-		err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, 0, &Message{
+		_, err := ps.aggregateTaskTx(&gorm.DB{Statement: &gorm.Statement{}}, &Aggregation{}, &Message{
 			ClientID:       "clientID",
 			ProjectID:      0,
 			ProjectVersion: "0.1",
@@ -464,6 +617,467 @@ func TestPersistence_aggregateTaskTx(t *testing.T) {
 	})
 }
 
+func TestPersistence_flushTx(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{}
+	deadline := time.Now()
+	b := &partialBatch{ProjectID: 1, ProjectVersion: "0.1", ClientID: "clientID"}
+
+	t.Run("FailedToFetch", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(ps, "fetchUnpackedMessagesTx", func(*Persistence, *gorm.DB, int, uint64, string, string, bool) ([]*Message, error) {
+			return nil, errors.New(t.Name())
+		})
+
+		_, err := ps.flushTx(&gorm.DB{}, &Aggregation{}, b, deadline, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("PackedConcurrently", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(ps, "fetchUnpackedMessagesTx", func(*Persistence, *gorm.DB, int, uint64, string, string, bool) ([]*Message, error) {
+			m := &Message{}
+			m.CreatedAt = deadline.Add(time.Second)
+			return []*Message{m}, nil
+		})
+
+		ok, err := ps.flushTx(&gorm.DB{}, &Aggregation{}, b, deadline, nil)
+		r.NoError(err)
+		r.False(ok)
+	})
+
+	t.Run("FailedToPack", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyPrivateMethod(ps, "fetchUnpackedMessagesTx", func(*Persistence, *gorm.DB, int, uint64, string, string, bool) ([]*Message, error) {
+			return []*Message{{}}, nil
+		})
+		p.ApplyPrivateMethod(ps, "packTaskTx", func(*Persistence, *gorm.DB, []*Message, uint64, *ecdsa.PrivateKey) error {
+			return errors.New(t.Name())
+		})
+
+		_, err := ps.flushTx(&gorm.DB{}, &Aggregation{}, b, deadline, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		amount := 0
+		p.ApplyPrivateMethod(ps, "fetchUnpackedMessagesTx", func(_ *Persistence, _ *gorm.DB, n int, _ uint64, _ string, _ string, _ bool) ([]*Message, error) {
+			amount = n
+			return []*Message{{}, {}}, nil
+		})
+		p.ApplyPrivateMethod(ps, "packTaskTx", func(*Persistence, *gorm.DB, []*Message, uint64, *ecdsa.PrivateKey) error {
+			return nil
+		})
+
+		ok, err := ps.flushTx(&gorm.DB{}, &Aggregation{Amount: 10}, b, deadline, nil)
+		r.NoError(err)
+		r.True(ok)
+		r.Equal(10, amount)
+	})
+}
+
+func TestPersistence_Flush(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{db: &gorm.DB{Statement: &gorm.Statement{}}}
+	policy := func(projectID uint64) *Aggregation {
+		if projectID == 1 {
+			return &Aggregation{Amount: 10, MaxLatency: time.Minute}
+		}
+		return &Aggregation{Amount: 10}
+	}
+	patchBatches := func(p *Patches, batches []*partialBatch) {
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Select", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Group", ps.db)
+		p.ApplyMethod(
+			&gorm.DB{},
+			"Find",
+			func(_ *gorm.DB, v any) *gorm.DB {
+				vi := reflect.ValueOf(&batches)
+				vo := reflect.ValueOf(v)
+				if vi.IsValid() && vo.IsValid() {
+					vo.Elem().Set(vi.Elem())
+				}
+				return &gorm.DB{Error: nil}
+			},
+		)
+	}
+
+	t.Run("FailedToFetchBatches", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Select", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Group", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{Error: errors.New(t.Name())})
+
+		_, err := ps.Flush(policy, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToFlush", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchBatches(p, []*partialBatch{
+			{ProjectID: 1, ClientID: "a", OldestAt: aggregatedTime{time.Now().Add(-time.Hour)}},
+			{ProjectID: 1, ClientID: "b", OldestAt: aggregatedTime{time.Now().Add(-time.Hour)}},
+			{ProjectID: 1, ClientID: "c", OldestAt: aggregatedTime{time.Now().Add(-time.Hour)}},
+		})
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+		p.ApplyPrivateMethod(ps, "flushTx", func(_ *Persistence, _ *gorm.DB, _ *Aggregation, b *partialBatch, _ time.Time, _ *ecdsa.PrivateKey) (bool, error) {
+			if b.ClientID != "b" {
+				return false, errors.New(t.Name() + b.ClientID)
+			}
+			return true, nil
+		})
+
+		packed, err := ps.Flush(policy, nil)
+		r.ErrorContains(err, t.Name()+"a")
+		r.ErrorContains(err, t.Name()+"c")
+		r.Equal(1, packed)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchBatches(p, []*partialBatch{
+			{ProjectID: 1, OldestAt: aggregatedTime{time.Now().Add(-time.Hour)}},
+			{ProjectID: 1, OldestAt: aggregatedTime{time.Now()}},                 // not expired
+			{ProjectID: 2, OldestAt: aggregatedTime{time.Now().Add(-time.Hour)}}, // no max latency
+		})
+		flushed := []*partialBatch{}
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+		p.ApplyPrivateMethod(ps, "flushTx", func(_ *Persistence, _ *gorm.DB, _ *Aggregation, b *partialBatch, _ time.Time, _ *ecdsa.PrivateKey) (bool, error) {
+			flushed = append(flushed, b)
+			return true, nil
+		})
+
+		packed, err := ps.Flush(policy, nil)
+		r.NoError(err)
+		r.Equal(1, packed)
+		r.Len(flushed, 1)
+		r.Equal(uint64(1), flushed[0].ProjectID)
+	})
+
+	t.Run("AcrossClients", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		oldest := time.Now().Add(-time.Hour)
+		patchBatches(p, []*partialBatch{
+			{ProjectID: 3, ProjectVersion: "0.1", ClientID: "a", OldestAt: aggregatedTime{time.Now()}},
+			{ProjectID: 3, ProjectVersion: "0.1", ClientID: "b", OldestAt: aggregatedTime{oldest}},
+			{ProjectID: 3, ProjectVersion: "0.2", ClientID: "a", OldestAt: aggregatedTime{time.Now()}},
+		})
+		flushed := []*partialBatch{}
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+		p.ApplyPrivateMethod(ps, "flushTx", func(_ *Persistence, _ *gorm.DB, ag *Aggregation, b *partialBatch, _ time.Time, _ *ecdsa.PrivateKey) (bool, error) {
+			r.True(ag.AcrossClients)
+			flushed = append(flushed, b)
+			return true, nil
+		})
+
+		packed, err := ps.Flush(func(uint64) *Aggregation {
+			return &Aggregation{Amount: 10, MaxLatency: time.Minute, AcrossClients: true}
+		}, nil)
+		r.NoError(err)
+		r.Equal(1, packed)
+		r.Len(flushed, 1)
+		r.Equal("0.1", flushed[0].ProjectVersion)
+		r.Equal(oldest, flushed[0].OldestAt.Time)
+	})
+}
+
+func TestPersistence_SaveBatch(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{db: &gorm.DB{Statement: &gorm.Statement{}}}
+	msgs := []*Message{
+		{ProjectID: 1, ProjectVersion: "0.1", ClientID: "a"},
+		{ProjectID: 1, ProjectVersion: "0.1", ClientID: "a"},
+		{ProjectID: 1, ProjectVersion: "0.1", ClientID: "b"},
+		{ProjectID: 2, ProjectVersion: "0.1", ClientID: "a"},
+		{ProjectID: 2, ProjectVersion: "0.1", ClientID: "b"},
+	}
+	policy := func(projectID uint64) *Aggregation {
+		return &Aggregation{Amount: 1, AcrossClients: projectID == 2}
+	}
+	patchTransaction := func(p *Patches) {
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+	}
+
+	t.Run("FailedToCreate", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchTransaction(p)
+		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{Error: errors.New(t.Name())})
+
+		_, err := ps.SaveBatch(msgs, policy, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToAggregate", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchTransaction(p)
+		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{})
+		p.ApplyPrivateMethod(ps, "aggregateTaskTx", func(*Persistence, *gorm.DB, *Aggregation, *Message, *ecdsa.PrivateKey) (bool, error) {
+			return false, errors.New(t.Name())
+		})
+
+		_, err := ps.SaveBatch(msgs, policy, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchTransaction(p)
+		p.ApplyMethodReturn(&gorm.DB{}, "Create", &gorm.DB{})
+		groups := []*Message{}
+		packed := map[*Message]int{}
+		p.ApplyPrivateMethod(ps, "aggregateTaskTx", func(_ *Persistence, _ *gorm.DB, _ *Aggregation, m *Message, _ *ecdsa.PrivateKey) (bool, error) {
+			if packed[m] == 0 {
+				groups = append(groups, m)
+			}
+			packed[m]++
+			// the first group fills two tasks
+			return m == msgs[0] && packed[m] <= 2, nil
+		})
+
+		_, err := ps.SaveBatch(msgs, policy, nil)
+		r.NoError(err)
+		r.Equal([]*Message{msgs[0], msgs[2], msgs[3]}, groups)
+		r.Equal(3, packed[msgs[0]])
+	})
+
+	t.Run("Idempotency", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		batch := []*Message{
+			{MessageID: "m1", ProjectID: 1, ClientID: "a", IdempotencyKey: "k1"},
+			{MessageID: "m2", ProjectID: 1, ClientID: "a", IdempotencyKey: "k1"},
+			{MessageID: "m3", ProjectID: 1, ClientID: "b", IdempotencyKey: "k1"},
+			{MessageID: "m4", ProjectID: 1, ClientID: "a", IdempotencyKey: "k2"},
+			{MessageID: "m5", ProjectID: 1, ClientID: "a"},
+		}
+		patchTransaction(p)
+		var created []*Message
+		p.ApplyMethod(&gorm.DB{}, "Create", func(_ *gorm.DB, v any) *gorm.DB {
+			created = v.([]*Message)
+			return &gorm.DB{}
+		})
+		p.ApplyPrivateMethod(ps, "dedupTx", func(_ *Persistence, _ *gorm.DB, m *Message) (string, error) {
+			if m.IdempotencyKey == "k2" {
+				return "origin", nil
+			}
+			return "", nil
+		})
+		p.ApplyPrivateMethod(ps, "aggregateTaskTx", func(*Persistence, *gorm.DB, *Aggregation, *Message, *ecdsa.PrivateKey) (bool, error) {
+			return false, nil
+		})
+
+		ids, err := ps.SaveBatch(batch, policy, nil)
+		r.NoError(err)
+		r.Equal([]string{"m1", "m1", "m3", "origin", "m5"}, ids)
+		r.Equal([]*Message{batch[0], batch[2], batch[4]}, created)
+	})
+}
+
+func TestPersistence_transaction(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{db: &gorm.DB{Statement: &gorm.Statement{}}}
+
+	t.Run("RetryOnDuplicatedKey", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodSeq(&gorm.DB{}, "Transaction", []OutputCell{
+			{Values: Params{errors.Wrap(gorm.ErrDuplicatedKey, "failed to create message")}},
+			{Values: Params{nil}},
+		})
+		r.NoError(ps.transaction(nil))
+	})
+
+	t.Run("NoRetry", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodSeq(&gorm.DB{}, "Transaction", []OutputCell{
+			{Values: Params{errors.New(t.Name())}},
+			{Values: Params{nil}},
+		})
+		r.ErrorContains(ps.transaction(nil), t.Name())
+	})
+}
+
+func TestPersistence_dedupTx(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{db: &gorm.DB{Statement: &gorm.Statement{}}, idempotencyWindow: time.Hour}
+	m := &Message{ClientID: "a", ProjectID: 1, IdempotencyKey: "key"}
+	patchFirst := func(p *Patches, origin *Message, err error) {
+		p.ApplyMethodReturn(&gorm.DB{}, "Clauses", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Where", ps.db)
+		p.ApplyMethod(&gorm.DB{}, "First", func(_ *gorm.DB, dest any, _ ...any) *gorm.DB {
+			if origin != nil {
+				*dest.(*Message) = *origin
+			}
+			return &gorm.DB{Error: err}
+		})
+	}
+
+	t.Run("WithoutKey", func(t *testing.T) {
+		id, err := ps.dedupTx(&gorm.DB{}, &Message{})
+		r.NoError(err)
+		r.Empty(id)
+	})
+
+	t.Run("FailedToFetch", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchFirst(p, nil, errors.New(t.Name()))
+		_, err := ps.dedupTx(&gorm.DB{}, m)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		patchFirst(p, nil, gorm.ErrRecordNotFound)
+		id, err := ps.dedupTx(&gorm.DB{}, m)
+		r.NoError(err)
+		r.Empty(id)
+	})
+
+	t.Run("Duplicated", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		origin := &Message{MessageID: "origin"}
+		origin.CreatedAt = time.Now().Add(-time.Minute)
+		patchFirst(p, origin, nil)
+		id, err := ps.dedupTx(&gorm.DB{}, m)
+		r.NoError(err)
+		r.Equal("origin", id)
+	})
+
+	t.Run("FailedToReleaseExpiredKey", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		origin := &Message{MessageID: "origin"}
+		origin.CreatedAt = time.Now().Add(-2 * time.Hour)
+		patchFirst(p, origin, nil)
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Update", &gorm.DB{Error: errors.New(t.Name())})
+		_, err := ps.dedupTx(&gorm.DB{}, m)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		origin := &Message{MessageID: "origin"}
+		origin.CreatedAt = time.Now().Add(-2 * time.Hour)
+		patchFirst(p, origin, nil)
+		p.ApplyMethodReturn(&gorm.DB{}, "Model", ps.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Update", &gorm.DB{})
+		id, err := ps.dedupTx(&gorm.DB{}, m)
+		r.NoError(err)
+		r.Empty(id)
+	})
+}
+
+func TestPersistence_Save(t *testing.T) {
+	r := require.New(t)
+
+	ps := &Persistence{db: &gorm.DB{Statement: &gorm.Statement{}}}
+	m := &Message{MessageID: "id"}
+
+	t.Run("FailedToDedup", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+		p.ApplyPrivateMethod(ps, "dedupTx", func(*Persistence, *gorm.DB, *Message) (string, error) {
+			return "", errors.New(t.Name())
+		})
+		_, err := ps.Save(m, &Aggregation{}, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Duplicated", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+		p.ApplyPrivateMethod(ps, "dedupTx", func(*Persistence, *gorm.DB, *Message) (string, error) {
+			return "origin", nil
+		})
+		id, err := ps.Save(m, &Aggregation{}, nil)
+		r.NoError(err)
+		r.Equal("origin", id)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethod(&gorm.DB{}, "Transaction", func(_ *gorm.DB, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+			return fc(&gorm.DB{})
+		})
+		p.ApplyPrivateMethod(ps, "dedupTx", func(*Persistence, *gorm.DB, *Message) (string, error) {
+			return "", nil
+		})
+		p.ApplyPrivateMethod(ps, "createMessageTx", func(*Persistence, *gorm.DB, *Message) error {
+			return nil
+		})
+		p.ApplyPrivateMethod(ps, "aggregateTaskTx", func(*Persistence, *gorm.DB, *Aggregation, *Message, *ecdsa.PrivateKey) (bool, error) {
+			return true, nil
+		})
+		id, err := ps.Save(m, &Aggregation{}, nil)
+		r.NoError(err)
+		r.Equal("id", id)
+	})
+}
+
 func TestTask_sign(t *testing.T) {
 	r := require.New(t)
 
@@ -551,4 +1165,21 @@ func TestTask_sign(t *testing.T) {
 		_, err := task.sign(nil, uint64(0), "", nil)
 		r.NoError(err)
 	})
+
+	t.Run("VerifiedByTask", func(t *testing.T) {
+		sk, err := crypto.GenerateKey()
+		r.NoError(err)
+		data := [][]byte{[]byte("a"), []byte("b")}
+
+		task := &Task{}
+		task.ID = 1
+		sig, err := task.sign(sk, 2, "client", data...)
+		r.NoError(err)
+
+		tk := &tasktype.Task{ID: 1, ProjectID: 2, ClientID: "client", Data: data, Signature: sig}
+		r.NoError(tk.VerifySignature(crypto.FromECDSAPub(&sk.PublicKey)))
+
+		tk.Data = [][]byte{data[1], data[0]}
+		r.ErrorContains(tk.VerifySignature(crypto.FromECDSAPub(&sk.PublicKey)), "task signature unmatched")
+	})
 }
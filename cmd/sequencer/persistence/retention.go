@@ -0,0 +1,226 @@
+package persistence
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/machinefi/sprout/util/archive"
+)
+
+// ArchiveComponent is the archive name prefix of the sequencer tasks
+const ArchiveComponent = "sequencer"
+
+// archivedTask is a purged task with its messages, the message data stored as blob is inlined
+type archivedTask struct {
+	Task     *Task      `json:"task"`
+	Messages []*Message `json:"messages"`
+}
+
+// ProcessedTaskID returns the id of the last task of the project processed by the coordinator
+type ProcessedTaskID func(projectID uint64) (uint64, error)
+
+// expiredTasks returns the oldest tasks of the project created before the deadline and processed by the coordinator,
+// so that a lagging coordinator never misses a purged task. the latest task is never expired either, so that the task
+// ids are not reused by sqlite
+func (p *Persistence) expiredTasks(projectID uint64, before time.Time, processed uint64, limit int) ([]*Task, error) {
+	ts := []*Task{}
+	if err := p.db.
+		Where("project_id = ? AND created_at < ? AND id <= ?", projectID, before.UTC(), processed).
+		Where("id < (?)", p.db.Model(&Task{}).Select("MAX(id)")).
+		Order("id").Limit(limit).Find(&ts).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch expired tasks of project %d", projectID)
+	}
+	return ts, nil
+}
+
+// archivedTasks returns the tasks with their messages in the task order
+func (p *Persistence) archivedTasks(ts []*Task) ([]*archivedTask, error) {
+	internalTaskIDs := make([]string, 0, len(ts))
+	for _, t := range ts {
+		internalTaskIDs = append(internalTaskIDs, t.InternalTaskID)
+	}
+	ms := []*Message{}
+	if err := p.db.Where("internal_task_id IN ?", internalTaskIDs).Order("id").Find(&ms).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to fetch messages of expired tasks")
+	}
+	data, err := p.MessageData(ms)
+	if err != nil {
+		return nil, err
+	}
+
+	byTask := map[string][]*Message{}
+	for i, m := range ms {
+		m.Data, m.DataHash = data[i], ""
+		byTask[m.InternalTaskID] = append(byTask[m.InternalTaskID], m)
+	}
+	ats := make([]*archivedTask, 0, len(ts))
+	for _, t := range ts {
+		ats = append(ats, &archivedTask{Task: t, Messages: byTask[t.InternalTaskID]})
+	}
+	return ats, nil
+}
+
+// purgeTx deletes the tasks and their messages, and the blobs no longer referenced
+func (p *Persistence) purgeTx(tx *gorm.DB, ts []*Task) error {
+	taskIDs := make([]uint, 0, len(ts))
+	internalTaskIDs := make([]string, 0, len(ts))
+	for _, t := range ts {
+		taskIDs = append(taskIDs, t.ID)
+		internalTaskIDs = append(internalTaskIDs, t.InternalTaskID)
+	}
+
+	hashes := []string{}
+	if err := tx.Model(&Message{}).Distinct("data_hash").
+		Where("internal_task_id IN ? AND data_hash <> ?", internalTaskIDs, "").
+		Pluck("data_hash", &hashes).Error; err != nil {
+		return errors.Wrap(err, "failed to fetch blob hashes of expired messages")
+	}
+	if err := tx.Unscoped().Where("internal_task_id IN ?", internalTaskIDs).Delete(&Message{}).Error; err != nil {
+		return errors.Wrap(err, "failed to delete expired messages")
+	}
+	if err := tx.Unscoped().Where("id IN ?", taskIDs).Delete(&Task{}).Error; err != nil {
+		return errors.Wrap(err, "failed to delete expired tasks")
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	// the same data may be referenced by the messages kept
+	if err := tx.Unscoped().
+		Where("hash IN ?", hashes).
+		Where("hash NOT IN (?)", tx.Model(&Message{}).Select("data_hash").Where("data_hash IN ?", hashes)).
+		Delete(&Blob{}).Error; err != nil {
+		return errors.Wrap(err, "failed to delete expired blobs")
+	}
+	return nil
+}
+
+// Archive archives the tasks of the project created before the deadline and up to the processed task with their
+// messages to the store, and purges them in batches. the unpacked messages are never purged. returns the number of
+// purged tasks
+func (p *Persistence) Archive(store archive.Store, projectID uint64, before time.Time, processed uint64, batchSize int) (int, error) {
+	purged := 0
+	for {
+		ts, err := p.expiredTasks(projectID, before, processed, batchSize)
+		if err != nil {
+			return purged, err
+		}
+		if len(ts) == 0 {
+			return purged, nil
+		}
+		ats, err := p.archivedTasks(ts)
+		if err != nil {
+			return purged, err
+		}
+		data, err := archive.Encode(ats)
+		if err != nil {
+			return purged, err
+		}
+		// the rows are only purged after archived, a batch archived but failed to purge is archived again with the
+		// same name by the next run
+		if err := store.Put(archive.Name(ArchiveComponent, projectID, uint64(ts[0].ID), uint64(ts[len(ts)-1].ID)), data); err != nil {
+			return purged, err
+		}
+		if err := p.db.Transaction(func(tx *gorm.DB) error { return p.purgeTx(tx, ts) }); err != nil {
+			return purged, err
+		}
+		purged += len(ts)
+	}
+}
+
+// RunRetention archives and purges the expired tasks of every project periodically, the project is skipped if its
+// processed task is unknown. this func will block caller
+func (p *Persistence) RunRetention(interval time.Duration, retention *archive.Retention, store archive.Store, processed ProcessedTaskID, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		projectIDs := []uint64{}
+		if err := p.db.Model(&Task{}).Distinct("project_id").Pluck("project_id", &projectIDs).Error; err != nil {
+			slog.Error("failed to fetch projects of tasks", "error", err)
+			continue
+		}
+		for _, id := range projectIDs {
+			d := retention.Of(id)
+			if d <= 0 {
+				continue
+			}
+			processedID, err := processed(id)
+			if err != nil {
+				slog.Error("failed to fetch processed task", "project_id", id, "error", err)
+				continue
+			}
+			purged, err := p.Archive(store, id, time.Now().Add(-d), processedID, batchSize)
+			if err != nil {
+				slog.Error("failed to archive expired tasks", "project_id", id, "error", err)
+			}
+			if purged > 0 {
+				slog.Info("expired tasks archived", "project_id", id, "tasks", purged)
+			}
+		}
+	}
+}
+
+// restoreTx inserts the archived task and its messages, and returns whether the task is restored. the rows already
+// existing are kept. the ids are restored as is, so the coordinator does not dispatch a restored task again
+func (p *Persistence) restoreTx(tx *gorm.DB, at *archivedTask) (bool, error) {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(at.Task)
+	if err := res.Error; err != nil {
+		return false, errors.Wrapf(err, "failed to restore task %d", at.Task.ID)
+	}
+	for _, m := range at.Messages {
+		// the idempotency window of an archived message is long expired, and the key may be reused
+		m.IdempotencyKey = ""
+		sm, err := p.storedMessageTx(tx, m)
+		if err != nil {
+			return false, err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(sm).Error; err != nil {
+			return false, errors.Wrapf(err, "failed to restore message %s", m.MessageID)
+		}
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// Restore reloads the archived tasks of the project from first to last with their messages, and returns the number
+// of restored tasks. note the restored tasks are purged again by the retention if they are still expired
+func (p *Persistence) Restore(store archive.Store, projectID, first, last uint64) (int, error) {
+	names, err := archive.Find(store, ArchiveComponent, projectID, first, last)
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, name := range names {
+		data, err := store.Get(name)
+		if err != nil {
+			return restored, err
+		}
+		ats, err := archive.Decode[*archivedTask](data)
+		if err != nil {
+			return restored, errors.Wrapf(err, "invalid archive %s", name)
+		}
+		n := 0
+		if err := p.db.Transaction(func(tx *gorm.DB) error {
+			for _, at := range ats {
+				if id := uint64(at.Task.ID); id < first || id > last {
+					continue
+				}
+				ok, err := p.restoreTx(tx, at)
+				if err != nil {
+					return err
+				}
+				if ok {
+					n++
+				}
+			}
+			return nil
+		}); err != nil {
+			return restored, errors.Wrapf(err, "failed to restore archive %s", name)
+		}
+		restored += n
+	}
+	return restored, nil
+}
@@ -0,0 +1,105 @@
+package persistence
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqlitePollInterval is the interval of polling the packed tasks, sqlite has no notification across connections
+const sqlitePollInterval = 500 * time.Millisecond
+
+// sqliteBackend is the embedded database for development and tests. sqlite has no row locking, the transactions are
+// serialized by the single connection instead, so the messages are still packed exactly once
+type sqliteBackend struct {
+	dsn string
+}
+
+// sqliteDSN appends the options of the sequencer to dsn, the coordinator datasource could read the file while the
+// sequencer is writing in wal mode
+func sqliteDSN(dsn string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+}
+
+func (b *sqliteBackend) dialector() gorm.Dialector {
+	return sqlite.Open(sqliteDSN(b.dsn))
+}
+
+func (b *sqliteBackend) prepare(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return errors.Wrap(err, "failed to get sql db")
+	}
+	// an in memory database is also private to its connection
+	sqlDB.SetMaxOpenConns(1)
+	// the times are stored as text, which are only ordered in the same time zone
+	db.Config.NowFunc = func() time.Time { return time.Now().UTC() }
+	return nil
+}
+
+// notifyPackedTx does nothing, the packed tasks are polled by listen
+func (b *sqliteBackend) notifyPackedTx(*gorm.DB, *Task) error {
+	return nil
+}
+
+// listen polls the tasks packed after listening started
+func (b *sqliteBackend) listen(db *gorm.DB, publish func(string)) error {
+	var last uint
+	if err := db.Model(&Task{}).Select("COALESCE(MAX(id), 0)").Scan(&last).Error; err != nil {
+		return errors.Wrap(err, "failed to fetch latest task")
+	}
+
+	ticker := time.NewTicker(sqlitePollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ts := []*Task{}
+		if err := db.Select("id", "internal_task_id").Where("id > ?", last).Order("id").Find(&ts).Error; err != nil {
+			slog.Error("failed to poll packed tasks", "error", err)
+			continue
+		}
+		for _, t := range ts {
+			publish(t.InternalTaskID)
+			last = t.ID
+		}
+	}
+	return nil
+}
+
+// aggregatedTime is the time aggregated by sql, e.g. MIN(created_at), which is scanned as text on sqlite since the
+// aggregation loses the column type
+type aggregatedTime struct {
+	time.Time
+}
+
+func (t *aggregatedTime) Scan(v any) error {
+	switch v := v.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	}
+	return errors.Errorf("unsupported aggregated time %T", v)
+}
+
+func (t *aggregatedTime) parse(s string) error {
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if v, err := time.Parse(layout, s); err == nil {
+			t.Time = v
+			return nil
+		}
+	}
+	return errors.Errorf("invalid aggregated time %s", s)
+}
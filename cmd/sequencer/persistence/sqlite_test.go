@@ -0,0 +1,377 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tasktype "github.com/machinefi/sprout/task"
+	"github.com/machinefi/sprout/util/archive"
+	"github.com/machinefi/sprout/util/merkle"
+)
+
+func newSQLitePersistence(t *testing.T) *Persistence {
+	p, err := NewPersistence(SQLiteScheme+filepath.Join(t.TempDir(), "sequencer.db"), time.Hour)
+	require.NoError(t, err)
+	return p
+}
+
+func newSQLiteMessage(clientID string, projectID uint64, data []byte) *Message {
+	return &Message{
+		MessageID:      uuid.NewString(),
+		ClientID:       clientID,
+		ProjectID:      projectID,
+		ProjectVersion: "0.1",
+		Data:           data,
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(&sqliteBackend{dsn: "./sequencer.db"}, newBackend("sqlite:./sequencer.db"))
+	r.Equal(&postgresBackend{dsn: "postgres://localhost:5432/test"}, newBackend("postgres://localhost:5432/test"))
+}
+
+func TestSqliteDSN(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("./sequencer.db?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", sqliteDSN("./sequencer.db"))
+	r.Equal("file:test.db?cache=shared&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", sqliteDSN("file:test.db?cache=shared"))
+}
+
+func TestSQLite_Save(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	t.Run("Aggregate", func(t *testing.T) {
+		p := newSQLitePersistence(t)
+		ag := &Aggregation{Amount: 2}
+
+		m1 := newSQLiteMessage("client", 1, []byte("m1"))
+		_, err := p.Save(m1, ag, sk)
+		r.NoError(err)
+		ms, err := p.FetchMessage(m1.MessageID)
+		r.NoError(err)
+		r.Len(ms, 1)
+		r.Empty(ms[0].InternalTaskID)
+
+		// the message of another client is not packed with m1
+		_, err = p.Save(newSQLiteMessage("other", 1, []byte("other")), ag, sk)
+		r.NoError(err)
+
+		m2 := newSQLiteMessage("client", 1, bytes.Repeat([]byte("a"), InlineDataSize+1))
+		_, err = p.Save(m2, ag, sk)
+		r.NoError(err)
+
+		ms, err = p.FetchMessage(m2.MessageID)
+		r.NoError(err)
+		r.NotEmpty(ms[0].InternalTaskID)
+		r.NotEmpty(ms[0].DataHash)
+
+		ts, err := p.FetchTask(ms[0].InternalTaskID)
+		r.NoError(err)
+		r.Len(ts, 1)
+		r.NotEmpty(ts[0].Signature)
+		r.NotEmpty(ts[0].MerkleRoot)
+		ids := []string{}
+		r.NoError(json.Unmarshal(ts[0].MessageIDs, &ids))
+		r.Equal([]string{m1.MessageID, m2.MessageID}, ids)
+		proof, err := ts[0].MessageProof(m2.MessageID)
+		r.NoError(err)
+		r.True(merkle.Verify(common.HexToHash(ts[0].MerkleRoot), m2.Data, proof))
+
+		data, err := p.MessageData(ms)
+		r.NoError(err)
+		r.Equal([][]byte{m2.Data}, data)
+	})
+
+	t.Run("Idempotency", func(t *testing.T) {
+		p := newSQLitePersistence(t)
+		ag := &Aggregation{Amount: 10}
+
+		m := newSQLiteMessage("client", 1, []byte("data"))
+		m.IdempotencyKey = "key"
+		id, err := p.Save(m, ag, sk)
+		r.NoError(err)
+
+		dup := newSQLiteMessage("client", 1, []byte("data"))
+		dup.IdempotencyKey = "key"
+		dupID, err := p.Save(dup, ag, sk)
+		r.NoError(err)
+		r.Equal(id, dupID)
+
+		n, err := p.CountMessages(&MessageFilter{ClientID: "client"})
+		r.NoError(err)
+		r.Equal(uint64(1), n)
+	})
+}
+
+func TestSQLite_SaveBatch(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	p := newSQLitePersistence(t)
+	policy := func(uint64) *Aggregation { return &Aggregation{Amount: 2, AcrossClients: true} }
+
+	msgs := []*Message{
+		newSQLiteMessage("a", 1, []byte("1")),
+		newSQLiteMessage("b", 1, []byte("2")),
+		newSQLiteMessage("a", 1, []byte("3")),
+		newSQLiteMessage("b", 1, []byte("4")),
+		newSQLiteMessage("a", 1, []byte("5")),
+	}
+	ids, err := p.SaveBatch(msgs, policy, sk)
+	r.NoError(err)
+	r.Len(ids, 5)
+
+	ts, err := p.ListTasks(&TaskFilter{ProjectID: 1, Limit: 10})
+	r.NoError(err)
+	r.Len(ts, 2)
+
+	packed := true
+	n, err := p.CountMessages(&MessageFilter{ProjectID: 1, Packed: &packed})
+	r.NoError(err)
+	r.Equal(uint64(4), n)
+
+	ts, err = p.ListTasks(&TaskFilter{ClientID: "b", Limit: 10})
+	r.NoError(err)
+	r.Len(ts, 2)
+}
+
+func TestSQLite_AcrossClientsTaskSignature(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	p := newSQLitePersistence(t)
+	ag := &Aggregation{Amount: 2, AcrossClients: true}
+
+	verify := func(clientID string, ms ...*Message) {
+		for _, m := range ms {
+			_, err := p.Save(m, ag, sk)
+			r.NoError(err)
+		}
+		fetched, err := p.FetchMessage(ms[0].MessageID)
+		r.NoError(err)
+		ts, err := p.FetchTask(fetched[0].InternalTaskID)
+		r.NoError(err)
+		r.Len(ts, 1)
+		data, err := p.MessageData(ms)
+		r.NoError(err)
+
+		tk := &tasktype.Task{ID: uint64(ts[0].ID), ProjectID: 1, ClientID: clientID, Data: data, Signature: ts[0].Signature}
+		r.NoError(tk.VerifySignature(crypto.FromECDSAPub(&sk.PublicKey)))
+	}
+
+	t.Run("SingleClient", func(t *testing.T) {
+		verify("a", newSQLiteMessage("a", 1, []byte("1")), newSQLiteMessage("a", 1, []byte("2")))
+	})
+	t.Run("SeveralClients", func(t *testing.T) {
+		verify("", newSQLiteMessage("a", 1, []byte("3")), newSQLiteMessage("b", 1, []byte("4")))
+	})
+}
+
+func TestSQLite_Flush(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	p := newSQLitePersistence(t)
+	ag := &Aggregation{Amount: 10, MaxLatency: time.Millisecond}
+	policy := func(uint64) *Aggregation { return ag }
+
+	m := newSQLiteMessage("client", 1, []byte("data"))
+	_, err = p.Save(m, ag, sk)
+	r.NoError(err)
+
+	time.Sleep(10 * time.Millisecond)
+	packed, err := p.Flush(policy, sk)
+	r.NoError(err)
+	r.Equal(1, packed)
+
+	ms, err := p.FetchMessage(m.MessageID)
+	r.NoError(err)
+	r.NotEmpty(ms[0].InternalTaskID)
+
+	packed, err = p.Flush(policy, sk)
+	r.NoError(err)
+	r.Equal(0, packed)
+}
+
+func TestSQLite_ListMessages(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	p := newSQLitePersistence(t)
+	ag := &Aggregation{Amount: 10}
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Save(newSQLiteMessage("client", 1, []byte("data")), ag, sk)
+		r.NoError(err)
+	}
+
+	// the times of other zones are compared in utc
+	zone := time.FixedZone("UTC+8", 8*60*60)
+	ms, err := p.ListMessages(&MessageFilter{ClientID: "client", Since: time.Now().Add(-time.Minute).In(zone), Limit: 2})
+	r.NoError(err)
+	r.Len(ms, 2)
+
+	ms, err = p.ListMessages(&MessageFilter{ClientID: "client", After: ms[1].ID, Limit: 2})
+	r.NoError(err)
+	r.Len(ms, 1)
+
+	ms, err = p.ListMessages(&MessageFilter{ClientID: "client", Until: time.Now().Add(-time.Minute).In(zone), Limit: 2})
+	r.NoError(err)
+	r.Len(ms, 0)
+}
+
+func TestSQLite_RunListener(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	p := newSQLitePersistence(t)
+	go func() {
+		_ = p.RunListener()
+	}()
+	// the tasks packed before listening are not published
+	time.Sleep(100 * time.Millisecond)
+
+	m := newSQLiteMessage("client", 1, []byte("data"))
+	ch, cancel := p.SubscribePacked(m.MessageID)
+	defer cancel()
+
+	_, err = p.Save(m, &Aggregation{Amount: 1}, sk)
+	r.NoError(err)
+
+	select {
+	case task := <-ch:
+		ms, err := p.FetchMessage(m.MessageID)
+		r.NoError(err)
+		r.Equal(ms[0].InternalTaskID, task.InternalTaskID)
+	case <-time.After(5 * time.Second):
+		r.Fail("packed task not published")
+	}
+}
+
+func TestSQLite_Archive(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	p := newSQLitePersistence(t)
+	ag := &Aggregation{Amount: 1}
+
+	ms := []*Message{
+		newSQLiteMessage("client", 1, []byte("1")),
+		newSQLiteMessage("client", 1, bytes.Repeat([]byte("a"), InlineDataSize+1)),
+		newSQLiteMessage("client", 1, []byte("3")),
+	}
+	for _, m := range ms {
+		_, err := p.Save(m, ag, sk)
+		r.NoError(err)
+	}
+	// an unpacked message is never purged
+	unpacked := newSQLiteMessage("client", 2, []byte("4"))
+	_, err = p.Save(unpacked, &Aggregation{Amount: 2}, sk)
+	r.NoError(err)
+	r.NoError(p.db.Exec("UPDATE messages SET created_at = ?", time.Now().Add(-48*time.Hour).UTC()).Error)
+	r.NoError(p.db.Exec("UPDATE tasks SET created_at = ?", time.Now().Add(-48*time.Hour).UTC()).Error)
+
+	ts, err := p.ListTasks(&TaskFilter{ProjectID: 1, Limit: 10})
+	r.NoError(err)
+	r.Len(ts, 3)
+
+	store, err := archive.NewStore(t.TempDir())
+	r.NoError(err)
+
+	t.Run("CoordinatorLagging", func(t *testing.T) {
+		// the expired tasks not processed by the coordinator are kept
+		purged, err := p.Archive(store, 1, time.Now().Add(-24*time.Hour), uint64(ts[0].ID)-1, 1)
+		r.NoError(err)
+		r.Zero(purged)
+
+		left, err := p.ListTasks(&TaskFilter{ProjectID: 1, Limit: 10})
+		r.NoError(err)
+		r.Len(left, 3)
+		names, err := store.List(ArchiveComponent + "/1/")
+		r.NoError(err)
+		r.Empty(names)
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		purged, err := p.Archive(store, 1, time.Now().Add(-24*time.Hour), uint64(ts[2].ID), 1)
+		r.NoError(err)
+		// the latest task is kept
+		r.Equal(2, purged)
+
+		names, err := store.List(ArchiveComponent + "/1/")
+		r.NoError(err)
+		r.Equal([]string{
+			archive.Name(ArchiveComponent, 1, uint64(ts[0].ID), uint64(ts[0].ID)),
+			archive.Name(ArchiveComponent, 1, uint64(ts[1].ID), uint64(ts[1].ID)),
+		}, names)
+
+		left, err := p.ListTasks(&TaskFilter{ProjectID: 1, Limit: 10})
+		r.NoError(err)
+		r.Len(left, 1)
+		r.Equal(ts[2].ID, left[0].ID)
+
+		n, err := p.CountMessages(&MessageFilter{})
+		r.NoError(err)
+		r.Equal(uint64(2), n)
+		var blobs int64
+		r.NoError(p.db.Model(&Blob{}).Count(&blobs).Error)
+		r.Zero(blobs)
+
+		purged, err = p.Archive(store, 1, time.Now().Add(-24*time.Hour), uint64(ts[2].ID), 1)
+		r.NoError(err)
+		r.Zero(purged)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		restored, err := p.Restore(store, 1, uint64(ts[1].ID), uint64(ts[2].ID))
+		r.NoError(err)
+		r.Equal(1, restored)
+
+		fetched, err := p.FetchTask(ts[1].InternalTaskID)
+		r.NoError(err)
+		r.Len(fetched, 1)
+		r.Equal(ts[1].ID, fetched[0].ID)
+		r.Equal(ts[1].Signature, fetched[0].Signature)
+
+		restoredMs, err := p.FetchMessage(ms[1].MessageID)
+		r.NoError(err)
+		r.Len(restoredMs, 1)
+		r.NotEmpty(restoredMs[0].DataHash)
+		data, err := p.MessageData(restoredMs)
+		r.NoError(err)
+		r.Equal([][]byte{ms[1].Data}, data)
+
+		// the rows restored already are kept
+		restored, err = p.Restore(store, 1, 0, uint64(ts[2].ID))
+		r.NoError(err)
+		r.Equal(1, restored)
+		n, err := p.CountMessages(&MessageFilter{ProjectID: 1})
+		r.NoError(err)
+		r.Equal(uint64(3), n)
+	})
+}
@@ -0,0 +1,333 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the reasons of rejection, also used as metrics labels
+const (
+	ReasonRateLimited   = "rate_limited"
+	ReasonQuotaExceeded = "quota_exceeded"
+)
+
+// Limit is the token bucket rate and the daily quota of messages, a zero field is unlimited, or falls back to the
+// lower priority limit when used as an override
+type Limit struct {
+	Rate       float64 `json:"rate,omitempty"`       // messages per second
+	Burst      uint64  `json:"burst,omitempty"`      // the bucket size, defaults to the rate rounded up
+	DailyQuota uint64  `json:"dailyQuota,omitempty"` // messages per UTC day
+}
+
+func (l *Limit) apply(o *Limit) {
+	if o == nil {
+		return
+	}
+	if o.Rate > 0 {
+		l.Rate = o.Rate
+	}
+	if o.Burst > 0 {
+		l.Burst = o.Burst
+	}
+	if o.DailyQuota > 0 {
+		l.DailyQuota = o.DailyQuota
+	}
+}
+
+func (l *Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+func (l *Limit) validate() error {
+	if l.Rate < 0 || math.IsNaN(l.Rate) || math.IsInf(l.Rate, 0) {
+		return errors.Errorf("invalid rate %v", l.Rate)
+	}
+	return nil
+}
+
+// Config is the rate limit file, e.g.
+// {"client": {"rate": 10, "burst": 20}, "project": {"dailyQuota": 100000}, "projects": {"1": {"rate": 100}}}
+type Config struct {
+	Client   *Limit            `json:"client,omitempty"`   // the default limit of each client
+	Project  *Limit            `json:"project,omitempty"`  // the default limit of each project
+	Clients  map[string]*Limit `json:"clients,omitempty"`  // the overrides by client did
+	Projects map[string]*Limit `json:"projects,omitempty"` // the overrides by project id
+}
+
+// LoadConfig loads the rate limit file
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read rate limit file %s", path)
+	}
+	c := &Config{}
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode rate limit file %s", path)
+	}
+	return c, nil
+}
+
+// ProjectLimit returns the limit defined by the project itself, e.g. by the project contract attributes. returns nil
+// if the project defines none
+type ProjectLimit func(projectID uint64) (*Limit, error)
+
+// Usage returns the amount of accepted messages of the client or the project since the time, the project id is 0 when
+// counting the client. it seeds the daily quota so that the quota is not reset by restarting
+type Usage func(clientID string, projectID uint64, since time.Time) (uint64, error)
+
+// Rejection is the error of a rejected request
+type Rejection struct {
+	Reason     string
+	RetryAfter time.Duration
+	msg        string
+}
+
+func (r *Rejection) Error() string {
+	return r.msg
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket is refilled to full, could be dropped after then
+}
+
+// Limiter limits messages by the client and the project with token buckets and daily quotas. the usage is kept in
+// memory, and the daily quota is seeded by Usage when first used in a day
+type Limiter struct {
+	conf         *Config
+	projects     map[uint64]*Limit
+	projectLimit ProjectLimit // optional
+	usage        Usage        // optional
+	now          func() time.Time
+
+	mux     sync.Mutex
+	cache   map[uint64]*Limit // the resolved project limits
+	buckets map[string]*bucket
+	quotas  map[string]uint64 // the used quotas of the day
+	day     time.Time
+}
+
+func NewLimiter(conf *Config, projectLimit ProjectLimit, usage Usage) (*Limiter, error) {
+	if conf == nil {
+		conf = &Config{}
+	}
+	l := &Limiter{
+		conf:         conf,
+		projects:     map[uint64]*Limit{},
+		projectLimit: projectLimit,
+		usage:        usage,
+		now:          time.Now,
+		cache:        map[uint64]*Limit{},
+		buckets:      map[string]*bucket{},
+		quotas:       map[string]uint64{},
+	}
+	for _, c := range []*Limit{conf.Client, conf.Project} {
+		if c == nil {
+			continue
+		}
+		if err := c.validate(); err != nil {
+			return nil, errors.Wrap(err, "invalid default limit")
+		}
+	}
+	for id, c := range conf.Clients {
+		if err := c.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid limit of client %s", id)
+		}
+	}
+	for k, c := range conf.Projects {
+		projectID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid project id %s in rate limit config", k)
+		}
+		if err := c.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid limit of project %d", projectID)
+		}
+		l.projects[projectID] = c
+	}
+	return l, nil
+}
+
+func (l *Limiter) clientLimit(clientID string) *Limit {
+	lm := &Limit{}
+	lm.apply(l.conf.Client)
+	lm.apply(l.conf.Clients[clientID])
+	return lm
+}
+
+func (l *Limiter) resolve(projectID uint64) (*Limit, bool) {
+	lm := &Limit{}
+	lm.apply(l.conf.Project)
+	cacheable := true
+	if l.projectLimit != nil {
+		c, err := l.projectLimit(projectID)
+		if err != nil {
+			// fall back without caching, the project limit will be fetched again next time
+			slog.Error("failed to get project rate limit", "project_id", projectID, "error", err)
+			cacheable = false
+		} else if c != nil {
+			if err := c.validate(); err != nil {
+				slog.Error("invalid project rate limit", "project_id", projectID, "error", err)
+			} else {
+				lm.apply(c)
+			}
+		}
+	}
+	lm.apply(l.projects[projectID])
+	return lm, cacheable
+}
+
+func (l *Limiter) projectLimitOf(projectID uint64) *Limit {
+	l.mux.Lock()
+	lm, ok := l.cache[projectID]
+	l.mux.Unlock()
+	if ok {
+		return lm
+	}
+
+	lm, cacheable := l.resolve(projectID)
+	if cacheable {
+		l.mux.Lock()
+		l.cache[projectID] = lm
+		l.mux.Unlock()
+	}
+	return lm
+}
+
+// Refresh drops the cached limit of the project
+func (l *Limiter) Refresh(projectID uint64) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	delete(l.cache, projectID)
+}
+
+// Watch refreshes the limit of the updated projects, this func will block caller
+func (l *Limiter) Watch(projectNotification <-chan uint64) {
+	for pid := range projectNotification {
+		l.Refresh(pid)
+	}
+}
+
+type subject struct {
+	key       string
+	limit     *Limit
+	clientID  string
+	projectID uint64
+}
+
+// Allow takes n messages from the limits of the client and the project, nothing is taken if rejected. the client id
+// is the client did, or any other identity of an anonymous client which is not seeded by Usage
+func (l *Limiter) Allow(clientID string, projectID uint64, n uint64) *Rejection {
+	subjects := []*subject{
+		{key: "client:" + clientID, limit: l.clientLimit(clientID), clientID: clientID},
+		{key: fmt.Sprintf("project:%d", projectID), limit: l.projectLimitOf(projectID), projectID: projectID},
+	}
+	now := l.now()
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	// seed the quotas outside the lock, the usage is usually a database query
+	seeds := map[string]uint64{}
+	for _, s := range subjects {
+		if s.limit.DailyQuota == 0 || l.usage == nil || l.seeded(s.key, day) {
+			continue
+		}
+		used, err := l.usage(s.clientID, s.projectID, day)
+		if err != nil {
+			slog.Error("failed to get message usage", "key", s.key, "error", err)
+			continue
+		}
+		seeds[s.key] = used
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.rotate(now, day)
+	for k, used := range seeds {
+		if _, ok := l.quotas[k]; !ok {
+			l.quotas[k] = used
+		}
+	}
+
+	for _, s := range subjects {
+		if q := s.limit.DailyQuota; q > 0 && l.quotas[s.key]+n > q {
+			return &Rejection{
+				Reason:     ReasonQuotaExceeded,
+				RetryAfter: day.Add(24 * time.Hour).Sub(now),
+				msg:        fmt.Sprintf("%s exceeds daily quota %d", s.key, q),
+			}
+		}
+		if s.limit.Rate == 0 {
+			continue
+		}
+		// a request larger than the burst is allowed with a full bucket, and the bucket goes into debt
+		need := math.Min(float64(n), s.limit.burst())
+		if tokens := l.refill(s, now); tokens < need {
+			return &Rejection{
+				Reason:     ReasonRateLimited,
+				RetryAfter: time.Duration((need - tokens) / s.limit.Rate * float64(time.Second)),
+				msg:        fmt.Sprintf("%s exceeds rate limit %v/s", s.key, s.limit.Rate),
+			}
+		}
+	}
+
+	for _, s := range subjects {
+		if s.limit.DailyQuota > 0 {
+			l.quotas[s.key] += n
+		}
+		if s.limit.Rate > 0 {
+			b := l.buckets[s.key]
+			b.tokens -= float64(n)
+			b.full = now.Add(time.Duration((s.limit.burst() - b.tokens) / s.limit.Rate * float64(time.Second)))
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) seeded(key string, day time.Time) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	_, ok := l.quotas[key]
+	return ok && l.day.Equal(day)
+}
+
+// rotate resets the quotas and drops the full buckets when the day changes
+func (l *Limiter) rotate(now, day time.Time) {
+	if l.day.Equal(day) {
+		return
+	}
+	l.day = day
+	l.quotas = map[string]uint64{}
+	for k, b := range l.buckets {
+		if !b.full.After(now) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// refill refills the bucket of the subject and returns the tokens, a new bucket is full
+func (l *Limiter) refill(s *subject, now time.Time) float64 {
+	b, ok := l.buckets[s.key]
+	if !ok {
+		b = &bucket{tokens: s.limit.burst(), last: now}
+		l.buckets[s.key] = b
+	}
+	if now.After(b.last) {
+		b.tokens = math.Min(s.limit.burst(), b.tokens+now.Sub(b.last).Seconds()*s.limit.Rate)
+		b.last = now
+	}
+	return b.tokens
+}
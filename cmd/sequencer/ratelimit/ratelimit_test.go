@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	t.Run("FailedToRead", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(dir, "not_exist.json"))
+		r.ErrorContains(err, "failed to read rate limit file")
+	})
+
+	t.Run("FailedToDecode", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		r.NoError(os.WriteFile(path, []byte("{"), 0600))
+		_, err := LoadConfig(path)
+		r.ErrorContains(err, "failed to decode rate limit file")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(dir, "limit.json")
+		r.NoError(os.WriteFile(path, []byte(`{"client": {"rate": 10, "burst": 20}, "projects": {"1": {"dailyQuota": 100}}}`), 0600))
+		c, err := LoadConfig(path)
+		r.NoError(err)
+		r.Equal(&Limit{Rate: 10, Burst: 20}, c.Client)
+		r.Equal(&Limit{DailyQuota: 100}, c.Projects["1"])
+	})
+}
+
+func TestNewLimiter(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidDefaultLimit", func(t *testing.T) {
+		_, err := NewLimiter(&Config{Client: &Limit{Rate: -1}}, nil, nil)
+		r.ErrorContains(err, "invalid default limit")
+	})
+
+	t.Run("InvalidClientLimit", func(t *testing.T) {
+		_, err := NewLimiter(&Config{Clients: map[string]*Limit{"did": {Rate: -1}}}, nil, nil)
+		r.ErrorContains(err, "invalid limit of client did")
+	})
+
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		_, err := NewLimiter(&Config{Projects: map[string]*Limit{"a": {}}}, nil, nil)
+		r.ErrorContains(err, "invalid project id a")
+	})
+
+	t.Run("InvalidProjectLimit", func(t *testing.T) {
+		_, err := NewLimiter(&Config{Projects: map[string]*Limit{"1": {Rate: -1}}}, nil, nil)
+		r.ErrorContains(err, "invalid limit of project 1")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		l, err := NewLimiter(nil, nil, nil)
+		r.NoError(err)
+		r.Nil(l.Allow("did", 1, 1000))
+	})
+}
+
+func TestLimiter_resolve(t *testing.T) {
+	r := require.New(t)
+
+	conf := &Config{
+		Project:  &Limit{Rate: 1, DailyQuota: 100},
+		Projects: map[string]*Limit{"1": {DailyQuota: 10}},
+	}
+
+	t.Run("FailedToGetProjectLimit", func(t *testing.T) {
+		l, err := NewLimiter(conf, func(uint64) (*Limit, error) { return nil, errors.New(t.Name()) }, nil)
+		r.NoError(err)
+		lm, cacheable := l.resolve(1)
+		r.False(cacheable)
+		r.Equal(&Limit{Rate: 1, DailyQuota: 10}, lm)
+	})
+
+	t.Run("InvalidProjectLimit", func(t *testing.T) {
+		l, err := NewLimiter(conf, func(uint64) (*Limit, error) { return &Limit{Rate: -1}, nil }, nil)
+		r.NoError(err)
+		lm, cacheable := l.resolve(2)
+		r.True(cacheable)
+		r.Equal(&Limit{Rate: 1, DailyQuota: 100}, lm)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		l, err := NewLimiter(conf, func(uint64) (*Limit, error) { return &Limit{Rate: 5, Burst: 10, DailyQuota: 50}, nil }, nil)
+		r.NoError(err)
+		lm, cacheable := l.resolve(1)
+		r.True(cacheable)
+		r.Equal(&Limit{Rate: 5, Burst: 10, DailyQuota: 10}, lm)
+	})
+}
+
+func TestLimiter_projectLimitOf(t *testing.T) {
+	r := require.New(t)
+
+	called := 0
+	l, err := NewLimiter(nil, func(uint64) (*Limit, error) {
+		called++
+		return &Limit{Rate: float64(called)}, nil
+	}, nil)
+	r.NoError(err)
+
+	r.Equal(1.0, l.projectLimitOf(1).Rate)
+	r.Equal(1.0, l.projectLimitOf(1).Rate)
+	r.Equal(1, called)
+
+	notification := make(chan uint64, 1)
+	notification <- 1
+	close(notification)
+	l.Watch(notification)
+	r.Equal(2.0, l.projectLimitOf(1).Rate)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+	newLimiter := func(conf *Config, usage Usage) *Limiter {
+		l, err := NewLimiter(conf, nil, usage)
+		r.NoError(err)
+		l.now = func() time.Time { return now }
+		return l
+	}
+
+	t.Run("RateLimited", func(t *testing.T) {
+		l := newLimiter(&Config{Client: &Limit{Rate: 2}}, nil)
+		r.Nil(l.Allow("did", 1, 1))
+		r.Nil(l.Allow("did", 2, 1))
+
+		rej := l.Allow("did", 1, 1)
+		r.NotNil(rej)
+		r.Equal(ReasonRateLimited, rej.Reason)
+		r.Equal(500*time.Millisecond, rej.RetryAfter)
+		r.Contains(rej.Error(), "client:did exceeds rate limit")
+
+		// other clients are not affected
+		r.Nil(l.Allow("other", 1, 1))
+
+		l.now = func() time.Time { return now.Add(500 * time.Millisecond) }
+		r.Nil(l.Allow("did", 1, 1))
+	})
+
+	t.Run("Burst", func(t *testing.T) {
+		l := newLimiter(&Config{Project: &Limit{Rate: 1, Burst: 5}}, nil)
+		r.Nil(l.Allow("did", 1, 10))
+
+		rej := l.Allow("did", 1, 1)
+		r.NotNil(rej)
+		r.Equal(6*time.Second, rej.RetryAfter)
+	})
+
+	t.Run("QuotaExceeded", func(t *testing.T) {
+		l := newLimiter(&Config{Project: &Limit{DailyQuota: 3}}, nil)
+		r.Nil(l.Allow("did", 1, 2))
+
+		rej := l.Allow("other", 1, 2)
+		r.NotNil(rej)
+		r.Equal(ReasonQuotaExceeded, rej.Reason)
+		r.Equal(time.Minute, rej.RetryAfter)
+		r.Contains(rej.Error(), "project:1 exceeds daily quota 3")
+		r.Nil(l.Allow("other", 1, 1))
+
+		// reset next day
+		l.now = func() time.Time { return now.Add(time.Minute) }
+		r.Nil(l.Allow("other", 1, 3))
+	})
+
+	t.Run("NothingTakenIfRejected", func(t *testing.T) {
+		l := newLimiter(&Config{Client: &Limit{DailyQuota: 2}, Projects: map[string]*Limit{"1": {DailyQuota: 1}}}, nil)
+		r.NotNil(l.Allow("did", 1, 2))
+		r.Nil(l.Allow("did", 2, 2))
+	})
+
+	t.Run("SeededByUsage", func(t *testing.T) {
+		seeded := map[string]time.Time{}
+		l := newLimiter(&Config{Client: &Limit{DailyQuota: 10}}, func(clientID string, projectID uint64, since time.Time) (uint64, error) {
+			r.Equal(uint64(0), projectID)
+			seeded[clientID] = since
+			if clientID == "failed" {
+				return 0, errors.New(t.Name())
+			}
+			return 9, nil
+		})
+		r.Nil(l.Allow("did", 1, 1))
+		r.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), seeded["did"])
+		r.NotNil(l.Allow("did", 1, 1))
+
+		// not limited if failed to seed
+		r.Nil(l.Allow("failed", 1, 10))
+	})
+
+	t.Run("DropFullBuckets", func(t *testing.T) {
+		l := newLimiter(&Config{Client: &Limit{Rate: 1}}, nil)
+		r.Nil(l.Allow("did", 1, 1))
+		r.Len(l.buckets, 1)
+
+		l.now = func() time.Time { return now.Add(time.Hour) }
+		r.Nil(l.Allow("other", 1, 1))
+		r.Len(l.buckets, 1)
+	})
+}
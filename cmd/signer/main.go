@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/signer"
+)
+
+var (
+	endpoint          string
+	token             string
+	keyID             string
+	keyIDED25519      string
+	privateKeyECDSA   string
+	privateKeyED25519 string
+)
+
+func init() {
+	flag.StringVar(&endpoint, "endpoint", ":9100", "signer http listen address")
+	flag.StringVar(&token, "token", "", "bearer token required by requests, optional")
+	flag.StringVar(&keyID, "keyID", "operator", "key id of the secp256k1 key")
+	flag.StringVar(&keyIDED25519, "keyIDED25519", "operator", "key id of the ed25519 key")
+	flag.StringVar(&privateKeyECDSA, "privateKeyECDSA", "", "hex encoded secp256k1 private key")
+	flag.StringVar(&privateKeyED25519, "privateKeyED25519", "", "hex encoded ed25519 private key")
+}
+
+// a local stand-in of the remote signer service, for development only
+func main() {
+	flag.Parse()
+
+	s := signer.NewServer(token)
+	if privateKeyECDSA != "" {
+		sk, err := crypto.ToECDSA(common.FromHex(privateKeyECDSA))
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "failed to parse ecdsa private key"))
+		}
+		s.AddECDSA(keyID, sk)
+		slog.Info("secp256k1 key loaded", "key_id", keyID, "address", crypto.PubkeyToAddress(sk.PublicKey))
+	}
+	if privateKeyED25519 != "" {
+		b := common.FromHex(privateKeyED25519)
+		if len(b) != ed25519.PrivateKeySize {
+			log.Fatal(errors.Errorf("invalid ed25519 private key length %d", len(b)))
+		}
+		s.AddED25519(keyIDED25519, ed25519.PrivateKey(b))
+		slog.Info("ed25519 key loaded", "key_id", keyIDED25519)
+	}
+
+	slog.Info("signer started", "endpoint", endpoint)
+	if err := http.ListenAndServe(endpoint, s); err != nil {
+		log.Fatal(errors.Wrap(err, "failed to serve signer"))
+	}
+}
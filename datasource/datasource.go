@@ -1,7 +1,33 @@
 package datasource
 
-import tasktype "github.com/machinefi/sprout/task"
+import (
+	"strings"
+
+	tasktype "github.com/machinefi/sprout/task"
+)
 
 type Datasource interface {
 	Retrieve(projectID, nextTaskID uint64) (*tasktype.Task, error)
 }
+
+// Datasources creates the datasource by uri, the datasources of the same uri share the connections
+type Datasources struct {
+	pg     *Postgres
+	sqlite *SQLite
+}
+
+// New returns the datasource of uri, the uri prefixed by SQLiteScheme is the embedded sqlite database of the
+// sequencer, others are postgres dsn
+func (d *Datasources) New(uri string) (Datasource, error) {
+	if dsn, ok := strings.CutPrefix(uri, SQLiteScheme); ok {
+		return d.sqlite.New(dsn)
+	}
+	return d.pg.New(uri)
+}
+
+func NewDatasources() *Datasources {
+	return &Datasources{
+		pg:     NewPostgres(),
+		sqlite: NewSQLite(),
+	}
+}
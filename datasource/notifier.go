@@ -0,0 +1,131 @@
+package datasource
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TaskPackedChannel is the postgres notification channel of packed tasks the sequencer notifies, the payload is the
+// project id of the packed task
+const TaskPackedChannel = "task_packed"
+
+// Subscriber is implemented by the datasource notifying new tasks, the dispatcher waits for the notification instead
+// of polling the datasource then
+type Subscriber interface {
+	// Subscribe returns the channel notified when new tasks of the project may be retrieved, and the func to cancel
+	// the subscription. the notifications are coalesced and may be lost, the subscriber should still poll at a lower
+	// rate
+	Subscribe(projectID uint64) (<-chan struct{}, func())
+}
+
+// Notifier fans out the notifications of new tasks to the subscribers of the project, which could be embedded by a
+// datasource, or fed by any task producer
+type Notifier struct {
+	mux  sync.Mutex
+	subs map[uint64]map[chan struct{}]struct{}
+}
+
+func (n *Notifier) Subscribe(projectID uint64) (<-chan struct{}, func()) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if n.subs[projectID] == nil {
+		n.subs[projectID] = map[chan struct{}]struct{}{}
+	}
+	n.subs[projectID][ch] = struct{}{}
+
+	return ch, func() {
+		n.mux.Lock()
+		defer n.mux.Unlock()
+
+		delete(n.subs[projectID], ch)
+		if len(n.subs[projectID]) == 0 {
+			delete(n.subs, projectID)
+		}
+	}
+}
+
+// Notify wakes the subscribers of the project without blocking, a notification is merged into the pending one
+func (n *Notifier) Notify(projectID uint64) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	for ch := range n.subs[projectID] {
+		notify(ch)
+	}
+}
+
+// NotifyAll wakes the subscribers of every project, e.g. after the notifications may have been lost
+func (n *Notifier) NotifyAll() {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	for _, subs := range n.subs {
+		for ch := range subs {
+			notify(ch)
+		}
+	}
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{
+		subs: map[uint64]map[chan struct{}]struct{}{},
+	}
+}
+
+// notifiedPostgres is the postgres datasource notified by the sequencer with LISTEN/NOTIFY, the listener is started
+// by the first subscription
+type notifiedPostgres struct {
+	*postgres
+	dsn      string
+	once     sync.Once
+	notifier *Notifier
+}
+
+func (p *notifiedPostgres) Subscribe(projectID uint64) (<-chan struct{}, func()) {
+	p.once.Do(func() {
+		go p.listen()
+	})
+	return p.notifier.Subscribe(projectID)
+}
+
+// listen notifies the subscribers of the packed tasks. the listener reconnects by itself, and the subscribers are
+// all woken after reconnected since the notifications sent meanwhile are lost
+func (p *notifiedPostgres) listen() {
+	l := pq.NewListener(p.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("postgres datasource listener event", "event", ev, "error", err)
+		}
+	})
+	defer l.Close()
+
+	if err := l.Listen(TaskPackedChannel); err != nil {
+		slog.Error("failed to listen packed task notification", "error", err)
+		return
+	}
+	for n := range l.Notify {
+		// nil after reconnected
+		if n == nil {
+			p.notifier.NotifyAll()
+			continue
+		}
+		projectID, err := strconv.ParseUint(n.Extra, 10, 64)
+		if err != nil {
+			slog.Error("invalid packed task notification", "payload", n.Extra)
+			continue
+		}
+		p.notifier.Notify(projectID)
+	}
+}
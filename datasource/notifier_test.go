@@ -0,0 +1,121 @@
+package datasource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	r := require.New(t)
+
+	n := NewNotifier()
+	ch1, cancel1 := n.Subscribe(1)
+	ch2, cancel2 := n.Subscribe(2)
+	defer cancel2()
+
+	t.Run("Notify", func(t *testing.T) {
+		n.Notify(1)
+		// coalesced into the pending notification
+		n.Notify(1)
+		r.Len(ch1, 1)
+		r.Len(ch2, 0)
+		<-ch1
+	})
+
+	t.Run("NotifyAll", func(t *testing.T) {
+		n.NotifyAll()
+		r.Len(ch1, 1)
+		r.Len(ch2, 1)
+		<-ch1
+		<-ch2
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		cancel1()
+		n.Notify(1)
+		r.Len(ch1, 0)
+		r.NotContains(n.subs, uint64(1))
+	})
+}
+
+func TestNotifiedPostgres_Subscribe(t *testing.T) {
+	r := require.New(t)
+	p := NewPatches()
+	defer p.Reset()
+
+	d := &notifiedPostgres{notifier: NewNotifier()}
+	listened := make(chan struct{}, 2)
+	p.ApplyPrivateMethod(d, "listen", func(*notifiedPostgres) { listened <- struct{}{} })
+
+	_, cancel1 := d.Subscribe(1)
+	defer cancel1()
+	_, cancel2 := d.Subscribe(2)
+	defer cancel2()
+
+	<-listened
+	time.Sleep(10 * time.Millisecond)
+	r.Len(listened, 0)
+}
+
+func TestNotifiedPostgres_listen(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("FailedToListen", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyFuncReturn(pq.NewListener, &pq.Listener{})
+		p.ApplyMethodReturn(&pq.Listener{}, "Close", nil)
+		p.ApplyMethodReturn(&pq.Listener{}, "Listen", errors.New(t.Name()))
+
+		d := &notifiedPostgres{notifier: NewNotifier()}
+		d.listen()
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		d := &notifiedPostgres{notifier: NewNotifier()}
+		ch1, cancel1 := d.notifier.Subscribe(1)
+		defer cancel1()
+		ch2, cancel2 := d.notifier.Subscribe(2)
+		defer cancel2()
+
+		notify := make(chan *pq.Notification, 3)
+		notify <- &pq.Notification{Extra: "invalid"}
+		notify <- &pq.Notification{Extra: "1"}
+		close(notify)
+		p.ApplyFuncReturn(pq.NewListener, &pq.Listener{Notify: notify})
+		p.ApplyMethodReturn(&pq.Listener{}, "Close", nil)
+		p.ApplyMethodReturn(&pq.Listener{}, "Listen", nil)
+
+		d.listen()
+		r.Len(ch1, 1)
+		r.Len(ch2, 0)
+	})
+
+	t.Run("Reconnected", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		d := &notifiedPostgres{notifier: NewNotifier()}
+		ch, cancel := d.notifier.Subscribe(2)
+		defer cancel()
+
+		notify := make(chan *pq.Notification, 1)
+		notify <- nil
+		close(notify)
+		p.ApplyFuncReturn(pq.NewListener, &pq.Listener{Notify: notify})
+		p.ApplyMethodReturn(&pq.Listener{}, "Close", nil)
+		p.ApplyMethodReturn(&pq.Listener{}, "Listen", nil)
+
+		d.listen()
+		r.Len(ch, 1)
+	})
+}
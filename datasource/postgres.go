@@ -15,20 +15,31 @@ import (
 
 type message struct {
 	gorm.Model
-	MessageID      string `gorm:"index:message_id,not null"`
-	ClientID       string `gorm:"index:message_fetch,not null,default:''"`
-	ProjectID      uint64 `gorm:"index:message_fetch,not null"`
-	ProjectVersion string `gorm:"index:message_fetch,not null,default:'0.0'"`
-	Data           []byte `gorm:"size:4096"`
-	InternalTaskID string `gorm:"index:internal_task_id,not null,default:''"`
+	MessageID       string `gorm:"index:message_id,not null"`
+	ClientID        string `gorm:"index:message_fetch,not null,default:''"`
+	ProjectID       uint64 `gorm:"index:message_fetch,not null"`
+	ProjectVersion  string `gorm:"index:message_fetch,not null,default:'0.0'"`
+	Data            []byte `gorm:"size:4096"`
+	DataHash        string `gorm:"not null;default:''"`
+	InternalTaskID  string `gorm:"index:internal_task_id,not null,default:''"`
+	DeviceSignature datatypes.JSON
+}
+
+// blob is the message data stored out of line by the sequencer, referenced by message.DataHash
+type blob struct {
+	gorm.Model
+	Hash string `gorm:"uniqueIndex:blob_hash,not null"`
+	Data []byte `gorm:"not null"`
 }
 
 type task struct {
 	gorm.Model
 	ProjectID      uint64         `gorm:"index:task_fetch,not null"`
-	InternalTaskID string         `gorm:"index:internal_task_id,not null"`
+	InternalTaskID string         `gorm:"index:task_internal_task_id,not null"`
 	MessageIDs     datatypes.JSON `gorm:"not null"`
 	Signature      string         `gorm:"not null,default:''"`
+	MerkleRoot     string         `gorm:"not null;default:''"`
+	RootSignature  string         `gorm:"not null;default:''"`
 }
 
 type postgres struct {
@@ -56,25 +67,121 @@ func (p *postgres) Retrieve(projectID, nextTaskID uint64) (*tasktype.Task, error
 	if len(ms) == 0 {
 		return nil, errors.Errorf("invalid task, task_id %v", t.ID)
 	}
+	ms, err := orderMessages(messageIDs, ms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid task, task_id %v", t.ID)
+	}
 
-	ds := [][]byte{}
-	for _, m := range ms {
-		ds = append(ds, m.Data)
+	ds, err := p.messageData(ms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to rehydrate task messages, task_id %v", t.ID)
+	}
+
+	ss, err := deviceSignatures(ms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode task device signatures, task_id %v", t.ID)
 	}
 
 	return &tasktype.Task{
-		ID:             uint64(t.ID),
-		ProjectID:      ms[0].ProjectID,
-		ProjectVersion: ms[0].ProjectVersion,
-		Data:           ds,
-		ClientID:       ms[0].ClientID,
-		Signature:      t.Signature,
+		ID:               uint64(t.ID),
+		ProjectID:        ms[0].ProjectID,
+		ProjectVersion:   ms[0].ProjectVersion,
+		Data:             ds,
+		ClientID:         taskClientID(ms),
+		Signature:        t.Signature,
+		DeviceSignatures: ss,
+		MerkleRoot:       t.MerkleRoot,
+		RootSignature:    t.RootSignature,
 	}, nil
 }
 
+// taskClientID returns the client of the messages, or empty if the task packs the messages of several clients, which
+// is the client id signed by the sequencer
+func taskClientID(ms []*message) string {
+	for _, m := range ms[1:] {
+		if m.ClientID != ms[0].ClientID {
+			return ""
+		}
+	}
+	return ms[0].ClientID
+}
+
+// orderMessages orders the messages as the message ids of the task, which is the order signed by the sequencer
+func orderMessages(messageIDs []string, ms []*message) ([]*message, error) {
+	byID := make(map[string]*message, len(ms))
+	for _, m := range ms {
+		byID[m.MessageID] = m
+	}
+	ordered := make([]*message, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		m, ok := byID[id]
+		if !ok {
+			return nil, errors.Errorf("task message not exist, message_id %s", id)
+		}
+		ordered = append(ordered, m)
+	}
+	if len(ordered) == 0 {
+		return nil, errors.New("empty task message ids")
+	}
+	return ordered, nil
+}
+
+// deviceSignatures returns the device signatures of messages in order, nil if none of the messages is signed
+func deviceSignatures(ms []*message) ([]*tasktype.DeviceSignature, error) {
+	var ss []*tasktype.DeviceSignature
+	for i, m := range ms {
+		if len(m.DeviceSignature) == 0 {
+			continue
+		}
+		if ss == nil {
+			ss = make([]*tasktype.DeviceSignature, len(ms))
+		}
+		s := &tasktype.DeviceSignature{}
+		if err := json.Unmarshal(m.DeviceSignature, s); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal device signature, message_id %s", m.MessageID)
+		}
+		ss[i] = s
+	}
+	return ss, nil
+}
+
+// messageData returns the data of messages in order, the data stored out of line is fetched from the blob table
+func (p *postgres) messageData(ms []*message) ([][]byte, error) {
+	hashes := []string{}
+	for _, m := range ms {
+		if m.DataHash != "" {
+			hashes = append(hashes, m.DataHash)
+		}
+	}
+	blobs := map[string][]byte{}
+	if len(hashes) > 0 {
+		bs := []*blob{}
+		if err := p.db.Where("hash IN ?", hashes).Find(&bs).Error; err != nil {
+			return nil, errors.Wrap(err, "failed to query message blobs")
+		}
+		for _, b := range bs {
+			blobs[b.Hash] = b.Data
+		}
+	}
+
+	ds := [][]byte{}
+	for _, m := range ms {
+		if m.DataHash == "" {
+			ds = append(ds, m.Data)
+			continue
+		}
+		d, ok := blobs[m.DataHash]
+		if !ok {
+			return nil, errors.Errorf("message blob not exist, message_id %s", m.MessageID)
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
 type Postgres struct {
 	mux sync.Mutex
-	ps  map[string]*postgres
+	ps  map[string]*notifiedPostgres
 }
 
 func (p *Postgres) New(dsn string) (Datasource, error) {
@@ -98,13 +205,13 @@ func (p *Postgres) New(dsn string) (Datasource, error) {
 	}
 	sqlDB.SetMaxOpenConns(500)
 
-	d = &postgres{db}
+	d = &notifiedPostgres{postgres: &postgres{db}, dsn: dsn, notifier: NewNotifier()}
 	p.ps[dsn] = d
 	return d, nil
 }
 
 func NewPostgres() *Postgres {
 	return &Postgres{
-		ps: map[string]*postgres{},
+		ps: map[string]*notifiedPostgres{},
 	}
 }
@@ -1,16 +1,22 @@
 package datasource
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/binary"
 	"encoding/json"
 	"testing"
 
 	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 
+	tasktype "github.com/machinefi/sprout/task"
 	"github.com/machinefi/sprout/testutil"
+	"github.com/machinefi/sprout/util/merkle"
 )
 
 func TestNewPostgres(t *testing.T) {
@@ -55,6 +61,14 @@ func TestNewPostgres(t *testing.T) {
 	})
 }
 
+// firstTask patches gorm.DB.First to query the task t
+func firstTask(p *Patches, t *task) {
+	p.ApplyMethod(&gorm.DB{}, "First", func(_ *gorm.DB, dst any, _ ...any) *gorm.DB {
+		*dst.(*task) = *t
+		return &gorm.DB{}
+	})
+}
+
 func TestPostgres_Retrieve(t *testing.T) {
 	r := require.New(t)
 
@@ -128,17 +142,183 @@ func TestPostgres_Retrieve(t *testing.T) {
 		r.ErrorContains(err, "invalid task,")
 	})
 
+	t.Run("MessageNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		firstTask(p, &task{MessageIDs: []byte(`["m1", "m2"]`)})
+		testutil.GormDBFind(p, &([]*message{{MessageID: "m1"}}), d.db)
+
+		_, err := d.Retrieve(uint64(1), uint64(1))
+		r.ErrorContains(err, "task message not exist, message_id m2")
+	})
+
+	t.Run("FailedToRehydrate", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		firstTask(p, &task{MessageIDs: []byte(`["m"]`)})
+		testutil.GormDBFind(p, &([]*message{{MessageID: "m", DataHash: "hash"}}), d.db)
+		p.ApplyPrivateMethod(d, "messageData", func(*postgres, []*message) ([][]byte, error) {
+			return nil, errors.New(t.Name())
+		})
+
+		_, err := d.Retrieve(uint64(1), uint64(1))
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("FailedToDecodeDeviceSignatures", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		firstTask(p, &task{MessageIDs: []byte(`["m"]`)})
+		testutil.GormDBFind(p, &([]*message{{MessageID: "m"}}), d.db)
+		p.ApplyFuncReturn(deviceSignatures, nil, errors.New(t.Name()))
+
+		_, err := d.Retrieve(uint64(1), uint64(1))
+		r.ErrorContains(err, t.Name())
+	})
+
 	t.Run("Success", func(t *testing.T) {
 		p := NewPatches()
 		defer p.Reset()
 
 		testutil.GormDBWhere(p, d.db)
-		testutil.GormDBFirst(p, &task{}, d.db)
-		p.ApplyFuncReturn(json.Unmarshal, nil)
-		testutil.GormDBFind(p, &([]*message{{}}), d.db)
+		firstTask(p, &task{MessageIDs: []byte(`["m"]`)})
+		testutil.GormDBFind(p, &([]*message{{MessageID: "m"}}), d.db)
 
 		task, err := d.Retrieve(uint64(1), uint64(1))
 		r.NoError(err)
 		r.NotNil(task)
 	})
+
+	t.Run("SeveralClients", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		firstTask(p, &task{MessageIDs: []byte(`["m1", "m2"]`)})
+		testutil.GormDBFind(p, &([]*message{{MessageID: "m1", ClientID: "a"}, {MessageID: "m2", ClientID: "b"}}), d.db)
+
+		task, err := d.Retrieve(uint64(1), uint64(1))
+		r.NoError(err)
+		r.Empty(task.ClientID)
+	})
+}
+
+func TestPostgres_Retrieve_SignedOrder(t *testing.T) {
+	r := require.New(t)
+
+	d := &postgres{db: &gorm.DB{Statement: &gorm.Statement{}}}
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+
+	// signed by the sequencer in the order of message ids
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	buf := bytes.NewBuffer(nil)
+	r.NoError(binary.Write(buf, binary.BigEndian, uint64(1)))
+	r.NoError(binary.Write(buf, binary.BigEndian, uint64(2)))
+	buf.WriteString("client")
+	buf.Write(crypto.Keccak256Hash(data...).Bytes())
+	sig, err := crypto.Sign(crypto.Keccak256(buf.Bytes()), sk)
+	r.NoError(err)
+
+	p := NewPatches()
+	defer p.Reset()
+
+	testutil.GormDBWhere(p, d.db)
+	firstTask(p, &task{
+		Model:      gorm.Model{ID: 1},
+		ProjectID:  2,
+		MessageIDs: []byte(`["m1", "m2", "m3"]`),
+		Signature:  hexutil.Encode(sig),
+		MerkleRoot: merkle.New(data).Root().Hex(),
+	})
+	// returned by postgres in another order
+	testutil.GormDBFind(p, &([]*message{
+		{MessageID: "m3", ClientID: "client", ProjectID: 2, Data: []byte("c")},
+		{MessageID: "m1", ClientID: "client", ProjectID: 2, Data: []byte("a")},
+		{MessageID: "m2", ClientID: "client", ProjectID: 2, Data: []byte("b")},
+	}), d.db)
+
+	tk, err := d.Retrieve(2, 1)
+	r.NoError(err)
+	r.Equal(data, tk.Data)
+	r.NoError(tk.VerifySignature(crypto.FromECDSAPub(&sk.PublicKey)))
+	r.Equal(tk.MerkleRoot, merkle.New(tk.Data).Root().Hex())
+}
+
+func TestPostgres_messageData(t *testing.T) {
+	r := require.New(t)
+
+	d := &postgres{
+		db: &gorm.DB{
+			Statement: &gorm.Statement{},
+		},
+	}
+	ms := []*message{{Data: []byte("a")}, {DataHash: "hash"}}
+
+	t.Run("Inline", func(t *testing.T) {
+		ds, err := d.messageData(ms[:1])
+		r.NoError(err)
+		r.Equal([][]byte{[]byte("a")}, ds)
+	})
+
+	t.Run("FailedToQueryBlobs", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		p.ApplyMethodReturn(&gorm.DB{}, "Find", &gorm.DB{Error: errors.New(t.Name())})
+
+		_, err := d.messageData(ms)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("BlobNotExist", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		testutil.GormDBFind(p, &([]*blob{}), d.db)
+
+		_, err := d.messageData(ms)
+		r.ErrorContains(err, "message blob not exist")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		testutil.GormDBWhere(p, d.db)
+		testutil.GormDBFind(p, &([]*blob{{Hash: "hash", Data: []byte("b")}}), d.db)
+
+		ds, err := d.messageData(ms)
+		r.NoError(err)
+		r.Equal([][]byte{[]byte("a"), []byte("b")}, ds)
+	})
+}
+
+func TestDeviceSignatures(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Unsigned", func(t *testing.T) {
+		ss, err := deviceSignatures([]*message{{}, {}})
+		r.NoError(err)
+		r.Nil(ss)
+	})
+
+	t.Run("FailedToUnmarshal", func(t *testing.T) {
+		_, err := deviceSignatures([]*message{{DeviceSignature: []byte("{")}})
+		r.ErrorContains(err, "failed to unmarshal device signature")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		ss, err := deviceSignatures([]*message{{}, {DeviceSignature: []byte(`{"keyID":"key"}`)}})
+		r.NoError(err)
+		r.Equal([]*tasktype.DeviceSignature{nil, {KeyID: "key"}}, ss)
+	})
 }
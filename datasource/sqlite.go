@@ -0,0 +1,52 @@
+package datasource
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLiteScheme is the uri prefix of the embedded sqlite database of the sequencer, e.g. sqlite:./sequencer.db
+const SQLiteScheme = "sqlite:"
+
+// SQLite reads the embedded sqlite database of the sequencer, which has the same tables as postgres
+type SQLite struct {
+	mux sync.Mutex
+	ds  map[string]*postgres
+}
+
+func (s *SQLite) New(dsn string) (Datasource, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	d, ok := s.ds[dsn]
+	if ok {
+		return d, nil
+	}
+
+	// wait for the writing sequencer instead of failing with database locked
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	db, err := gorm.Open(sqlite.Open(dsn+sep+"_busy_timeout=5000"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open sqlite, dsn %s", dsn)
+	}
+
+	d = &postgres{db}
+	s.ds[dsn] = d
+	return d, nil
+}
+
+func NewSQLite() *SQLite {
+	return &SQLite{
+		ds: map[string]*postgres{},
+	}
+}
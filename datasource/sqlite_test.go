@@ -0,0 +1,57 @@
+package datasource
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatasources_New(t *testing.T) {
+	r := require.New(t)
+
+	ds := NewDatasources()
+	uri := SQLiteScheme + filepath.Join(t.TempDir(), "sequencer.db")
+
+	d, err := ds.New(uri)
+	r.NoError(err)
+	r.IsType(&postgres{}, d)
+	// the dispatcher polls the embedded sqlite database
+	_, ok := d.(Subscriber)
+	r.False(ok)
+
+	// the datasources of the same uri share the connections
+	d2, err := ds.New(uri)
+	r.NoError(err)
+	r.Same(d, d2)
+}
+
+func TestSQLite_Retrieve(t *testing.T) {
+	r := require.New(t)
+
+	d, err := NewSQLite().New(filepath.Join(t.TempDir(), "sequencer.db"))
+	r.NoError(err)
+	db := d.(*postgres).db
+	r.NoError(db.AutoMigrate(&message{}, &task{}, &blob{}))
+
+	ids, err := json.Marshal([]string{"m2", "m1"})
+	r.NoError(err)
+	r.NoError(db.Create(&[]*message{
+		{MessageID: "m1", ClientID: "client", ProjectID: 1, ProjectVersion: "0.1", Data: []byte("1"), InternalTaskID: "t"},
+		{MessageID: "m2", ClientID: "client", ProjectID: 1, ProjectVersion: "0.1", DataHash: "hash", InternalTaskID: "t"},
+	}).Error)
+	r.NoError(db.Create(&blob{Hash: "hash", Data: []byte("2")}).Error)
+	r.NoError(db.Create(&task{ProjectID: 1, InternalTaskID: "t", MessageIDs: ids, Signature: "sig"}).Error)
+
+	tk, err := d.Retrieve(1, 1)
+	r.NoError(err)
+	r.Equal(uint64(1), tk.ID)
+	r.Equal("client", tk.ClientID)
+	r.Equal("sig", tk.Signature)
+	r.Equal([][]byte{[]byte("2"), []byte("1")}, tk.Data)
+
+	tk, err = d.Retrieve(1, 2)
+	r.NoError(err)
+	r.Nil(tk)
+}
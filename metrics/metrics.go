@@ -45,6 +45,31 @@ var (
 		Name: "task_final_state_num_metrics",
 		Help: "task final state num metrics.",
 	}, []string{"projectID", "projectVersion", "state"})
+	budgetGasSpentMtc = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "budget_gas_spent_metrics",
+		Help: "gas spent by project output in current budget window.",
+	}, []string{"projectID"})
+	budgetFeeSpentMtc = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "budget_fee_spent_metrics",
+		Help: "fee in wei paid by project output in current budget window.",
+	}, []string{"projectID"})
+	budgetGasRemainingMtc = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "budget_gas_remaining_metrics",
+		Help: "remaining gas budget of project in current budget window.",
+	}, []string{"projectID"})
+	budgetFeeRemainingMtc = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "budget_fee_remaining_metrics",
+		Help: "remaining fee budget in wei of project in current budget window.",
+	}, []string{"projectID"})
+	budgetPausedMtc = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "budget_paused_metrics",
+		Help: "whether project output is paused by budget, 1 means paused.",
+	}, []string{"projectID"})
+	sequencerMessageNumMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sequencer_message_num_metrics",
+			Help: "messages accepted or rejected by sequencer rate limits, by client.",
+		}, []string{"clientID", "projectID", "result"})
 )
 
 func init() {
@@ -56,6 +81,12 @@ func init() {
 	prometheus.MustRegister(succeedTaskNumMtc)
 	prometheus.MustRegister(taskFinalStateNumMtc)
 	prometheus.MustRegister(taskRuntimeMtc)
+	prometheus.MustRegister(budgetGasSpentMtc)
+	prometheus.MustRegister(budgetFeeSpentMtc)
+	prometheus.MustRegister(budgetGasRemainingMtc)
+	prometheus.MustRegister(budgetFeeRemainingMtc)
+	prometheus.MustRegister(budgetPausedMtc)
+	prometheus.MustRegister(sequencerMessageNumMtc)
 }
 
 func DispatchedTaskNumMtc(projectID uint64, projectVersion string) {
@@ -87,3 +118,33 @@ func SucceedTaskNumMtc(projectID uint64, projectVersion string) {
 func TaskFinalStateNumMtc(projectID uint64, projectVersion, state string) {
 	taskFinalStateNumMtc.WithLabelValues(strconv.FormatUint(projectID, 10), projectVersion, state).Inc()
 }
+
+func BudgetSpentMtc(projectID uint64, gas, fee float64) {
+	budgetGasSpentMtc.WithLabelValues(strconv.FormatUint(projectID, 10)).Set(gas)
+	budgetFeeSpentMtc.WithLabelValues(strconv.FormatUint(projectID, 10)).Set(fee)
+}
+
+func BudgetGasRemainingMtc(projectID uint64, remaining float64) {
+	budgetGasRemainingMtc.WithLabelValues(strconv.FormatUint(projectID, 10)).Set(remaining)
+}
+
+func BudgetFeeRemainingMtc(projectID uint64, remaining float64) {
+	budgetFeeRemainingMtc.WithLabelValues(strconv.FormatUint(projectID, 10)).Set(remaining)
+}
+
+func BudgetPausedMtc(projectID uint64, paused bool) {
+	v := 0.0
+	if paused {
+		v = 1
+	}
+	budgetPausedMtc.WithLabelValues(strconv.FormatUint(projectID, 10)).Set(v)
+}
+
+func AcceptedMessageNumMtc(clientID string, projectID uint64, n int) {
+	sequencerMessageNumMtc.WithLabelValues(clientID, strconv.FormatUint(projectID, 10), "accepted").Add(float64(n))
+}
+
+// RejectedMessageNumMtc counts the rejected messages, the reason is the result label
+func RejectedMessageNumMtc(clientID string, projectID uint64, reason string, n int) {
+	sequencerMessageNumMtc.WithLabelValues(clientID, strconv.FormatUint(projectID, 10), reason).Add(float64(n))
+}
@@ -1,20 +1,26 @@
 package output
 
 import (
+	"bytes"
 	"context"
-	"crypto/ecdsa"
+	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
 
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
@@ -23,17 +29,107 @@ var (
 	errSnarkProofDataMissingFieldPostStateDigest = errors.New("missing field `Snark.post_state_digest`")
 	errSnarkProofDataMissingFieldJournal         = errors.New("missing field `Snark.journal`")
 	errMissingReceiverParam                      = errors.New("missing receiver param")
+
+	// panicSelector is the selector of the builtin `Panic(uint256)` error raised by solidity assertions
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// revertError is returned when the pre-flight simulation of a transaction reverts
+type revertError struct {
+	reason string
+}
+
+func (e *revertError) Error() string {
+	return "execution reverted: " + e.reason
+}
+
+const (
+	defaultReceiptTimeout        = 2 * time.Minute
+	defaultPendingReceiptTimeout = time.Hour
+	defaultReceiptPollInterval   = time.Second
 )
 
 type ethereumContract struct {
-	client            *ethclient.Client
-	contractAddress   common.Address
-	receiverAddress   string
-	secretKey         *ecdsa.PrivateKey
-	signer            ethtypes.Signer
-	contractABI       abi.ABI
-	contractMethod    abi.Method
-	contractWhitelist []string
+	client                *ethclient.Client
+	contractAddress       common.Address
+	receiverAddress       string
+	operator              signer.ECDSA
+	txSigner              ethtypes.Signer
+	contractABI           abi.ABI
+	contractMethod        abi.Method
+	contractWhitelist     []string
+	dryRun                bool
+	receiptTimeout        time.Duration
+	pendingReceiptTimeout time.Duration
+	pollInterval          time.Duration
+}
+
+// OutputWithSpend outputs and waits the transaction receipt to get the spend, nothing is spent in dry run mode. a
+// transaction not mined in time is still output with the tx hash, since it may be mined later, and its receipt is
+// polled in background to report the spend
+func (e *ethereumContract) OutputWithSpend(task *task.Task, proof []byte, spent func(*Spend)) (string, error) {
+	txHash, err := e.Output(task, proof)
+	if err != nil || e.dryRun {
+		return txHash, err
+	}
+	hash := common.HexToHash(txHash)
+	spend, err := e.waitReceipt(context.Background(), hash, e.receiptTimeout)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("transaction sent but not mined in time", "tx_hash", txHash, "task_id", task.ID, "timeout", e.receiptTimeout)
+		go e.waitPendingReceipt(hash, spent)
+		return txHash, nil
+	}
+	if spend != nil {
+		spent(spend)
+	}
+	return txHash, err
+}
+
+// waitPendingReceipt keeps polling the receipt of the transaction not mined in time, and reports the spend once mined
+func (e *ethereumContract) waitPendingReceipt(txHash common.Hash, spent func(*Spend)) {
+	spend, err := e.waitReceipt(context.Background(), txHash, e.pendingReceiptTimeout)
+	if err != nil {
+		slog.Error("failed to wait pending transaction receipt", "tx_hash", txHash, "error", err)
+	}
+	if spend != nil {
+		spent(spend)
+	}
+}
+
+// waitReceipt polls the receipt of the transaction until mined, the fee is gas used multiplied by effective gas price
+func (e *ethereumContract) waitReceipt(ctx context.Context, txHash common.Hash, timeout time.Duration) (*Spend, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		receipt, err := e.client.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			gasPrice := receipt.EffectiveGasPrice
+			if gasPrice == nil {
+				tx, _, err := e.client.TransactionByHash(ctx, txHash)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get transaction %s", txHash)
+				}
+				gasPrice = tx.GasPrice()
+			}
+			spend := &Spend{
+				GasUsed: receipt.GasUsed,
+				Fee:     new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), gasPrice),
+			}
+			if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+				return spend, errors.Errorf("transaction %s reverted", txHash)
+			}
+			return spend, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, errors.Wrapf(err, "failed to get transaction receipt %s", txHash)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "failed to wait transaction receipt %s", txHash)
+		case <-time.After(e.pollInterval):
+		}
+	}
 }
 
 func (e *ethereumContract) Output(task *task.Task, proof []byte) (string, error) {
@@ -125,7 +221,7 @@ func (e *ethereumContract) Output(task *task.Task, proof []byte) (string, error)
 }
 
 func (e *ethereumContract) sendTX(ctx context.Context, data []byte) (string, error) {
-	sender := crypto.PubkeyToAddress(e.secretKey.PublicKey)
+	sender := e.operator.Address()
 	gasPrice, err := e.client.SuggestGasPrice(ctx)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get suggest gas price")
@@ -136,6 +232,13 @@ func (e *ethereumContract) sendTX(ctx context.Context, data []byte) (string, err
 		GasPrice: gasPrice,
 		Data:     data,
 	}
+	ret, err := e.simulate(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+	if e.dryRun {
+		return DryRunPrefix + hexutil.Encode(ret), nil
+	}
 	gasLimit, err := e.client.EstimateGas(ctx, msg)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to estimate gas")
@@ -153,7 +256,11 @@ func (e *ethereumContract) sendTX(ctx context.Context, data []byte) (string, err
 			To:       &e.contractAddress,
 			Data:     data,
 		})
-	signedTx, err := ethtypes.SignTx(tx, e.signer, e.secretKey)
+	sig, err := e.operator.SignHash(e.txSigner.Hash(tx).Bytes())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign tx")
+	}
+	signedTx, err := tx.WithSignature(e.txSigner, sig)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to sign tx")
 	}
@@ -165,6 +272,48 @@ func (e *ethereumContract) sendTX(ctx context.Context, data []byte) (string, err
 	return signedTx.Hash().Hex(), nil
 }
 
+// simulate executes the transaction with eth_call against the latest block, a revert is decoded by the contract abi
+func (e *ethereumContract) simulate(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	ret, err := e.client.CallContract(ctx, msg, nil)
+	if err == nil {
+		return ret, nil
+	}
+	var de rpc.DataError
+	if !errors.As(err, &de) {
+		return nil, errors.Wrap(err, "failed to simulate transaction")
+	}
+	data, ok := de.ErrorData().(string)
+	if !ok {
+		return nil, errors.Wrap(err, "failed to simulate transaction")
+	}
+	return nil, &revertError{reason: e.unpackRevert(common.FromHex(data))}
+}
+
+// unpackRevert decodes revert data as `Error(string)`, `Panic(uint256)` or a custom error declared in the
+// contract abi, the raw data is returned in hex if none of them matched
+func (e *ethereumContract) unpackRevert(data []byte) string {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+	if len(data) < 4 {
+		return hexutil.Encode(data)
+	}
+	if bytes.Equal(data[:4], panicSelector) && len(data) == 36 {
+		return fmt.Sprintf("panic: 0x%x", new(big.Int).SetBytes(data[4:]))
+	}
+	for _, abiErr := range e.contractABI.Errors {
+		if !bytes.Equal(abiErr.ID[:4], data[:4]) {
+			continue
+		}
+		values, err := abiErr.Unpack(data)
+		if err != nil {
+			break
+		}
+		return fmt.Sprintf("%s%v", abiErr.Name, values)
+	}
+	return hexutil.Encode(data)
+}
+
 func (e *ethereumContract) isWhitelist() bool {
 	for _, address := range e.contractWhitelist {
 		if strings.ToLower(e.contractAddress.String()) == strings.ToLower(address) {
@@ -173,9 +322,9 @@ func (e *ethereumContract) isWhitelist() bool {
 	}
 	return false
 }
-func newEthereum(conf EthereumConfig, secretKey string, contractWhitelist string) (*ethereumContract, error) {
-	if secretKey == "" {
-		return nil, errors.New("secret key is empty")
+func newEthereum(conf EthereumConfig, operator signer.ECDSA, contractWhitelist string) (*ethereumContract, error) {
+	if operator == nil {
+		return nil, errors.New("operator signer is empty")
 	}
 	contractABI, err := abi.JSON(strings.NewReader(conf.ContractAbiJSON))
 	if err != nil {
@@ -194,13 +343,17 @@ func newEthereum(conf EthereumConfig, secretKey string, contractWhitelist string
 		return nil, errors.Wrap(err, "failed to get chain id")
 	}
 	return &ethereumContract{
-		client:            client,
-		secretKey:         crypto.ToECDSAUnsafe(common.FromHex(secretKey)),
-		signer:            ethtypes.NewLondonSigner(chainID),
-		contractAddress:   common.HexToAddress(conf.ContractAddress),
-		receiverAddress:   conf.ReceiverAddress,
-		contractABI:       contractABI,
-		contractMethod:    method,
-		contractWhitelist: strings.Split(contractWhitelist, ","),
+		client:                client,
+		operator:              operator,
+		txSigner:              ethtypes.NewLondonSigner(chainID),
+		contractAddress:       common.HexToAddress(conf.ContractAddress),
+		receiverAddress:       conf.ReceiverAddress,
+		contractABI:           contractABI,
+		contractMethod:        method,
+		contractWhitelist:     strings.Split(contractWhitelist, ","),
+		dryRun:                conf.DryRun,
+		receiptTimeout:        defaultReceiptTimeout,
+		pendingReceiptTimeout: defaultPendingReceiptTimeout,
+		pollInterval:          defaultReceiptPollInterval,
 	}, nil
 }
@@ -2,16 +2,19 @@ package output
 
 import (
 	"context"
-	"crypto/ecdsa"
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -38,6 +41,8 @@ var (
 	//go:embed testdata/testABI_otherInputOnlyMethod_uint256.json
 	testABIOtherInputOnlyMethod_uint256 string
 
+	testOperatorECDSA = &mockECDSA{}
+
 	conf = &Config{
 		Type: EthereumContract,
 		Ethereum: EthereumConfig{
@@ -46,6 +51,27 @@ var (
 	}
 )
 
+type testDataError struct {
+	data string
+}
+
+func (e *testDataError) Error() string { return "execution reverted" }
+
+func (e *testDataError) ErrorData() interface{} { return e.data }
+
+type mockECDSA struct {
+	err error
+}
+
+func (s *mockECDSA) Address() common.Address { return common.Address{} }
+
+func (s *mockECDSA) SignHash(hash []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return make([]byte, crypto.SignatureLength), nil
+}
+
 func patchEthereumContractSendTX(p *Patches, txhash string, err error) *Patches {
 	return p.ApplyPrivateMethod(&ethereumContract{}, "sendTX",
 		func(contract *ethereumContract, ctx context.Context, data []byte) (string, error) {
@@ -68,7 +94,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
 
 			conf.Ethereum.ContractAbiJSON = testABIProofInputOnlyMethod
-			o, err := New(conf, "1", "", "")
+			o, err := New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 			_, ok := o.(*ethereumContract)
 			r.True(ok)
@@ -87,7 +113,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
 
 			conf.Ethereum.ContractAbiJSON = testABIProjectInputOnlyMethod
-			o, err := New(conf, "1", "", "")
+			o, err := New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 			_, ok := o.(*ethereumContract)
 			r.True(ok)
@@ -106,7 +132,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
 
 			conf.Ethereum.ContractAbiJSON = testABIReceiverInputOnlyMethod
-			o, err := New(conf, "1", "", "")
+			o, err := New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 			_, ok := o.(*ethereumContract)
 			r.True(ok)
@@ -118,7 +144,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			})
 
 			conf.Ethereum.ReceiverAddress = "0x"
-			o, err = New(conf, "1", "", "")
+			o, err = New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 			_, ok = o.(*ethereumContract)
 			r.True(ok)
@@ -139,7 +165,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
 
 			conf.Ethereum.ContractAbiJSON = testABIDataSnarkInputOnlyMethod
-			o, err := New(conf, "1", "", "")
+			o, err := New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 			_, ok := o.(*ethereumContract)
 			r.True(ok)
@@ -174,7 +200,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 
 			t.Run("MissingMethodNameParam", func(t *testing.T) {
 				conf.Ethereum.ContractAbiJSON = testABIOtherInputOnlyMethod
-				o, err := New(conf, "1", "", "")
+				o, err := New(conf, testOperatorECDSA, nil, "")
 				r.NoError(err)
 				_, ok := o.(*ethereumContract)
 				r.True(ok)
@@ -190,7 +216,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			t.Run("BuildParamsByType", func(t *testing.T) {
 				t.Run("Address", func(t *testing.T) {
 					conf.Ethereum.ContractAbiJSON = testABIOtherInputOnlyMethod_address
-					o, err := New(conf, "1", "", "")
+					o, err := New(conf, testOperatorECDSA, nil, "")
 					r.NoError(err)
 					_, ok := o.(*ethereumContract)
 					r.True(ok)
@@ -204,7 +230,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 				})
 				t.Run("Uint256", func(t *testing.T) {
 					conf.Ethereum.ContractAbiJSON = testABIOtherInputOnlyMethod_uint256
-					o, err := New(conf, "1", "", "")
+					o, err := New(conf, testOperatorECDSA, nil, "")
 					r.NoError(err)
 					_, ok := o.(*ethereumContract)
 					r.True(ok)
@@ -218,7 +244,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 				})
 				t.Run("Other", func(t *testing.T) {
 					conf.Ethereum.ContractAbiJSON = testABIOtherInputOnlyMethod
-					o, err := New(conf, "1", "", "")
+					o, err := New(conf, testOperatorECDSA, nil, "")
 					r.NoError(err)
 					_, ok := o.(*ethereumContract)
 					r.True(ok)
@@ -244,7 +270,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			p.ApplyMethodReturn(abi.ABI{}, "Pack", nil, errors.New(t.Name()))
 
 			conf.Ethereum.ContractAbiJSON = testABIProofInputOnlyMethod
-			o, err := New(conf, "1", "", "")
+			o, err := New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 
 			txHash, err := o.Output(&task.Task{
@@ -267,7 +293,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 			p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
 			p.ApplyMethodReturn(abi.ABI{}, "Pack", nil, errors.New(t.Name()))
 
-			o, err := New(conf, "1", "", "")
+			o, err := New(conf, testOperatorECDSA, nil, "")
 			r.NoError(err)
 
 			txHash, err := o.Output(&task.Task{
@@ -289,7 +315,7 @@ func Test_ethereumContract_Output(t *testing.T) {
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
 		p.ApplyMethodReturn(abi.ABI{}, "Pack", nil, nil)
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, testOperatorECDSA, nil, "")
 		r.NoError(err)
 
 		txHash, err := o.Output(&task.Task{
@@ -313,12 +339,11 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 
 		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", nil, nil)
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
 		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
 		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
 		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", nil, errors.New(t.Name()))
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, testOperatorECDSA, nil, "")
 		r.NoError(err)
 		contract, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -331,15 +356,15 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
 		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
 		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
 		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", []byte{}, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "EstimateGas", uint64(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "PendingNonceAt", nil, errors.New(t.Name()))
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, testOperatorECDSA, nil, "")
 		r.NoError(err)
 		contract, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -352,15 +377,15 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
 		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
 		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
 		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", []byte{}, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "PendingNonceAt", uint64(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "EstimateGas", nil, errors.New(t.Name()))
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, testOperatorECDSA, nil, "")
 		r.NoError(err)
 		contract, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -368,21 +393,90 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 		r.ErrorContains(err, t.Name())
 	})
 
+	t.Run("SimulateFailed", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
+		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
+		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
+		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", nil, errors.New(t.Name()))
+
+		o, err := New(conf, testOperatorECDSA, nil, "")
+		r.NoError(err)
+		contract, ok := o.(*ethereumContract)
+		r.True(ok)
+		_, err = contract.sendTX(ctx, nil)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("SimulateReverted", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		data, err := abi.Arguments{{Type: abi.Type{T: abi.StringTy}}}.Pack(t.Name())
+		r.NoError(err)
+		data = append(crypto.Keccak256([]byte("Error(string)"))[:4], data...)
+
+		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
+		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
+		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
+		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", nil, &testDataError{data: hexutil.Encode(data)})
+
+		o, err := New(conf, testOperatorECDSA, nil, "")
+		r.NoError(err)
+		contract, ok := o.(*ethereumContract)
+		r.True(ok)
+		_, err = contract.sendTX(ctx, nil)
+		r.Equal(err.Error(), "execution reverted: "+t.Name())
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
+		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
+		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
+		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", []byte{1}, nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "EstimateGas", nil, errors.New(t.Name()))
+
+		o, err := New(&Config{
+			Type: EthereumContract,
+			Ethereum: EthereumConfig{
+				ContractMethod:  testMethodName,
+				ContractAbiJSON: testABIOtherInputOnlyMethod,
+				DryRun:          true,
+			},
+		}, testOperatorECDSA, nil, "")
+		r.NoError(err)
+		contract, ok := o.(*ethereumContract)
+		r.True(ok)
+		ret, err := contract.sendTX(ctx, nil)
+		r.NoError(err)
+		r.Equal(ret, DryRunPrefix+"0x01")
+	})
+
 	t.Run("SignTxFailed", func(t *testing.T) {
 		p := NewPatches()
 		defer p.Reset()
 
 		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
 		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
 		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
 		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", []byte{}, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "PendingNonceAt", uint64(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "EstimateGas", uint64(1), nil)
-		p.ApplyFuncReturn(ethtypes.SignTx, nil, errors.New(t.Name()))
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, &mockECDSA{err: errors.New(t.Name())}, nil, "")
 		r.NoError(err)
 		contract, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -395,17 +489,16 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
 		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
 		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
 		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", []byte{}, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "PendingNonceAt", uint64(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "EstimateGas", uint64(1), nil)
-		p.ApplyFuncReturn(ethtypes.SignTx, nil, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "SendTransaction", errors.New(t.Name()))
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, testOperatorECDSA, nil, "")
 		r.NoError(err)
 		contract, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -418,18 +511,17 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 		defer p.Reset()
 
 		p.ApplyFuncReturn(ethclient.Dial, &ethclient.Client{}, nil)
-		p.ApplyFuncReturn(crypto.ToECDSAUnsafe, &ecdsa.PrivateKey{})
 		p.ApplyFuncReturn(crypto.PubkeyToAddress, common.Address{})
 		p.ApplyFuncReturn(common.HexToAddress, common.Address{})
 		p.ApplyMethodReturn(&ethclient.Client{}, "SuggestGasPrice", big.NewInt(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "ChainID", big.NewInt(1), nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "CallContract", []byte{}, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "PendingNonceAt", uint64(1), nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "EstimateGas", uint64(1), nil)
-		p.ApplyFuncReturn(ethtypes.SignTx, nil, nil)
 		p.ApplyMethodReturn(&ethclient.Client{}, "SendTransaction", nil)
 		p.ApplyMethodReturn(&ethtypes.Transaction{}, "Hash", common.Hash{})
 
-		o, err := New(conf, "1", "", "")
+		o, err := New(conf, testOperatorECDSA, nil, "")
 		r.NoError(err)
 		contract, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -438,3 +530,147 @@ func Test_ethereumContract_sendTX(t *testing.T) {
 		r.Equal(tx, "0x0000000000000000000000000000000000000000000000000000000000000000")
 	})
 }
+
+func Test_ethereumContract_unpackRevert(t *testing.T) {
+	r := require.New(t)
+
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"error","name":"InvalidProof","inputs":[{"name":"code","type":"uint256"}]}]`))
+	r.NoError(err)
+	e := &ethereumContract{contractABI: contractABI}
+
+	t.Run("ErrorString", func(t *testing.T) {
+		data, err := abi.Arguments{{Type: abi.Type{T: abi.StringTy}}}.Pack("invalid proof")
+		r.NoError(err)
+		r.Equal("invalid proof", e.unpackRevert(append(crypto.Keccak256([]byte("Error(string)"))[:4], data...)))
+	})
+	t.Run("Panic", func(t *testing.T) {
+		data := append(crypto.Keccak256([]byte("Panic(uint256)"))[:4], common.LeftPadBytes([]byte{0x11}, 32)...)
+		r.Equal("panic: 0x11", e.unpackRevert(data))
+	})
+	t.Run("CustomError", func(t *testing.T) {
+		abiErr := contractABI.Errors["InvalidProof"]
+		data, err := abiErr.Inputs.Pack(big.NewInt(7))
+		r.NoError(err)
+		r.Equal("InvalidProof[7]", e.unpackRevert(append(abiErr.ID[:4], data...)))
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		r.Equal("0x01020304", e.unpackRevert([]byte{1, 2, 3, 4}))
+	})
+}
+
+func Test_ethereumContract_OutputWithSpend(t *testing.T) {
+	r := require.New(t)
+
+	e := &ethereumContract{
+		client:                &ethclient.Client{},
+		receiptTimeout:        10 * time.Millisecond,
+		pendingReceiptTimeout: time.Second,
+		pollInterval:          time.Millisecond,
+	}
+	txHash := common.HexToHash("0x01").Hex()
+
+	t.Run("FailedToOutput", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(e, "Output", "", errors.New(t.Name()))
+
+		_, err := e.OutputWithSpend(&task.Task{}, nil, func(*Spend) { r.Fail("nothing spent") })
+		r.ErrorContains(err, t.Name())
+	})
+	t.Run("Mined", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(e, "Output", txHash, nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "TransactionReceipt", &ethtypes.Receipt{
+			Status:            ethtypes.ReceiptStatusSuccessful,
+			GasUsed:           2,
+			EffectiveGasPrice: big.NewInt(3),
+		}, nil)
+
+		spent := []*Spend{}
+		res, err := e.OutputWithSpend(&task.Task{}, nil, func(s *Spend) { spent = append(spent, s) })
+		r.NoError(err)
+		r.Equal(txHash, res)
+		r.Equal([]*Spend{{GasUsed: 2, Fee: big.NewInt(6)}}, spent)
+	})
+	t.Run("MinedAfterTimeout", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		mined := time.Now().Add(50 * time.Millisecond)
+		p.ApplyMethodReturn(e, "Output", txHash, nil)
+		p.ApplyMethodFunc(&ethclient.Client{}, "TransactionReceipt", func(context.Context, common.Hash) (*ethtypes.Receipt, error) {
+			if time.Now().Before(mined) {
+				return nil, ethereum.NotFound
+			}
+			return &ethtypes.Receipt{Status: ethtypes.ReceiptStatusSuccessful, GasUsed: 2, EffectiveGasPrice: big.NewInt(3)}, nil
+		})
+
+		spent := make(chan *Spend, 1)
+		res, err := e.OutputWithSpend(&task.Task{}, nil, func(s *Spend) { spent <- s })
+		r.NoError(err)
+		r.Equal(txHash, res)
+		r.Equal(&Spend{GasUsed: 2, Fee: big.NewInt(6)}, <-spent)
+	})
+}
+
+func Test_ethereumContract_waitReceipt(t *testing.T) {
+	r := require.New(t)
+
+	e := &ethereumContract{
+		client:         &ethclient.Client{},
+		receiptTimeout: time.Second,
+		pollInterval:   time.Millisecond,
+	}
+	ctx := context.Background()
+
+	t.Run("FailedToGetReceipt", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ethclient.Client{}, "TransactionReceipt", nil, errors.New(t.Name()))
+
+		_, err := e.waitReceipt(ctx, common.Hash{}, e.receiptTimeout)
+		r.ErrorContains(err, t.Name())
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ethclient.Client{}, "TransactionReceipt", nil, ethereum.NotFound)
+
+		_, err := e.waitReceipt(ctx, common.Hash{}, e.receiptTimeout)
+		r.ErrorIs(err, context.DeadlineExceeded)
+	})
+	t.Run("Reverted", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ethclient.Client{}, "TransactionReceipt", &ethtypes.Receipt{
+			Status:            ethtypes.ReceiptStatusFailed,
+			GasUsed:           2,
+			EffectiveGasPrice: big.NewInt(3),
+		}, nil)
+
+		spend, err := e.waitReceipt(ctx, common.Hash{}, e.receiptTimeout)
+		r.ErrorContains(err, "reverted")
+		r.Equal(&Spend{GasUsed: 2, Fee: big.NewInt(6)}, spend)
+	})
+	t.Run("LegacyGasPrice", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ethclient.Client{}, "TransactionReceipt", &ethtypes.Receipt{
+			Status:  ethtypes.ReceiptStatusSuccessful,
+			GasUsed: 2,
+		}, nil)
+		p.ApplyMethodReturn(&ethclient.Client{}, "TransactionByHash",
+			ethtypes.NewTx(&ethtypes.LegacyTx{GasPrice: big.NewInt(5)}), false, nil)
+
+		spend, err := e.waitReceipt(ctx, common.Hash{}, e.receiptTimeout)
+		r.NoError(err)
+		r.Equal(&Spend{GasUsed: 2, Fee: big.NewInt(10)}, spend)
+	})
+}
@@ -0,0 +1,94 @@
+package output
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/signer"
+	"github.com/machinefi/sprout/task"
+	"github.com/machinefi/sprout/util/ipfs"
+)
+
+// ipfsEnvelope is the canonical content stored to ipfs, fields are marshaled in declaration order
+type ipfsEnvelope struct {
+	TaskID          uint64        `json:"taskID"`
+	ProjectID       uint64        `json:"projectID"`
+	ProjectVersion  string        `json:"projectVersion"`
+	ClientID        string        `json:"clientID"`
+	DataHash        string        `json:"dataHash"`
+	Proof           hexutil.Bytes `json:"proof"`
+	ProverSignature string        `json:"proverSignature,omitempty"`
+}
+
+type ipfsChainedResult struct {
+	CID  string `json:"cid"`
+	Next string `json:"next"`
+}
+
+type ipfsStorage struct {
+	endpoint string
+	sh       *ipfs.IPFS
+	next     Output // optional, receives the cid as proof
+}
+
+func (s *ipfsStorage) Output(task *task.Task, proof []byte) (string, error) {
+	return s.OutputWithProverSignature(task, proof, "", nil)
+}
+
+func (s *ipfsStorage) OutputWithProverSignature(task *task.Task, proof []byte, proverSignature string, spent func(*Spend)) (string, error) {
+	slog.Debug("outputing to ipfs", "endpoint", s.endpoint)
+	content, err := json.Marshal(&ipfsEnvelope{
+		TaskID:          task.ID,
+		ProjectID:       task.ProjectID,
+		ProjectVersion:  task.ProjectVersion,
+		ClientID:        task.ClientID,
+		DataHash:        crypto.Keccak256Hash(task.Data...).Hex(),
+		Proof:           proof,
+		ProverSignature: proverSignature,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal ipfs envelope")
+	}
+	cid, err := s.sh.AddContent(content)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to add content to ipfs")
+	}
+	if s.next == nil {
+		return cid, nil
+	}
+
+	res, err := outputWithSpend(s.next, task, []byte(cid), spent)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to output cid %s to next output", cid)
+	}
+	data, err := json.Marshal(&ipfsChainedResult{CID: cid, Next: res})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal chained result")
+	}
+	return string(data), nil
+}
+
+func newIPFSStorage(conf IPFSConfig, operatorECDSA signer.ECDSA, operatorED25519 signer.ED25519, contractWhitelist string) (*ipfsStorage, error) {
+	if conf.Endpoint == "" {
+		return nil, errors.New("ipfs endpoint is empty")
+	}
+	s := &ipfsStorage{
+		endpoint: conf.Endpoint,
+		sh:       ipfs.NewIPFS(conf.Endpoint),
+	}
+	if conf.Next != nil {
+		if conf.Next.Type == IPFS {
+			return nil, errors.New("ipfs output cannot chain into another ipfs output")
+		}
+		next, err := New(conf.Next, operatorECDSA, operatorED25519, contractWhitelist)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to new next output")
+		}
+		s.next = next
+	}
+	return s, nil
+}
@@ -0,0 +1,131 @@
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/agiledragon/gomonkey/v2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/machinefi/sprout/task"
+	"github.com/machinefi/sprout/util/ipfs"
+)
+
+type mockSpendOutput struct {
+	spend *Spend
+}
+
+func (m *mockSpendOutput) Output(task *task.Task, proof []byte) (string, error) {
+	return "", errors.New("spend not reported")
+}
+
+func (m *mockSpendOutput) OutputWithSpend(task *task.Task, proof []byte, spent func(*Spend)) (string, error) {
+	spent(m.spend)
+	return "tx", nil
+}
+
+func Test_ipfsStorage_Output(t *testing.T) {
+	r := require.New(t)
+
+	tsk := &task.Task{ID: 1, ProjectID: 2, ProjectVersion: "0.1", Data: [][]byte{[]byte("any")}}
+
+	t.Run("FailedToAddContent", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ipfs.IPFS{}, "AddContent", "", errors.New(t.Name()))
+
+		o, err := New(&Config{Type: IPFS, IPFS: IPFSConfig{Endpoint: "any"}}, nil, nil, "")
+		r.NoError(err)
+		_, err = o.Output(tsk, []byte("proof"))
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		var content []byte
+		p.ApplyMethodFunc(&ipfs.IPFS{}, "AddContent", func(c []byte) (string, error) {
+			content = c
+			return "cid", nil
+		})
+
+		o, err := New(&Config{Type: IPFS, IPFS: IPFSConfig{Endpoint: "any"}}, nil, nil, "")
+		r.NoError(err)
+		so, ok := o.(ProverSignedOutput)
+		r.True(ok)
+		res, err := so.OutputWithProverSignature(tsk, []byte("proof"), "0x01", nil)
+		r.NoError(err)
+		r.Equal("cid", res)
+
+		envelope := &ipfsEnvelope{}
+		r.NoError(json.Unmarshal(content, envelope))
+		r.Equal(tsk.ID, envelope.TaskID)
+		r.Equal([]byte("proof"), []byte(envelope.Proof))
+		r.Equal("0x01", envelope.ProverSignature)
+	})
+
+	t.Run("ChainToNextOutput", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ipfs.IPFS{}, "AddContent", "cid", nil)
+		p.ApplyMethodFunc(&stdout{}, "Output", func(_ *task.Task, proof []byte) (string, error) {
+			r.Equal("cid", string(proof))
+			return "next", nil
+		})
+
+		o, err := New(&Config{Type: IPFS, IPFS: IPFSConfig{Endpoint: "any", Next: &Config{Type: Stdout}}}, nil, nil, "")
+		r.NoError(err)
+		res, err := o.Output(tsk, []byte("proof"))
+		r.NoError(err)
+		r.JSONEq(`{"cid":"cid","next":"next"}`, res)
+	})
+
+	t.Run("ChainedSpend", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ipfs.IPFS{}, "AddContent", "cid", nil)
+
+		spend := &Spend{GasUsed: 1}
+		o := &ipfsStorage{sh: &ipfs.IPFS{}, next: &mockSpendOutput{spend: spend}}
+		spent := []*Spend{}
+		res, err := o.OutputWithProverSignature(tsk, []byte("proof"), "0x01", func(s *Spend) { spent = append(spent, s) })
+		r.NoError(err)
+		r.JSONEq(`{"cid":"cid","next":"tx"}`, res)
+		r.Equal([]*Spend{spend}, spent)
+	})
+
+	t.Run("FailedToOutputToNext", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		p.ApplyMethodReturn(&ipfs.IPFS{}, "AddContent", "cid", nil)
+		p.ApplyMethodReturn(&stdout{}, "Output", "", errors.New(t.Name()))
+
+		o, err := New(&Config{Type: IPFS, IPFS: IPFSConfig{Endpoint: "any", Next: &Config{Type: Stdout}}}, nil, nil, "")
+		r.NoError(err)
+		_, err = o.Output(tsk, []byte("proof"))
+		r.ErrorContains(err, t.Name())
+	})
+}
+
+func Test_newIPFSStorage(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("MissingEndpoint", func(t *testing.T) {
+		_, err := newIPFSStorage(IPFSConfig{}, nil, nil, "")
+		r.Error(err)
+	})
+	t.Run("ChainToIPFS", func(t *testing.T) {
+		_, err := newIPFSStorage(IPFSConfig{Endpoint: "any", Next: &Config{Type: IPFS}}, nil, nil, "")
+		r.Error(err)
+	})
+	t.Run("FailedToNewNext", func(t *testing.T) {
+		_, err := newIPFSStorage(IPFSConfig{Endpoint: "any", Next: &Config{Type: EthereumContract}}, nil, nil, "")
+		r.Error(err)
+	})
+}
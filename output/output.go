@@ -1,6 +1,11 @@
 package output
 
-import "github.com/machinefi/sprout/task"
+import (
+	"math/big"
+
+	"github.com/machinefi/sprout/signer"
+	"github.com/machinefi/sprout/task"
+)
 
 type Type string
 
@@ -9,6 +14,8 @@ const (
 	EthereumContract Type = "ethereumContract"
 	SolanaProgram    Type = "solanaProgram"
 	Textile          Type = "textile"
+	Webhook          Type = "webhook"
+	IPFS             Type = "ipfs"
 )
 
 type Config struct {
@@ -16,6 +23,8 @@ type Config struct {
 	Ethereum EthereumConfig `json:"ethereum"`
 	Solana   SolanaConfig   `json:"solana"`
 	Textile  TextileConfig  `json:"textile"`
+	Webhook  WebhookConfig  `json:"webhook"`
+	IPFS     IPFSConfig     `json:"ipfs"`
 }
 
 type EthereumConfig struct {
@@ -24,30 +33,96 @@ type EthereumConfig struct {
 	ReceiverAddress string `json:"receiverAddress,omitempty"`
 	ContractMethod  string `json:"contractMethod"`
 	ContractAbiJSON string `json:"contractAbiJSON"`
+	DryRun          bool   `json:"dryRun,omitempty"` // only simulate the transaction, nothing will be sent
 }
 
 type SolanaConfig struct {
-	ChainEndpoint  string `json:"chainEndpoint"`
-	ProgramID      string `json:"programID"`
-	StateAccountPK string `json:"stateAccountPK"`
+	ChainEndpoint            string              `json:"chainEndpoint"`
+	ProgramID                string              `json:"programID"`
+	StateAccountPK           string              `json:"stateAccountPK"` // deprecated, used as the only writable account if Accounts is empty
+	Accounts                 []SolanaAccountMeta `json:"accounts,omitempty"`
+	InstructionDiscriminator string              `json:"instructionDiscriminator,omitempty"` // hex, default is 0x00
+	DataLayout               []string            `json:"dataLayout,omitempty"`               // fields follow the discriminator, default is [proof]
+	Commitment               string              `json:"commitment,omitempty"`               // processed, confirmed(default) or finalized
+}
+
+type SolanaAccountMeta struct {
+	PubKey     string `json:"pubKey"`
+	IsSigner   bool   `json:"isSigner,omitempty"`
+	IsWritable bool   `json:"isWritable,omitempty"`
 }
 
 type TextileConfig struct {
-	VaultID string `json:"vaultID"`
+	Endpoint         string `json:"endpoint,omitempty"` // default is https://basin.tableland.xyz
+	VaultID          string `json:"vaultID"`
+	PayloadExtractor string `json:"payloadExtractor,omitempty"` // one of risc0(default), halo2, zkwasm and raw
+	MaxRetries       *int   `json:"maxRetries,omitempty"`
 }
 
+type WebhookConfig struct {
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Secret        string            `json:"secret,omitempty"`       // hmac-sha256 key for signing the request body
+	BodyTemplate  string            `json:"bodyTemplate,omitempty"` // text/template renders a json body, `json` func is provided
+	MaxRetries    *int              `json:"maxRetries,omitempty"`
+	RetryBackoff  string            `json:"retryBackoff,omitempty"` // initial backoff duration, doubled after each retry
+	SuccessStatus []int             `json:"successStatus,omitempty"`
+}
+
+type IPFSConfig struct {
+	Endpoint string  `json:"endpoint"`
+	Next     *Config `json:"next,omitempty"` // optional, the cid will be output to it as proof
+}
+
+// DryRunPrefix prefixes the result of an output in dry run mode, e.g. the eth_call return data of a simulated
+// transaction, so that it is never taken as the result of an output actually sent
+const DryRunPrefix = "dryrun:"
+
 type Output interface {
 	Output(task *task.Task, proof []byte) (string, error)
 }
 
-func New(conf *Config, privateKeyECDSA, privateKeyED25519 string, contractWhitelist string) (Output, error) {
+// ProverSignedOutput is implemented by outputs which publish the prover signature along with the proof, spent is
+// called with the spend of the chained output paying for transactions
+type ProverSignedOutput interface {
+	OutputWithProverSignature(task *task.Task, proof []byte, proverSignature string, spent func(*Spend)) (string, error)
+}
+
+// Spend is the cost paid by the operator for an output
+type Spend struct {
+	GasUsed uint64
+	Fee     *big.Int // in wei
+}
+
+// SpendOutput is implemented by outputs which pay for transactions, spent is called with the spend taken from the
+// transaction receipt, even if the output failed after the transaction is mined. spent may be called after the
+// output returned, if the transaction is sent but not mined in time
+type SpendOutput interface {
+	OutputWithSpend(task *task.Task, proof []byte, spent func(*Spend)) (string, error)
+}
+
+// outputWithSpend outputs to o, the spend is reported to spent if o pays for transactions. spent is optional
+func outputWithSpend(o Output, task *task.Task, proof []byte, spent func(*Spend)) (string, error) {
+	if so, ok := o.(SpendOutput); ok && spent != nil {
+		return so.OutputWithSpend(task, proof, spent)
+	}
+	return o.Output(task, proof)
+}
+
+// New creates the output, the operator signers are used by the outputs sending transactions, a nil signer means
+// the corresponding key is not configured
+func New(conf *Config, operatorECDSA signer.ECDSA, operatorED25519 signer.ED25519, contractWhitelist string) (Output, error) {
 	switch conf.Type {
 	case EthereumContract:
-		return newEthereum(conf.Ethereum, privateKeyECDSA, contractWhitelist)
+		return newEthereum(conf.Ethereum, operatorECDSA, contractWhitelist)
 	case SolanaProgram:
-		return newSolanaProgram(conf.Solana, privateKeyED25519)
+		return newSolanaProgram(conf.Solana, operatorED25519)
 	case Textile:
-		return newTextileDBAdapter(conf.Textile, privateKeyECDSA)
+		return newTextileDBAdapter(conf.Textile, operatorECDSA)
+	case Webhook:
+		return newWebhook(conf.Webhook)
+	case IPFS:
+		return newIPFSStorage(conf.IPFS, operatorECDSA, operatorED25519, contractWhitelist)
 	default:
 		return newStdout(), nil
 	}
@@ -13,7 +13,7 @@ func TestNew(t *testing.T) {
 
 	t.Run("Default", func(t *testing.T) {
 		c := &Config{}
-		o, err := New(c, "", "", "")
+		o, err := New(c, nil, nil, "")
 		r.NoError(err)
 		_, ok := o.(*stdout)
 		r.True(ok)
@@ -22,7 +22,7 @@ func TestNew(t *testing.T) {
 		c := &Config{
 			Type: Stdout,
 		}
-		o, err := New(c, "", "", "")
+		o, err := New(c, nil, nil, "")
 		r.NoError(err)
 		_, ok := o.(*stdout)
 		r.True(ok)
@@ -41,7 +41,7 @@ func TestNew(t *testing.T) {
 				ContractMethod:  "getProof",
 			},
 		}
-		o, err := New(c, "c47bbade736b0f82788aa6eaa06140cdf41a544707edef944299642e0d708cab", "", "")
+		o, err := New(c, testOperatorECDSA, nil, "")
 		r.NoError(err)
 		_, ok := o.(*ethereumContract)
 		r.True(ok)
@@ -49,9 +49,9 @@ func TestNew(t *testing.T) {
 	t.Run("Solana", func(t *testing.T) {
 		c := &Config{
 			Type:   SolanaProgram,
-			Solana: SolanaConfig{},
+			Solana: SolanaConfig{ProgramID: testSolanaProgramID},
 		}
-		o, err := New(c, "", "308edd7fca562182adbffaa59264a138d9e04f9f3adbda2c80ef1ca71b7dcfa4", "")
+		o, err := New(c, nil, testOperatorED25519, "")
 		r.NoError(err)
 		_, ok := o.(*solanaProgram)
 		r.True(ok)
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = time.Second
+)
+
+// httpStatusError is returned when an http endpoint responds with an unexpected status
+type httpStatusError struct {
+	code int
+	body string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status %d: %s", e.code, e.body)
+}
+
+// retryable reports whether a failed output attempt is worth retrying; client errors of http endpoints are not
+func retryable(err error) bool {
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.code >= http.StatusInternalServerError || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// retry calls fn until it succeeds, the error is not retryable or maxRetries is reached, the backoff is doubled
+// after each attempt
+func retry(maxRetries int, backoff time.Duration, fn func() error) error {
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || i >= maxRetries || !retryable(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -2,28 +2,61 @@ package output
 
 import (
 	"context"
-	"crypto/ed25519"
+	"encoding/binary"
 	"log/slog"
+	"time"
 
 	"github.com/blocto/solana-go-sdk/client"
 	solcommon "github.com/blocto/solana-go-sdk/common"
+	"github.com/blocto/solana-go-sdk/rpc"
 	soltypes "github.com/blocto/solana-go-sdk/types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mr-tron/base58"
 	"github.com/pkg/errors"
 
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
+// fields could be used in solana instruction data layout
+const (
+	solanaFieldProof           = "proof"           // raw proof bytes
+	solanaFieldProofWithLength = "proofWithLength" // proof bytes with u32 little endian length prefix, as borsh Vec<u8>
+	solanaFieldProjectID       = "projectID"       // u64 little endian
+	solanaFieldTaskID          = "taskID"          // u64 little endian
+	solanaFieldClientID        = "clientID"        // utf8 with u32 little endian length prefix, as borsh String
+	solanaFieldDataHash        = "dataHash"        // keccak256 of task data, 32 bytes
+)
+
+var (
+	errSolanaBlockhashExpired = errors.New("solana blockhash expired before tx confirmed")
+
+	solanaCommitmentLevels = map[rpc.Commitment]int{
+		rpc.CommitmentProcessed: 0,
+		rpc.CommitmentConfirmed: 1,
+		rpc.CommitmentFinalized: 2,
+	}
+)
+
 type solanaProgram struct {
-	endpoint       string
-	programID      string
-	secretKey      string
-	stateAccountPK string
+	endpoint      string
+	programID     solcommon.PublicKey
+	operator      signer.ED25519
+	accounts      []soltypes.AccountMeta
+	discriminator []byte
+	dataLayout    []string
+	commitment    rpc.Commitment
+	maxRetries    int
+	pollInterval  time.Duration
 }
 
 func (e *solanaProgram) Output(task *task.Task, proof []byte) (string, error) {
 	slog.Debug("outputing to solana program", "chain endpoint", e.endpoint)
-	ins := e.packInstructions(proof)
+	ins, err := e.packInstructions(task, proof)
+	if err != nil {
+		return "", err
+	}
 	txHash, err := e.sendTX(ins)
 	if err != nil {
 		return "", err
@@ -32,79 +65,221 @@ func (e *solanaProgram) Output(task *task.Task, proof []byte) (string, error) {
 	return txHash, nil
 }
 
+// sendTX sends the instructions and waits the tx reaching the configured commitment, the tx will be rebuilt with
+// a new blockhash and resent if the previous one expired
 func (e *solanaProgram) sendTX(ins []soltypes.Instruction) (string, error) {
 	cli := client.NewClient(e.endpoint)
-	b := common.FromHex(e.secretKey)
-	pk := ed25519.PrivateKey(b)
-	account := soltypes.Account{
-		PublicKey:  solcommon.PublicKeyFromBytes(pk.Public().(ed25519.PublicKey)),
-		PrivateKey: pk,
-	}
+	feePayer := solcommon.PublicKeyFromBytes(e.operator.PublicKey())
 	if len(ins) == 0 {
 		return "", errors.New("missing instruction data")
 	}
 
-	resp, err := cli.GetLatestBlockhash(context.Background())
-	if err != nil {
-		return "", errors.Wrap(err, "failed to get solana latest block hash")
-	}
-	tx, err := soltypes.NewTransaction(soltypes.NewTransactionParam{
-		Message: soltypes.NewMessage(soltypes.NewMessageParam{
-			FeePayer:        account.PublicKey,
-			RecentBlockhash: resp.Blockhash,
-			Instructions:    ins,
-		}),
-		Signers: []soltypes.Account{account},
+	ctx := context.Background()
+	for i := 0; ; i++ {
+		resp, err := cli.GetLatestBlockhash(ctx)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get solana latest block hash")
+		}
+		tx, err := e.buildTX(feePayer, resp.Blockhash, ins)
+		if err != nil {
+			return "", err
+		}
+
+		hash, err := cli.SendTransaction(ctx, tx)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to send solana tx")
+		}
+
+		err = e.waitConfirmation(ctx, cli, hash, resp.Blockhash)
+		if err == nil {
+			return hash, nil
+		}
+		if !errors.Is(err, errSolanaBlockhashExpired) || i >= e.maxRetries {
+			return "", err
+		}
+		slog.Debug("solana blockhash expired, resend tx", "tx_hash", hash)
+	}
+}
+
+// buildTX builds a tx with the fee payer signature, the message is signed by the operator signer
+func (e *solanaProgram) buildTX(feePayer solcommon.PublicKey, blockhash string, ins []soltypes.Instruction) (soltypes.Transaction, error) {
+	msg := soltypes.NewMessage(soltypes.NewMessageParam{
+		FeePayer:        feePayer,
+		RecentBlockhash: blockhash,
+		Instructions:    ins,
 	})
+	tx, err := soltypes.NewTransaction(soltypes.NewTransactionParam{Message: msg})
 	if err != nil {
-		return "", errors.Wrap(err, "failed to build solana raw tx")
+		return soltypes.Transaction{}, errors.Wrap(err, "failed to build solana raw tx")
 	}
-
-	hash, err := cli.SendTransaction(context.Background(), tx)
+	serialized, err := msg.Serialize()
 	if err != nil {
-		return "", errors.Wrap(err, "failed to send solana tx")
+		return soltypes.Transaction{}, errors.Wrap(err, "failed to serialize solana tx message")
+	}
+	sig, err := e.operator.Sign(serialized)
+	if err != nil {
+		return soltypes.Transaction{}, errors.Wrap(err, "failed to sign solana tx")
+	}
+	if err := tx.AddSignature(sig); err != nil {
+		return soltypes.Transaction{}, errors.Wrap(err, "failed to add solana tx signature")
 	}
-	return hash, nil
+	return tx, nil
 }
 
-// encodeData encodes the proof into the data field of the instruction
-// the first byte is the instruction, which is 0 for now;
-// the rest is the proof data.
-// e.g. assume proof is [0x01, 0x02, 0x03], then the encoded data is [0x00, 0x01, 0x02, 0x03]
-func (e *solanaProgram) encodeData(proof []byte) []byte {
+func (e *solanaProgram) waitConfirmation(ctx context.Context, cli *client.Client, hash, blockhash string) error {
+	expired := false
+	for {
+		status, err := cli.GetSignatureStatus(ctx, hash)
+		if err != nil {
+			return errors.Wrap(err, "failed to get solana tx status")
+		}
+		if status != nil {
+			if status.Err != nil {
+				return errors.Errorf("solana tx %s failed: %v", hash, status.Err)
+			}
+			if status.ConfirmationStatus != nil &&
+				solanaCommitmentLevels[*status.ConfirmationStatus] >= solanaCommitmentLevels[e.commitment] {
+				return nil
+			}
+		} else {
+			// the status is checked once more after blockhash expired, in case the tx landed meanwhile
+			if expired {
+				return errSolanaBlockhashExpired
+			}
+			valid, err := cli.IsBlockhashValid(ctx, blockhash)
+			if err != nil {
+				return errors.Wrap(err, "failed to check solana blockhash")
+			}
+			expired = !valid
+			if expired {
+				continue
+			}
+		}
+		time.Sleep(e.pollInterval)
+	}
+}
+
+// encodeData encodes the instruction data, which is the discriminator followed by the fields in data layout.
+// e.g. with the default discriminator [0x00] and layout [proof], assume proof is [0x01, 0x02, 0x03], then the
+// encoded data is [0x00, 0x01, 0x02, 0x03]
+func (e *solanaProgram) encodeData(task *task.Task, proof []byte) ([]byte, error) {
 	data := []byte{}
-	data = append(data, byte(0)) // 0 means submit proof
-	data = append(data, proof...)
-	return data
+	data = append(data, e.discriminator...)
+	for _, f := range e.dataLayout {
+		switch f {
+		case solanaFieldProof:
+			data = append(data, proof...)
+		case solanaFieldProofWithLength:
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(proof)))
+			data = append(data, proof...)
+		case solanaFieldProjectID:
+			data = binary.LittleEndian.AppendUint64(data, task.ProjectID)
+		case solanaFieldTaskID:
+			data = binary.LittleEndian.AppendUint64(data, task.ID)
+		case solanaFieldClientID:
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(task.ClientID)))
+			data = append(data, task.ClientID...)
+		case solanaFieldDataHash:
+			data = append(data, crypto.Keccak256Hash(task.Data...).Bytes()...)
+		default:
+			return nil, errors.Errorf("unsupported solana data layout field %s", f)
+		}
+	}
+	return data, nil
 }
 
-func (e *solanaProgram) packInstructions(proof []byte) []soltypes.Instruction {
+func (e *solanaProgram) packInstructions(task *task.Task, proof []byte) ([]soltypes.Instruction, error) {
+	data, err := e.encodeData(task, proof)
+	if err != nil {
+		return nil, err
+	}
+	return []soltypes.Instruction{
+		{
+			ProgramID: e.programID,
+			Accounts:  e.accounts,
+			Data:      data,
+		},
+	}, nil
+}
+
+// solanaPublicKey decodes the base58 public key, unlike solcommon.PublicKeyFromString which ignores the invalid key
+func solanaPublicKey(s string) (solcommon.PublicKey, error) {
+	b, err := base58.Decode(s)
+	if err != nil {
+		return solcommon.PublicKey{}, errors.Wrapf(err, "failed to decode solana public key %s", s)
+	}
+	if len(b) != solcommon.PublicKeyLength {
+		return solcommon.PublicKey{}, errors.Errorf("invalid solana public key %s, expect %d bytes", s, solcommon.PublicKeyLength)
+	}
+	return solcommon.PublicKeyFromBytes(b), nil
+}
+
+func newSolanaProgram(conf SolanaConfig, operator signer.ED25519) (*solanaProgram, error) {
+	if operator == nil {
+		return nil, errors.New("operator signer is empty")
+	}
+	programID, err := solanaPublicKey(conf.ProgramID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid solana program id")
+	}
+	// the tx is only signed by the operator, so no other account could be a signer
+	operatorPK := solcommon.PublicKeyFromBytes(operator.PublicKey())
 	accounts := []soltypes.AccountMeta{}
-	if e.stateAccountPK != "" {
+	for _, a := range conf.Accounts {
+		pk, err := solanaPublicKey(a.PubKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid solana account")
+		}
+		if a.IsSigner && pk != operatorPK {
+			return nil, errors.Errorf("solana account %s could not be a signer, only the operator signs the tx", a.PubKey)
+		}
+		accounts = append(accounts, soltypes.AccountMeta{
+			PubKey:     pk,
+			IsSigner:   a.IsSigner,
+			IsWritable: a.IsWritable,
+		})
+	}
+	if len(conf.Accounts) == 0 && conf.StateAccountPK != "" {
+		pk, err := solanaPublicKey(conf.StateAccountPK)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid solana state account")
+		}
 		accounts = append(accounts, soltypes.AccountMeta{
-			PubKey:     solcommon.PublicKeyFromString(e.stateAccountPK),
+			PubKey:     pk,
 			IsSigner:   false,
 			IsWritable: true,
 		})
 	}
 
-	return []soltypes.Instruction{
-		{
-			ProgramID: solcommon.PublicKeyFromString(e.programID),
-			Accounts:  accounts,
-			Data:      e.encodeData(proof),
-		},
+	discriminator := []byte{0} // 0 means submit proof
+	if conf.InstructionDiscriminator != "" {
+		discriminator = common.FromHex(conf.InstructionDiscriminator)
+	}
+	dataLayout := conf.DataLayout
+	if len(dataLayout) == 0 {
+		dataLayout = []string{solanaFieldProof}
+	}
+	commitment := rpc.CommitmentConfirmed
+	if conf.Commitment != "" {
+		commitment = rpc.Commitment(conf.Commitment)
+		if _, ok := solanaCommitmentLevels[commitment]; !ok {
+			return nil, errors.Errorf("unsupported solana commitment %s", conf.Commitment)
+		}
 	}
-}
 
-func newSolanaProgram(conf SolanaConfig, secretKey string) (*solanaProgram, error) {
-	if secretKey == "" {
-		return nil, errors.New("secret key is empty")
+	e := &solanaProgram{
+		endpoint:      conf.ChainEndpoint,
+		programID:     programID,
+		operator:      operator,
+		accounts:      accounts,
+		discriminator: discriminator,
+		dataLayout:    dataLayout,
+		commitment:    commitment,
+		maxRetries:    defaultMaxRetries,
+		pollInterval:  time.Second,
 	}
-	return &solanaProgram{
-		endpoint:       conf.ChainEndpoint,
-		programID:      conf.ProgramID,
-		secretKey:      secretKey,
-		stateAccountPK: conf.StateAccountPK,
-	}, nil
+	if _, err := e.encodeData(&task.Task{}, nil); err != nil {
+		return nil, err
+	}
+	return e, nil
 }
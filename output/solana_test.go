@@ -1,10 +1,12 @@
 package output
 
 import (
+	"crypto/ed25519"
 	"testing"
 
 	. "github.com/agiledragon/gomonkey/v2"
 	"github.com/blocto/solana-go-sdk/client"
+	"github.com/blocto/solana-go-sdk/rpc"
 	soltypes "github.com/blocto/solana-go-sdk/types"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
@@ -12,6 +14,27 @@ import (
 	"github.com/machinefi/sprout/task"
 )
 
+var testOperatorED25519 = &mockED25519{}
+
+// the public key of testOperatorED25519 is zero
+const (
+	testSolanaOperatorPK = "11111111111111111111111111111111"
+	testSolanaProgramID  = "EyZxqBDzbjLb3qAAWEYswqEpENpYtNWKnKiB6jX1pBd1"
+)
+
+type mockED25519 struct {
+	err error
+}
+
+func (s *mockED25519) PublicKey() ed25519.PublicKey { return make([]byte, ed25519.PublicKeySize) }
+
+func (s *mockED25519) Sign(message []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return make([]byte, ed25519.SignatureSize), nil
+}
+
 func patchSolanaProgramSendTX(p *Patches, txhash string, err error) *Patches {
 	return p.ApplyPrivateMethod(&solanaProgram{}, "sendTX", func(*solanaProgram, []soltypes.Instruction) (string, error) {
 		return txhash, err
@@ -20,8 +43,8 @@ func patchSolanaProgramSendTX(p *Patches, txhash string, err error) *Patches {
 
 func Test_solanaProgram_Output(t *testing.T) {
 	r := require.New(t)
-	e1 := &solanaProgram{stateAccountPK: ""}
-	e2 := &solanaProgram{stateAccountPK: "any"}
+	e1 := &solanaProgram{dataLayout: []string{solanaFieldProof}}
+	e2 := &solanaProgram{accounts: []soltypes.AccountMeta{{IsWritable: true}}, dataLayout: []string{solanaFieldProof}}
 
 	t.Run("FailedToSendTX", func(t *testing.T) {
 		p := NewPatches()
@@ -37,6 +60,12 @@ func Test_solanaProgram_Output(t *testing.T) {
 		r.ErrorContains(err, t.Name())
 	})
 
+	t.Run("FailedToPackInstructions", func(t *testing.T) {
+		e := &solanaProgram{dataLayout: []string{"any"}}
+		_, err := e.Output(&task.Task{}, nil)
+		r.ErrorContains(err, "unsupported solana data layout field")
+	})
+
 	t.Run("Success", func(t *testing.T) {
 		txHashRet := "anyTxHash"
 		p := NewPatches()
@@ -59,9 +88,13 @@ func Test_solanaProgram_sendTX(t *testing.T) {
 	defer p.Reset()
 
 	contract := &solanaProgram{
-		secretKey: "fd6ac80f1b9886a6d157cd8e71f842a63c52ebd237cf48fba03ae587e197d511f0b2439ae6da236d26f17f56c68f05d48513cd99b33143fa0b1aec7838ce4276",
+		operator:   testOperatorED25519,
+		dataLayout: []string{solanaFieldProof},
+		commitment: rpc.CommitmentConfirmed,
+		maxRetries: 1,
 	}
-	ins := contract.packInstructions([]byte("proof"))
+	ins, err := contract.packInstructions(&task.Task{}, []byte("proof"))
+	r.NoError(err)
 
 	t.Run("MissingInstructionData", func(t *testing.T) {
 		p = p.ApplyFuncReturn(client.NewClient, &client.Client{})
@@ -74,14 +107,22 @@ func Test_solanaProgram_sendTX(t *testing.T) {
 		_, err := contract.sendTX(ins)
 		r.ErrorContains(err, t.Name())
 	})
-	p = p.ApplyMethodReturn(&client.Client{}, "GetLatestBlockhash", nil, nil)
+	p = p.ApplyMethodReturn(&client.Client{}, "GetLatestBlockhash", rpc.GetLatestBlockhashValue{Blockhash: "11111111111111111111111111111111"}, nil)
 
 	t.Run("BuildSolanaTxFailed", func(t *testing.T) {
 		p = p.ApplyFuncReturn(soltypes.NewTransaction, nil, errors.New(t.Name()))
 		_, err := contract.sendTX(ins)
 		r.ErrorContains(err, t.Name())
 	})
-	p = p.ApplyFuncReturn(soltypes.NewTransaction, nil, nil)
+	p = p.ApplyFuncReturn(soltypes.NewTransaction, soltypes.Transaction{}, nil)
+
+	t.Run("SignSolanaTxFailed", func(t *testing.T) {
+		contract := *contract
+		contract.operator = &mockED25519{err: errors.New(t.Name())}
+		_, err := contract.sendTX(ins)
+		r.ErrorContains(err, t.Name())
+	})
+	p = p.ApplyMethodReturn(&soltypes.Transaction{}, "AddSignature", nil)
 
 	t.Run("SendSolanaTxFailed", func(t *testing.T) {
 		p = p.ApplyMethodReturn(&client.Client{}, "SendTransaction", "", errors.New(t.Name()))
@@ -89,11 +130,127 @@ func Test_solanaProgram_sendTX(t *testing.T) {
 		r.ErrorContains(err, t.Name())
 	})
 
+	p = p.ApplyMethodReturn(&client.Client{}, "SendTransaction", "anyTxHash", nil)
+
+	t.Run("GetSignatureStatusFailed", func(t *testing.T) {
+		p = p.ApplyMethodReturn(&client.Client{}, "GetSignatureStatus", nil, errors.New(t.Name()))
+		_, err := contract.sendTX(ins)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("SolanaTxFailed", func(t *testing.T) {
+		p = p.ApplyMethodReturn(&client.Client{}, "GetSignatureStatus", &rpc.SignatureStatus{Err: t.Name()}, nil)
+		_, err := contract.sendTX(ins)
+		r.ErrorContains(err, t.Name())
+	})
+
+	t.Run("BlockhashExpired", func(t *testing.T) {
+		p = p.ApplyMethodReturn(&client.Client{}, "GetSignatureStatus", nil, nil)
+		p = p.ApplyMethodReturn(&client.Client{}, "IsBlockhashValid", false, nil)
+		_, err := contract.sendTX(ins)
+		r.ErrorIs(err, errSolanaBlockhashExpired)
+	})
+
 	t.Run("SendSolanaTxSuccess", func(t *testing.T) {
-		p = p.ApplyMethodReturn(&client.Client{}, "SendTransaction", t.Name(), nil)
+		confirmed := rpc.CommitmentFinalized
+		p = p.ApplyMethodReturn(&client.Client{}, "GetSignatureStatus", &rpc.SignatureStatus{ConfirmationStatus: &confirmed}, nil)
 
 		hash, err := contract.sendTX(ins)
 		r.NoError(err)
-		r.Equal(t.Name(), hash)
+		r.Equal("anyTxHash", hash)
+	})
+}
+
+func Test_solanaProgram_encodeData(t *testing.T) {
+	r := require.New(t)
+
+	e, err := newSolanaProgram(SolanaConfig{
+		ProgramID:                testSolanaProgramID,
+		InstructionDiscriminator: "0x0102",
+		DataLayout: []string{
+			solanaFieldProjectID,
+			solanaFieldTaskID,
+			solanaFieldClientID,
+			solanaFieldProofWithLength,
+		},
+	}, testOperatorED25519)
+	r.NoError(err)
+
+	data, err := e.encodeData(&task.Task{ID: 2, ProjectID: 1, ClientID: "c"}, []byte{9})
+	r.NoError(err)
+	r.Equal([]byte{
+		1, 2,
+		1, 0, 0, 0, 0, 0, 0, 0,
+		2, 0, 0, 0, 0, 0, 0, 0,
+		1, 0, 0, 0, 'c',
+		1, 0, 0, 0, 9,
+	}, data)
+
+	t.Run("DefaultLayout", func(t *testing.T) {
+		e, err := newSolanaProgram(SolanaConfig{ProgramID: testSolanaProgramID}, testOperatorED25519)
+		r.NoError(err)
+		data, err := e.encodeData(&task.Task{}, []byte{1, 2, 3})
+		r.NoError(err)
+		r.Equal([]byte{0, 1, 2, 3}, data)
+	})
+}
+
+func Test_newSolanaProgram(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("MissingSecretKey", func(t *testing.T) {
+		_, err := newSolanaProgram(SolanaConfig{ProgramID: testSolanaProgramID}, nil)
+		r.Error(err)
+	})
+	t.Run("InvalidProgramID", func(t *testing.T) {
+		for _, id := range []string{"", "0OIl", "1111"} {
+			_, err := newSolanaProgram(SolanaConfig{ProgramID: id}, testOperatorED25519)
+			r.ErrorContains(err, "invalid solana program id")
+		}
+	})
+	t.Run("InvalidAccount", func(t *testing.T) {
+		_, err := newSolanaProgram(SolanaConfig{
+			ProgramID: testSolanaProgramID,
+			Accounts:  []SolanaAccountMeta{{PubKey: "0OIl"}},
+		}, testOperatorED25519)
+		r.ErrorContains(err, "invalid solana account")
+	})
+	t.Run("InvalidStateAccount", func(t *testing.T) {
+		_, err := newSolanaProgram(SolanaConfig{ProgramID: testSolanaProgramID, StateAccountPK: "1111"}, testOperatorED25519)
+		r.ErrorContains(err, "invalid solana state account")
+	})
+	t.Run("NonOperatorSigner", func(t *testing.T) {
+		_, err := newSolanaProgram(SolanaConfig{
+			ProgramID: testSolanaProgramID,
+			Accounts:  []SolanaAccountMeta{{PubKey: testSolanaProgramID, IsSigner: true}},
+		}, testOperatorED25519)
+		r.ErrorContains(err, "could not be a signer")
+	})
+	t.Run("InvalidDataLayout", func(t *testing.T) {
+		_, err := newSolanaProgram(SolanaConfig{ProgramID: testSolanaProgramID, DataLayout: []string{"any"}}, testOperatorED25519)
+		r.Error(err)
+	})
+	t.Run("InvalidCommitment", func(t *testing.T) {
+		_, err := newSolanaProgram(SolanaConfig{ProgramID: testSolanaProgramID, Commitment: "any"}, testOperatorED25519)
+		r.Error(err)
+	})
+	t.Run("Accounts", func(t *testing.T) {
+		e, err := newSolanaProgram(SolanaConfig{ProgramID: testSolanaProgramID, StateAccountPK: testSolanaProgramID}, testOperatorED25519)
+		r.NoError(err)
+		r.Len(e.accounts, 1)
+		r.True(e.accounts[0].IsWritable)
+		r.Equal(testSolanaProgramID, e.programID.ToBase58())
+
+		e, err = newSolanaProgram(SolanaConfig{
+			ProgramID:      testSolanaProgramID,
+			StateAccountPK: testSolanaProgramID,
+			Accounts: []SolanaAccountMeta{
+				{PubKey: testSolanaOperatorPK, IsSigner: true},
+				{PubKey: testSolanaProgramID, IsWritable: true},
+			},
+		}, testOperatorED25519)
+		r.NoError(err)
+		r.Len(e.accounts, 2)
+		r.True(e.accounts[0].IsSigner)
 	})
 }
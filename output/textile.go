@@ -2,7 +2,6 @@ package output
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -11,36 +10,37 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
-	"github.com/tablelandnetwork/basin-cli/pkg/signing"
 	"github.com/tidwall/gjson"
 
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
-type textileDB struct {
-	endpoint  string
-	secretKey *ecdsa.PrivateKey
+const defaultTextileEndpoint = "https://basin.tableland.xyz"
+
+// textilePayload is the event content written to the vault
+type textilePayload struct {
+	Result string `json:"result,omitempty"`
+	Proof  string `json:"proof"`
 }
 
-func (t *textileDB) Output(task *task.Task, proof []byte) (string, error) {
-	slog.Debug("outputing to textileDB", "chain endpoint", t.endpoint)
-	encodedData, err := t.packData(proof)
-	if err != nil {
-		return "", err
-	}
-	txHash, err := t.write(encodedData)
-	if err != nil {
-		return "", err
-	}
-	return txHash, nil
+// textilePayloadExtractor extracts the event content from the proof generated by a specific vm
+type textilePayloadExtractor func(proof []byte) (*textilePayload, error)
+
+var textilePayloadExtractors = map[string]textilePayloadExtractor{
+	"risc0":  extractRisc0Payload,
+	"halo2":  extractHexPayload,
+	"zkwasm": extractRawPayload,
+	"raw":    extractRawPayload,
 }
 
-func (t *textileDB) packData(proof []byte) ([]byte, error) {
+// extractRisc0Payload decodes a hex encoded risc0 receipt, the journal is the result
+func extractRisc0Payload(proof []byte) (*textilePayload, error) {
 	proof, err := hex.DecodeString(string(proof))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to decode proof")
@@ -63,21 +63,63 @@ func (t *textileDB) packData(proof []byte) ([]byte, error) {
 	for _, value := range values {
 		result += fmt.Sprint(value.Int())
 	}
+	return &textilePayload{Result: result, Proof: string(proof)}, nil
+}
 
-	data := map[string]string{
-		"result": result,
-		"proof":  string(proof),
+// extractHexPayload keeps a binary proof, such as halo2's, as a 0x prefixed hex string
+func extractHexPayload(proof []byte) (*textilePayload, error) {
+	s := strings.TrimPrefix(string(proof), "0x")
+	if _, err := hex.DecodeString(s); err != nil {
+		s = hex.EncodeToString(proof)
 	}
-	jsonData, err := json.Marshal(data)
+	return &textilePayload{Proof: "0x" + s}, nil
+}
+
+// extractRawPayload keeps a textual proof, such as zkwasm's, as it is
+func extractRawPayload(proof []byte) (*textilePayload, error) {
+	return &textilePayload{Proof: string(proof)}, nil
+}
+
+type textileDB struct {
+	endpoint     string
+	operator     signer.ECDSA
+	extractor    textilePayloadExtractor
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+func (t *textileDB) Output(task *task.Task, proof []byte) (string, error) {
+	slog.Debug("outputing to textileDB", "chain endpoint", t.endpoint)
+	encodedData, err := t.packData(proof)
 	if err != nil {
-		return nil, errors.Wrap(err, "marshal pack data error")
+		return "", err
+	}
+	txHash, err := t.write(encodedData)
+	if err != nil {
+		return "", err
 	}
+	return txHash, nil
+}
 
+func (t *textileDB) packData(proof []byte) ([]byte, error) {
+	extractor := t.extractor
+	if extractor == nil {
+		extractor = extractRisc0Payload
+	}
+	payload, err := extractor(proof)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal pack data error")
+	}
 	return jsonData, nil
 }
 
 func (t *textileDB) write(data []byte) (string, error) {
-	signatureBytes, err := signing.NewSigner(t.secretKey).SignBytes(data)
+	// the same as basin signing, which signs the keccak256 hash of data
+	signatureBytes, err := t.operator.SignHash(crypto.Keccak256(data))
 	if err != nil {
 		return "", errors.Wrap(err, "failed to sign data")
 	}
@@ -88,7 +130,13 @@ func (t *textileDB) write(data []byte) (string, error) {
 		t.endpoint,
 		strconv.FormatInt(time.Now().Unix(), 10),
 		txHash)
-	err = writeTextileEvent(url, data)
+	err = retry(t.maxRetries, t.retryBackoff, func() error {
+		err := writeTextileEvent(url, data)
+		if err != nil {
+			slog.Debug("failed to write textile event", "endpoint", t.endpoint, "error", err)
+		}
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -102,9 +150,8 @@ func writeTextileEvent(url string, fileData []byte) error {
 		return errors.Wrap(err, "failed to create request")
 	}
 
-	hn := sha256.New()
-	hr := hn.Sum(fileData)
-	req.Header.Set("filename", string(hr)[0:7])
+	hash := sha256.Sum256(fileData)
+	req.Header.Set("filename", hex.EncodeToString(hash[:]))
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -117,18 +164,42 @@ func writeTextileEvent(url string, fileData []byte) error {
 	if err != nil {
 		return err
 	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &httpStatusError{code: resp.StatusCode, body: string(responseBody)}
+	}
 
 	slog.Debug("Write event", "response", string(responseBody))
 	return nil
 }
 
-// TODO: refactor textile with a KV database adapter
-func newTextileDBAdapter(conf TextileConfig, secretKey string) (*textileDB, error) {
-	if secretKey == "" {
-		return nil, errors.New("secret key is empty")
+func newTextileDBAdapter(conf TextileConfig, operator signer.ECDSA) (*textileDB, error) {
+	if operator == nil {
+		return nil, errors.New("operator signer is empty")
+	}
+	if conf.VaultID == "" {
+		return nil, errors.New("vault id is empty")
+	}
+	endpoint := conf.Endpoint
+	if endpoint == "" {
+		endpoint = defaultTextileEndpoint
+	}
+	name := conf.PayloadExtractor
+	if name == "" {
+		name = "risc0"
+	}
+	extractor, ok := textilePayloadExtractors[name]
+	if !ok {
+		return nil, errors.Errorf("unsupported textile payload extractor %s", name)
+	}
+	t := &textileDB{
+		endpoint:     fmt.Sprintf("%s/vaults/%s/events", strings.TrimSuffix(endpoint, "/"), conf.VaultID),
+		operator:     operator,
+		extractor:    extractor,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+	}
+	if conf.MaxRetries != nil {
+		t.maxRetries = *conf.MaxRetries
 	}
-	return &textileDB{
-		endpoint:  fmt.Sprintf("https://basin.tableland.xyz/vaults/%s/events", conf.VaultID),
-		secretKey: crypto.ToECDSAUnsafe(common.FromHex(secretKey)),
-	}, nil
+	return t, nil
 }
@@ -2,16 +2,17 @@ package output
 
 import (
 	"bytes"
-	"crypto/ecdsa"
+	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	. "github.com/agiledragon/gomonkey/v2"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
-	"github.com/tablelandnetwork/basin-cli/pkg/signing"
 
 	"github.com/machinefi/sprout/task"
 	"github.com/machinefi/sprout/testutil"
@@ -33,17 +34,15 @@ func Test_textile_write(t *testing.T) {
 	r := require.New(t)
 
 	o := &textileDB{
-		endpoint:  "any",
-		secretKey: &ecdsa.PrivateKey{},
+		endpoint: "any",
+		operator: testOperatorECDSA,
 	}
 
 	t.Run("FailedToSignData", func(t *testing.T) {
 		p := NewPatches()
 		defer p.Reset()
 
-		p = p.ApplyFuncReturn(signing.NewSigner, &signing.Signer{})
-		p = p.ApplyMethodReturn(&signing.Signer{}, "SignBytes", nil, errors.New(t.Name()))
-
+		o := &textileDB{endpoint: "any", operator: &mockECDSA{err: errors.New(t.Name())}}
 		txHash, err := o.write([]byte("any"))
 		r.Equal(txHash, "")
 		r.ErrorContains(err, t.Name())
@@ -53,8 +52,6 @@ func Test_textile_write(t *testing.T) {
 		p := NewPatches()
 		defer p.Reset()
 
-		p = p.ApplyFuncReturn(signing.NewSigner, &signing.Signer{})
-		p = p.ApplyMethodReturn(&signing.Signer{}, "SignBytes", []byte("any"), nil)
 		p = p.ApplyFuncReturn(writeTextileEvent, errors.New(t.Name()))
 
 		txHash, err := o.write([]byte("any"))
@@ -62,16 +59,33 @@ func Test_textile_write(t *testing.T) {
 		r.ErrorContains(err, t.Name())
 	})
 
+	t.Run("RetryWriteTextileEvent", func(t *testing.T) {
+		p := NewPatches()
+		defer p.Reset()
+
+		calls := 0
+		p = p.ApplyFunc(writeTextileEvent, func(string, []byte) error {
+			calls++
+			if calls < 2 {
+				return &httpStatusError{code: http.StatusBadGateway}
+			}
+			return nil
+		})
+
+		o := &textileDB{endpoint: "any", operator: testOperatorECDSA, maxRetries: 1, retryBackoff: time.Millisecond}
+		_, err := o.write([]byte("any"))
+		r.NoError(err)
+		r.Equal(2, calls)
+	})
+
 	t.Run("Success", func(t *testing.T) {
 		p := NewPatches()
 		defer p.Reset()
 
-		p = p.ApplyFuncReturn(signing.NewSigner, &signing.Signer{})
-		p = p.ApplyMethodReturn(&signing.Signer{}, "SignBytes", []byte("any"), nil)
 		p = p.ApplyFuncReturn(writeTextileEvent, nil)
 
 		txHash, err := o.write([]byte("any"))
-		r.Equal(txHash, hex.EncodeToString([]byte("any")))
+		r.Equal(txHash, hex.EncodeToString(make([]byte, 65)))
 		r.NoError(err)
 	})
 }
@@ -80,8 +94,8 @@ func Test_textile_packData(t *testing.T) {
 	r := require.New(t)
 
 	o := &textileDB{
-		endpoint:  "any",
-		secretKey: &ecdsa.PrivateKey{},
+		endpoint: "any",
+		operator: testOperatorECDSA,
 	}
 
 	t.Run("FailedToDecodeProof", func(t *testing.T) {
@@ -135,8 +149,8 @@ func Test_textile_Output(t *testing.T) {
 	r := require.New(t)
 
 	o := &textileDB{
-		endpoint:  "any",
-		secretKey: &ecdsa.PrivateKey{},
+		endpoint: "any",
+		operator: testOperatorECDSA,
 	}
 
 	t.Run("FailedToPackData", func(t *testing.T) {
@@ -216,9 +230,69 @@ func Test_writeTextileEvent(t *testing.T) {
 			Header: http.Header{},
 		}, nil)
 		p = p.ApplyMethodReturn(&http.Client{}, "Do", &http.Response{
-			Body: io.NopCloser(bytes.NewBufferString("any")),
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString("any")),
 		}, nil)
 		p = testutil.IoReadAll(p, []byte("any"), nil)
 		r.NoError(writeTextileEvent("any", []byte("any")))
 	})
+
+	t.Run("UnexpectedStatus", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			hash := sha256.Sum256([]byte("any"))
+			r.Equal(hex.EncodeToString(hash[:]), req.Header.Get("filename"))
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+
+		err := writeTextileEvent(srv.URL, []byte("any"))
+		r.ErrorContains(err, "status 401")
+		r.False(retryable(err))
+	})
+}
+
+func Test_textilePayloadExtractors(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Halo2", func(t *testing.T) {
+		payload, err := textilePayloadExtractors["halo2"]([]byte{1, 2})
+		r.NoError(err)
+		r.Equal("0x0102", payload.Proof)
+
+		payload, err = textilePayloadExtractors["halo2"]([]byte("0x0102"))
+		r.NoError(err)
+		r.Equal("0x0102", payload.Proof)
+	})
+	t.Run("Zkwasm", func(t *testing.T) {
+		payload, err := textilePayloadExtractors["zkwasm"]([]byte(`{"any":1}`))
+		r.NoError(err)
+		r.Equal(`{"any":1}`, payload.Proof)
+	})
+}
+
+func Test_newTextileDBAdapter(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("MissingSecretKey", func(t *testing.T) {
+		_, err := newTextileDBAdapter(TextileConfig{VaultID: "any"}, nil)
+		r.Error(err)
+	})
+	t.Run("MissingVaultID", func(t *testing.T) {
+		_, err := newTextileDBAdapter(TextileConfig{}, testOperatorECDSA)
+		r.Error(err)
+	})
+	t.Run("UnsupportedExtractor", func(t *testing.T) {
+		_, err := newTextileDBAdapter(TextileConfig{VaultID: "any", PayloadExtractor: "any"}, testOperatorECDSA)
+		r.Error(err)
+	})
+	t.Run("DefaultEndpoint", func(t *testing.T) {
+		o, err := newTextileDBAdapter(TextileConfig{VaultID: "any"}, testOperatorECDSA)
+		r.NoError(err)
+		r.Equal("https://basin.tableland.xyz/vaults/any/events", o.endpoint)
+	})
+	t.Run("CustomEndpoint", func(t *testing.T) {
+		o, err := newTextileDBAdapter(TextileConfig{Endpoint: "http://localhost:8080/", VaultID: "any", PayloadExtractor: "halo2"}, testOperatorECDSA)
+		r.NoError(err)
+		r.Equal("http://localhost:8080/vaults/any/events", o.endpoint)
+	})
 }
@@ -0,0 +1,178 @@
+package output
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/machinefi/sprout/task"
+)
+
+const (
+	webhookSignatureHeader = "X-Sprout-Signature"
+	webhookMaxResponseSize = 4096
+)
+
+// webhookBody is the template data and the default request body of webhook output
+type webhookBody struct {
+	TaskID         uint64   `json:"taskID"`
+	ProjectID      uint64   `json:"projectID"`
+	ProjectVersion string   `json:"projectVersion"`
+	ClientID       string   `json:"clientID"`
+	Data           []string `json:"data"`
+	Proof          string   `json:"proof"`
+}
+
+type webhook struct {
+	url           string
+	headers       map[string]string
+	secret        []byte
+	tmpl          *template.Template // optional, the default body will be used if nil
+	maxRetries    int
+	retryBackoff  time.Duration
+	successStatus map[int]bool // optional, any 2xx status is success if empty
+	client        *http.Client
+}
+
+func (w *webhook) Output(task *task.Task, proof []byte) (string, error) {
+	slog.Debug("outputing to webhook", "url", w.url)
+	body, err := w.packBody(task, proof)
+	if err != nil {
+		return "", err
+	}
+
+	var res string
+	err = retry(w.maxRetries, w.retryBackoff, func() error {
+		res, err = w.post(body)
+		if err != nil {
+			slog.Debug("failed to post webhook", "url", w.url, "task_id", task.ID, "error", err)
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (w *webhook) packBody(t *task.Task, proof []byte) ([]byte, error) {
+	b := &webhookBody{
+		TaskID:         t.ID,
+		ProjectID:      t.ProjectID,
+		ProjectVersion: t.ProjectVersion,
+		ClientID:       t.ClientID,
+		Data:           make([]string, 0, len(t.Data)),
+		Proof:          string(proof),
+	}
+	for _, d := range t.Data {
+		b.Data = append(b.Data, string(d))
+	}
+
+	if w.tmpl == nil {
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal webhook body")
+		}
+		return data, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := w.tmpl.Execute(buf, b); err != nil {
+		return nil, errors.Wrap(err, "failed to execute webhook body template")
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("webhook body template produced invalid json")
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends the body to webhook url, and returns the response body
+func (w *webhook) post(body []byte) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseSize))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read response")
+	}
+
+	if !w.isSuccess(resp.StatusCode) {
+		return "", &httpStatusError{code: resp.StatusCode, body: string(content)}
+	}
+	return string(content), nil
+}
+
+func (w *webhook) isSuccess(status int) bool {
+	if len(w.successStatus) == 0 {
+		return status >= 200 && status < 300
+	}
+	return w.successStatus[status]
+}
+
+func newWebhook(conf WebhookConfig) (*webhook, error) {
+	if conf.URL == "" {
+		return nil, errors.New("webhook url is empty")
+	}
+
+	w := &webhook{
+		url:           conf.URL,
+		headers:       conf.Headers,
+		secret:        []byte(conf.Secret),
+		maxRetries:    defaultMaxRetries,
+		retryBackoff:  defaultRetryBackoff,
+		successStatus: map[int]bool{},
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+	if conf.BodyTemplate != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				data, err := json.Marshal(v)
+				return string(data), err
+			},
+		}).Parse(conf.BodyTemplate)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse webhook body template")
+		}
+		w.tmpl = tmpl
+	}
+	if conf.MaxRetries != nil {
+		w.maxRetries = *conf.MaxRetries
+	}
+	if conf.RetryBackoff != "" {
+		d, err := time.ParseDuration(conf.RetryBackoff)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse webhook retry backoff")
+		}
+		w.retryBackoff = d
+	}
+	for _, s := range conf.SuccessStatus {
+		w.successStatus[s] = true
+	}
+	return w, nil
+}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/machinefi/sprout/task"
+)
+
+func Test_webhook_Output(t *testing.T) {
+	r := require.New(t)
+
+	tsk := &task.Task{
+		ID:             1,
+		ProjectID:      2,
+		ProjectVersion: "0.1",
+		ClientID:       "any",
+		Data:           [][]byte{[]byte(`{"a":1}`)},
+	}
+	noRetry := 0
+
+	t.Run("DefaultBody", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			r.JSONEq(`{"taskID":1,"projectID":2,"projectVersion":"0.1","clientID":"any","data":["{\"a\":1}"],"proof":"proof"}`, string(body))
+			r.Equal("value", req.Header.Get("X-Any"))
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		o, err := New(&Config{Type: Webhook, Webhook: WebhookConfig{
+			URL:     srv.URL,
+			Headers: map[string]string{"X-Any": "value"},
+		}}, nil, nil, "")
+		r.NoError(err)
+		res, err := o.Output(tsk, []byte("proof"))
+		r.NoError(err)
+		r.Equal("ok", res)
+	})
+
+	t.Run("TemplateAndSignature", func(t *testing.T) {
+		secret := "secret"
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			r.JSONEq(`{"id":1,"proof":"proof"}`, string(body))
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			r.Equal("sha256="+hex.EncodeToString(mac.Sum(nil)), req.Header.Get(webhookSignatureHeader))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer srv.Close()
+
+		o, err := New(&Config{Type: Webhook, Webhook: WebhookConfig{
+			URL:          srv.URL,
+			Secret:       secret,
+			BodyTemplate: `{"id":{{.TaskID}},"proof":{{json .Proof}}}`,
+		}}, nil, nil, "")
+		r.NoError(err)
+		_, err = o.Output(tsk, []byte("proof"))
+		r.NoError(err)
+	})
+
+	t.Run("InvalidTemplateOutput", func(t *testing.T) {
+		o, err := New(&Config{Type: Webhook, Webhook: WebhookConfig{
+			URL:          "http://any",
+			BodyTemplate: `{"proof":{{.Proof}}}`,
+		}}, nil, nil, "")
+		r.NoError(err)
+		_, err = o.Output(tsk, []byte("proof"))
+		r.ErrorContains(err, "invalid json")
+	})
+
+	t.Run("RetryOnServerError", func(t *testing.T) {
+		calls := atomic.Int32{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		o, err := New(&Config{Type: Webhook, Webhook: WebhookConfig{
+			URL:          srv.URL,
+			RetryBackoff: "1ms",
+		}}, nil, nil, "")
+		r.NoError(err)
+		res, err := o.Output(tsk, []byte("proof"))
+		r.NoError(err)
+		r.Equal("ok", res)
+		r.Equal(int32(3), calls.Load())
+	})
+
+	t.Run("NoRetryOnClientError", func(t *testing.T) {
+		calls := atomic.Int32{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		o, err := New(&Config{Type: Webhook, Webhook: WebhookConfig{
+			URL:          srv.URL,
+			RetryBackoff: "1ms",
+		}}, nil, nil, "")
+		r.NoError(err)
+		_, err = o.Output(tsk, []byte("proof"))
+		r.ErrorContains(err, "status 400")
+		r.Equal(int32(1), calls.Load())
+	})
+
+	t.Run("SuccessStatus", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		o, err := New(&Config{Type: Webhook, Webhook: WebhookConfig{
+			URL:           srv.URL,
+			MaxRetries:    &noRetry,
+			SuccessStatus: []int{http.StatusCreated},
+		}}, nil, nil, "")
+		r.NoError(err)
+		_, err = o.Output(tsk, []byte("proof"))
+		r.ErrorContains(err, "status 200")
+	})
+}
+
+func Test_newWebhook(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("MissingURL", func(t *testing.T) {
+		_, err := newWebhook(WebhookConfig{})
+		r.Error(err)
+	})
+	t.Run("InvalidTemplate", func(t *testing.T) {
+		_, err := newWebhook(WebhookConfig{URL: "http://any", BodyTemplate: "{{"})
+		r.Error(err)
+	})
+	t.Run("InvalidRetryBackoff", func(t *testing.T) {
+		_, err := newWebhook(WebhookConfig{URL: "http://any", RetryBackoff: "any"})
+		r.Error(err)
+	})
+}
@@ -11,6 +11,14 @@ var (
 	RequiredProverAmount         = crypto.Keccak256Hash([]byte("RequiredProverAmount"))
 	VmType                       = crypto.Keccak256Hash([]byte("VmType"))
 	ClientManagementContractAddr = crypto.Keccak256Hash([]byte("ClientManagementContractAddress"))
+	OperatorSigner               = crypto.Keccak256Hash([]byte("OperatorSigner"))           // utf8 identity of the dedicated ecdsa operator key
+	OperatorSignerED25519        = crypto.Keccak256Hash([]byte("OperatorSignerED25519"))    // utf8 identity of the dedicated ed25519 operator key
+	AggregationAmount            = crypto.Keccak256Hash([]byte("AggregationAmount"))        // utf8 decimal
+	AggregationMaxLatency        = crypto.Keccak256Hash([]byte("AggregationMaxLatency"))    // utf8 duration, e.g. 30s
+	AggregationAcrossClients     = crypto.Keccak256Hash([]byte("AggregationAcrossClients")) // utf8 bool
+	RateLimit                    = crypto.Keccak256Hash([]byte("RateLimit"))                // utf8 decimal, messages per second
+	RateLimitBurst               = crypto.Keccak256Hash([]byte("RateLimitBurst"))           // utf8 decimal
+	DailyQuota                   = crypto.Keccak256Hash([]byte("DailyQuota"))               // utf8 decimal, messages per UTC day
 
 	attributeSetTopic         = crypto.Keccak256Hash([]byte("AttributeSet(uint256,bytes32,bytes)"))
 	projectPausedTopic        = crypto.Keccak256Hash([]byte("ProjectPaused(uint256)"))
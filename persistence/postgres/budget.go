@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/machinefi/sprout/budget"
+)
+
+type budgetRecord struct {
+	gorm.Model
+	ProjectID uint64 `gorm:"index:budget_record_fetch,not null"`
+	GasUsed   uint64 `gorm:"not null"`
+	Fee       string `gorm:"not null"` // decimal wei
+}
+
+type budgetState struct {
+	gorm.Model
+	ProjectID uint64 `gorm:"uniqueIndex:budget_state_project_id,not null"`
+	Paused    bool   `gorm:"not null"`
+	ResumedAt time.Time
+}
+
+func (p *Postgres) CreateBudgetRecord(r *budget.Record) error {
+	fee := "0"
+	if r.Fee != nil {
+		fee = r.Fee.String()
+	}
+	if err := p.db.Create(&budgetRecord{
+		ProjectID: r.ProjectID,
+		GasUsed:   r.GasUsed,
+		Fee:       fee,
+		Model: gorm.Model{
+			CreatedAt: r.CreatedAt,
+		},
+	}).Error; err != nil {
+		return errors.Wrapf(err, "failed to create budget record, project_id %v", r.ProjectID)
+	}
+	return nil
+}
+
+func (p *Postgres) BudgetRecords(projectID uint64, since time.Time) ([]*budget.Record, error) {
+	rs := []*budgetRecord{}
+	if err := p.db.Where("project_id = ? AND created_at > ?", projectID, since).Order("created_at").Find(&rs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to query budget records, project_id %v", projectID)
+	}
+	brs := make([]*budget.Record, 0, len(rs))
+	for _, r := range rs {
+		fee, ok := new(big.Int).SetString(r.Fee, 10)
+		if !ok {
+			return nil, errors.Errorf("invalid budget record fee %s, project_id %v", r.Fee, projectID)
+		}
+		brs = append(brs, &budget.Record{
+			ProjectID: r.ProjectID,
+			GasUsed:   r.GasUsed,
+			Fee:       fee,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return brs, nil
+}
+
+func (p *Postgres) BudgetState(projectID uint64) (*budget.State, error) {
+	s := budgetState{}
+	if err := p.db.Where("project_id = ?", projectID).First(&s).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to query budget state, project_id %v", projectID)
+	}
+	return &budget.State{ProjectID: s.ProjectID, Paused: s.Paused, ResumedAt: s.ResumedAt}, nil
+}
+
+func (p *Postgres) UpsertBudgetState(s *budget.State) error {
+	if err := p.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"paused", "resumed_at", "updated_at"}),
+	}).Create(&budgetState{
+		ProjectID: s.ProjectID,
+		Paused:    s.Paused,
+		ResumedAt: s.ResumedAt,
+	}).Error; err != nil {
+		return errors.Wrapf(err, "failed to upsert budget state, project_id %v", s.ProjectID)
+	}
+	return nil
+}
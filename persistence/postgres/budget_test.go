@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/machinefi/sprout/budget"
+)
+
+func TestPostgres_Budget(t *testing.T) {
+	r := require.New(t)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "coordinator.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	r.NoError(err)
+	r.NoError(db.AutoMigrate(&budgetRecord{}, &budgetState{}))
+	p := &Postgres{db: db}
+
+	now := time.Now().UTC()
+	t.Run("Records", func(t *testing.T) {
+		r.NoError(p.CreateBudgetRecord(&budget.Record{ProjectID: 1, GasUsed: 1, Fee: big.NewInt(10), CreatedAt: now.Add(-2 * time.Hour)}))
+		r.NoError(p.CreateBudgetRecord(&budget.Record{ProjectID: 1, GasUsed: 2, CreatedAt: now}))
+		r.NoError(p.CreateBudgetRecord(&budget.Record{ProjectID: 2, GasUsed: 3, Fee: big.NewInt(30), CreatedAt: now}))
+
+		rs, err := p.BudgetRecords(1, now.Add(-time.Hour))
+		r.NoError(err)
+		r.Len(rs, 1)
+		r.Equal(uint64(2), rs[0].GasUsed)
+		r.Equal(big.NewInt(0), rs[0].Fee)
+
+		rs, err = p.BudgetRecords(1, time.Time{})
+		r.NoError(err)
+		r.Len(rs, 2)
+		r.Equal(big.NewInt(10), rs[0].Fee)
+	})
+	t.Run("State", func(t *testing.T) {
+		s, err := p.BudgetState(1)
+		r.NoError(err)
+		r.Nil(s)
+
+		r.NoError(p.UpsertBudgetState(&budget.State{ProjectID: 1, Paused: true}))
+		s, err = p.BudgetState(1)
+		r.NoError(err)
+		r.True(s.Paused)
+
+		r.NoError(p.UpsertBudgetState(&budget.State{ProjectID: 1, ResumedAt: now}))
+		s, err = p.BudgetState(1)
+		r.NoError(err)
+		r.False(s.Paused)
+		r.True(now.Equal(s.ResumedAt))
+	})
+}
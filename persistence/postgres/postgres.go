@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"github.com/machinefi/sprout/task"
+	"github.com/machinefi/sprout/util/broker"
 )
 
 type projectProcessedTask struct {
@@ -26,8 +27,15 @@ type taskStateLog struct {
 	Result         []byte
 }
 
+// stateKey is the key of task state log subscriptions
+type stateKey struct {
+	projectID uint64
+	taskID    uint64
+}
+
 type Postgres struct {
-	db *gorm.DB
+	db     *gorm.DB
+	states *broker.Broker[stateKey, *task.StateLog]
 }
 
 func (p *Postgres) ProcessedTaskID(projectID uint64) (uint64, error) {
@@ -70,9 +78,24 @@ func (p *Postgres) Create(tl *task.StateLog, t *task.Task) error {
 	if err := p.db.Create(l).Error; err != nil {
 		return errors.Wrap(err, "failed to create task state log")
 	}
+	if p.states != nil {
+		p.states.Publish(stateKey{projectID: t.ProjectID, taskID: tl.TaskID}, &task.StateLog{
+			TaskID:    tl.TaskID,
+			State:     l.State,
+			Comment:   l.Comment,
+			Result:    l.Result,
+			CreatedAt: l.CreatedAt,
+		})
+	}
 	return nil
 }
 
+// Subscribe returns the channel of task state logs created after subscribing, and the func to cancel the
+// subscription. the channel is closed if the subscriber could not keep up
+func (p *Postgres) Subscribe(taskID, projectID uint64) (<-chan *task.StateLog, func()) {
+	return p.states.Subscribe(stateKey{projectID: projectID, taskID: taskID})
+}
+
 func (p *Postgres) Fetch(taskID, projectID uint64) ([]*task.StateLog, error) {
 	ls := []*taskStateLog{}
 	if err := p.db.Order("created_at").Where("task_id = ? AND project_id = ?", taskID, projectID).Find(&ls).Error; err != nil {
@@ -98,8 +121,8 @@ func New(pgEndpoint string) (*Postgres, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to connect postgres")
 	}
-	if err := db.AutoMigrate(&taskStateLog{}, &projectProcessedTask{}); err != nil {
+	if err := db.AutoMigrate(&taskStateLog{}, &projectProcessedTask{}, &budgetRecord{}, &budgetState{}); err != nil {
 		return nil, errors.Wrap(err, "failed to migrate model")
 	}
-	return &Postgres{db}, nil
+	return &Postgres{db: db, states: broker.New[stateKey, *task.StateLog](16)}, nil
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/machinefi/sprout/task"
 	"github.com/machinefi/sprout/testutil"
+	"github.com/machinefi/sprout/util/broker"
 )
 
 func TestPostgres_ProcessedTaskID(t *testing.T) {
@@ -108,6 +109,18 @@ func TestPostgres_Create(t *testing.T) {
 		err := v.Create(&task.StateLog{}, &task.Task{})
 		r.NoError(err)
 	})
+
+	t.Run("Publish", func(t *testing.T) {
+		v := &Postgres{db: db, states: broker.New[stateKey, *task.StateLog](1)}
+		ch, cancel := v.Subscribe(2, 1)
+		defer cancel()
+
+		err := v.Create(&task.StateLog{TaskID: 2, State: task.StateProved}, &task.Task{ProjectID: 1})
+		r.NoError(err)
+		l := <-ch
+		r.Equal(task.StateProved, l.State)
+		r.Equal(uint64(2), l.TaskID)
+	})
 }
 
 func TestPostgres_Fetch(t *testing.T) {
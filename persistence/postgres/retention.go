@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+
+	"github.com/machinefi/sprout/util/archive"
+)
+
+// ArchiveComponent is the archive name prefix of the coordinator task state logs
+const ArchiveComponent = "coordinator"
+
+// expiredTaskIDs returns the oldest processed tasks of the project whose latest state log is created before the
+// deadline, the state logs of the tasks not processed yet are never expired
+func (p *Postgres) expiredTaskIDs(projectID, processedTaskID uint64, before time.Time, limit int) ([]uint64, error) {
+	ids := []uint64{}
+	if err := p.db.Model(&taskStateLog{}).
+		Where("project_id = ? AND task_id <= ?", projectID, processedTaskID).
+		Group("task_id").
+		Having("MAX(created_at) < ?", before.UTC()).
+		Order("task_id").Limit(limit).
+		Pluck("task_id", &ids).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch expired tasks of project %d", projectID)
+	}
+	return ids, nil
+}
+
+// Archive archives the state logs of the processed tasks of the project expired before the deadline to the store, and
+// purges them in batches. the processed task id is kept, so the dispatcher continues from it after a purge. returns
+// the number of purged tasks
+func (p *Postgres) Archive(store archive.Store, projectID uint64, before time.Time, batchSize int) (int, error) {
+	processedTaskID, err := p.ProcessedTaskID(projectID)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for {
+		ids, err := p.expiredTaskIDs(projectID, processedTaskID, before, batchSize)
+		if err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+		ls := []*taskStateLog{}
+		if err := p.db.Where("project_id = ? AND task_id IN ?", projectID, ids).Order("id").Find(&ls).Error; err != nil {
+			return purged, errors.Wrapf(err, "failed to fetch expired task state logs of project %d", projectID)
+		}
+		data, err := archive.Encode(ls)
+		if err != nil {
+			return purged, err
+		}
+		if err := store.Put(archive.Name(ArchiveComponent, projectID, ids[0], ids[len(ids)-1]), data); err != nil {
+			return purged, err
+		}
+		if err := p.db.Unscoped().Where("project_id = ? AND task_id IN ?", projectID, ids).Delete(&taskStateLog{}).Error; err != nil {
+			return purged, errors.Wrapf(err, "failed to delete expired task state logs of project %d", projectID)
+		}
+		purged += len(ids)
+	}
+}
+
+// RunRetention archives and purges the expired task state logs of every project periodically, this func will block
+// caller
+func (p *Postgres) RunRetention(interval time.Duration, retention *archive.Retention, store archive.Store, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ts := []*projectProcessedTask{}
+		if err := p.db.Find(&ts).Error; err != nil {
+			slog.Error("failed to fetch project processed tasks", "error", err)
+			continue
+		}
+		for _, t := range ts {
+			d := retention.Of(t.ProjectID)
+			if d <= 0 {
+				continue
+			}
+			purged, err := p.Archive(store, t.ProjectID, time.Now().Add(-d), batchSize)
+			if err != nil {
+				slog.Error("failed to archive expired task state logs", "project_id", t.ProjectID, "error", err)
+			}
+			if purged > 0 {
+				slog.Info("expired task state logs archived", "project_id", t.ProjectID, "tasks", purged)
+			}
+		}
+	}
+}
+
+// Restore reloads the archived state logs of the project tasks from first to last, and returns the number of restored
+// state logs. the state logs existing are kept, and the processed task id is not changed
+func (p *Postgres) Restore(store archive.Store, projectID, first, last uint64) (int, error) {
+	names, err := archive.Find(store, ArchiveComponent, projectID, first, last)
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, n := range names {
+		data, err := store.Get(n)
+		if err != nil {
+			return restored, err
+		}
+		ls, err := archive.Decode[*taskStateLog](data)
+		if err != nil {
+			return restored, errors.Wrapf(err, "invalid archive %s", n)
+		}
+		ranged := make([]*taskStateLog, 0, len(ls))
+		for _, l := range ls {
+			if l.TaskID >= first && l.TaskID <= last {
+				ranged = append(ranged, l)
+			}
+		}
+		if len(ranged) == 0 {
+			continue
+		}
+		res := p.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ranged)
+		if err := res.Error; err != nil {
+			return restored, errors.Wrapf(err, "failed to restore archive %s", n)
+		}
+		restored += int(res.RowsAffected)
+	}
+	return restored, nil
+}
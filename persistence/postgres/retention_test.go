@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/machinefi/sprout/task"
+	"github.com/machinefi/sprout/util/archive"
+)
+
+func TestPostgres_Archive(t *testing.T) {
+	r := require.New(t)
+
+	// the embedded sqlite runs the same sql
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "coordinator.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	r.NoError(err)
+	r.NoError(db.AutoMigrate(&taskStateLog{}, &projectProcessedTask{}))
+	v := &Postgres{db: db}
+
+	expired := time.Now().Add(-48 * time.Hour).UTC()
+	for taskID := uint64(1); taskID <= 4; taskID++ {
+		for _, s := range []task.State{task.StatePacked, task.StateOutputted} {
+			r.NoError(v.Create(&task.StateLog{TaskID: taskID, State: s, CreatedAt: expired}, &task.Task{ProjectID: 1, ProjectVersion: "0.1"}))
+		}
+	}
+	// the task 3 is updated recently
+	r.NoError(v.Create(&task.StateLog{TaskID: 3, State: task.StateFailed, CreatedAt: time.Now().UTC()}, &task.Task{ProjectID: 1, ProjectVersion: "0.1"}))
+	// the task 4 is not processed yet
+	r.NoError(v.UpsertProcessedTask(1, 3))
+
+	store, err := archive.NewStore(t.TempDir())
+	r.NoError(err)
+
+	t.Run("Archive", func(t *testing.T) {
+		purged, err := v.Archive(store, 1, time.Now().Add(-24*time.Hour), 1)
+		r.NoError(err)
+		r.Equal(2, purged)
+
+		names, err := store.List(ArchiveComponent + "/1/")
+		r.NoError(err)
+		r.Equal([]string{archive.Name(ArchiveComponent, 1, 1, 1), archive.Name(ArchiveComponent, 1, 2, 2)}, names)
+
+		for taskID, n := range map[uint64]int{1: 0, 2: 0, 3: 3, 4: 2} {
+			ls, err := v.Fetch(taskID, 1)
+			r.NoError(err)
+			r.Len(ls, n)
+		}
+		processed, err := v.ProcessedTaskID(1)
+		r.NoError(err)
+		r.Equal(uint64(3), processed)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		restored, err := v.Restore(store, 1, 2, 10)
+		r.NoError(err)
+		r.Equal(2, restored)
+
+		ls, err := v.Fetch(2, 1)
+		r.NoError(err)
+		r.Len(ls, 2)
+		r.Equal(task.StatePacked, ls[0].State)
+
+		// the state logs restored already are kept
+		restored, err = v.Restore(store, 1, 1, 2)
+		r.NoError(err)
+		r.Equal(2, restored)
+		ls, err = v.Fetch(2, 1)
+		r.NoError(err)
+		r.Len(ls, 2)
+	})
+}
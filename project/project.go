@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -17,15 +18,24 @@ import (
 )
 
 var (
-	errEmptyConfig       = errors.New("config is empty")
-	errEmptyCode         = errors.New("code is empty")
-	errUnsupportedVMType = errors.New("unsupported vm type")
+	errInvalidAggregation = errors.New("invalid aggregation")
+	errEmptyConfig        = errors.New("config is empty")
+	errEmptyCode          = errors.New("code is empty")
+	errUnsupportedVMType  = errors.New("unsupported vm type")
 )
 
 type Project struct {
-	DatasourceURI  string    `json:"datasourceURI,omitempty"`
-	DefaultVersion string    `json:"defaultVersion"`
-	Versions       []*Config `json:"versions"`
+	DatasourceURI  string       `json:"datasourceURI,omitempty"`
+	DefaultVersion string       `json:"defaultVersion"`
+	Versions       []*Config    `json:"versions"`
+	Aggregation    *Aggregation `json:"aggregation,omitempty"`
+}
+
+// Aggregation is the strategy of packing project messages into tasks, the zero value fields fall back to the sequencer
+type Aggregation struct {
+	Amount        uint   `json:"amount,omitempty"`
+	MaxLatency    string `json:"maxLatency,omitempty"` // e.g. 30s
+	AcrossClients bool   `json:"acrossClients,omitempty"`
 }
 
 type Meta struct {
@@ -40,11 +50,12 @@ type Attribute struct {
 }
 
 type Config struct {
-	Version       string        `json:"version"`
-	VMType        vm.Type       `json:"vmType"`
-	Output        output.Config `json:"output"`
-	CodeExpParams []string      `json:"codeExpParams,omitempty"`
-	Code          string        `json:"code"`
+	Version       string         `json:"version"`
+	VMType        vm.Type        `json:"vmType"`
+	Output        output.Config  `json:"output"`
+	CodeExpParams []string       `json:"codeExpParams,omitempty"`
+	Code          string         `json:"code"`
+	MessageSchema *MessageSchema `json:"messageSchema,omitempty"` // validated by the sequencer if declared
 }
 
 func (p *Project) Config(version string) (*Config, error) {
@@ -64,6 +75,11 @@ func (c *Config) validate() error {
 	if len(c.Code) == 0 {
 		return errEmptyCode
 	}
+	if c.MessageSchema != nil {
+		if err := c.MessageSchema.validate(); err != nil {
+			return err
+		}
+	}
 	switch c.VMType {
 	default:
 		return errUnsupportedVMType
@@ -72,6 +88,16 @@ func (c *Config) validate() error {
 	}
 }
 
+func (a *Aggregation) validate() error {
+	if a.MaxLatency == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(a.MaxLatency); err != nil || d < 0 {
+		return errors.Wrapf(errInvalidAggregation, "max latency %s", a.MaxLatency)
+	}
+	return nil
+}
+
 func (m *Meta) FetchProjectRawData(ipfsEndpoint string) ([]byte, error) {
 	u, err := url.Parse(m.Uri)
 	if err != nil {
@@ -126,6 +152,11 @@ func convertProject(projectRawData []byte) (*Project, error) {
 	if len(p.Versions) == 0 {
 		return nil, errEmptyConfig
 	}
+	if p.Aggregation != nil {
+		if err := p.Aggregation.validate(); err != nil {
+			return nil, err
+		}
+	}
 	for _, c := range p.Versions {
 		if err := c.validate(); err != nil {
 			return nil, err
@@ -155,6 +155,13 @@ func TestConfig_Validate(t *testing.T) {
 		r.EqualError(err, errUnsupportedVMType.Error())
 	})
 
+	t.Run("InvalidMessageSchema", func(t *testing.T) {
+		c := *config
+		c.MessageSchema = &MessageSchema{Fields: map[string]*Field{"a": {Type: "any"}}}
+		err := c.validate()
+		r.ErrorIs(err, errInvalidMessageSchema)
+	})
+
 	t.Run("Success", func(t *testing.T) {
 		err := config.validate()
 		r.NoError(err)
@@ -181,4 +188,14 @@ func TestConvertProject(t *testing.T) {
 		_, err := convertProject(nil)
 		r.ErrorContains(err, errEmptyConfig.Error())
 	})
+	t.Run("InvalidAggregation", func(t *testing.T) {
+		_, err := convertProject([]byte(`{"versions":[{}],"aggregation":{"maxLatency":"any"}}`))
+		r.ErrorIs(err, errInvalidAggregation)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p, err := convertProject([]byte(`{"versions":[{"code":"any","vmType":"risc0"}],"aggregation":{"amount":10,"maxLatency":"30s","acrossClients":true}}`))
+		r.NoError(err)
+		r.Equal(&Aggregation{Amount: 10, MaxLatency: "30s", AcrossClients: true}, p.Aggregation)
+	})
 }
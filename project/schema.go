@@ -0,0 +1,222 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidMessageSchema = errors.New("invalid message schema")
+
+type FieldType string
+
+const (
+	FieldString  FieldType = "string"
+	FieldNumber  FieldType = "number"
+	FieldInteger FieldType = "integer"
+	FieldBoolean FieldType = "boolean"
+	FieldArray   FieldType = "array"
+	FieldObject  FieldType = "object"
+)
+
+// MessageSchema is the typed field spec of message data, the data must be a json object
+type MessageSchema struct {
+	Fields             map[string]*Field `json:"fields"`
+	AllowUnknownFields bool              `json:"allowUnknownFields,omitempty"`
+}
+
+// Field is the spec of a message data field, min and max limit the value of number and integer, and the length of
+// string and array
+type Field struct {
+	Type     FieldType         `json:"type"`
+	Required bool              `json:"required,omitempty"`
+	Min      *float64          `json:"min,omitempty"`
+	Max      *float64          `json:"max,omitempty"`
+	Enum     []string          `json:"enum,omitempty"`  // the allowed values of string
+	Items    *Field            `json:"items,omitempty"` // the element of array
+	Fields   map[string]*Field `json:"fields,omitempty"`
+}
+
+// FieldError is the validation error of a field, the field is a path like `a.b[0]`
+type FieldError struct {
+	Field string
+	Error string
+}
+
+func (e *FieldError) String() string {
+	if e.Field == "" {
+		return e.Error
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Error)
+}
+
+func (s *MessageSchema) validate() error {
+	for name, f := range s.Fields {
+		if err := f.validate(); err != nil {
+			return errors.Wrapf(err, "field %s", name)
+		}
+	}
+	return nil
+}
+
+func (f *Field) validate() error {
+	if f == nil {
+		return errInvalidMessageSchema
+	}
+	switch f.Type {
+	case FieldString, FieldNumber, FieldInteger, FieldBoolean:
+	case FieldArray:
+		if f.Items == nil {
+			return errors.Wrap(errInvalidMessageSchema, "array items is required")
+		}
+		if err := f.Items.validate(); err != nil {
+			return errors.Wrap(err, "items")
+		}
+	case FieldObject:
+		for name, sub := range f.Fields {
+			if err := sub.validate(); err != nil {
+				return errors.Wrapf(err, "field %s", name)
+			}
+		}
+	default:
+		return errors.Wrapf(errInvalidMessageSchema, "unsupported field type %s", f.Type)
+	}
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		return errors.Wrap(errInvalidMessageSchema, "min is greater than max")
+	}
+	return nil
+}
+
+// Validate validates the message data, and returns the field errors sorted by field
+func (s *MessageSchema) Validate(data []byte) []*FieldError {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return []*FieldError{{Error: "data is not valid json"}}
+	}
+	// the data should be exactly one json value
+	if _, err := d.Token(); err != io.EOF {
+		return []*FieldError{{Error: "data is not valid json"}}
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return []*FieldError{{Error: "data is not a json object"}}
+	}
+	return validateFields("", obj, s.Fields, s.AllowUnknownFields)
+}
+
+func validateFields(path string, obj map[string]any, fields map[string]*Field, allowUnknown bool) []*FieldError {
+	errs := []*FieldError{}
+	for _, name := range sortedKeys(fields) {
+		v, ok := obj[name]
+		if !ok {
+			if fields[name].Required {
+				errs = append(errs, &FieldError{Field: join(path, name), Error: "is required"})
+			}
+			continue
+		}
+		errs = append(errs, fields[name].validateValue(join(path, name), v)...)
+	}
+	if allowUnknown {
+		return errs
+	}
+	for _, name := range sortedKeys(obj) {
+		if _, ok := fields[name]; !ok {
+			errs = append(errs, &FieldError{Field: join(path, name), Error: "is unknown"})
+		}
+	}
+	return errs
+}
+
+func (f *Field) validateValue(path string, v any) []*FieldError {
+	invalid := func(format string, args ...any) []*FieldError {
+		return []*FieldError{{Field: path, Error: fmt.Sprintf(format, args...)}}
+	}
+
+	switch f.Type {
+	case FieldString:
+		s, ok := v.(string)
+		if !ok {
+			return invalid("expected string")
+		}
+		if err := f.checkRange("length", float64(utf8.RuneCountInString(s))); err != "" {
+			return invalid(err)
+		}
+		if len(f.Enum) > 0 && !slices.Contains(f.Enum, s) {
+			return invalid("expected one of %v", f.Enum)
+		}
+	case FieldNumber, FieldInteger:
+		n, ok := v.(json.Number)
+		if !ok {
+			return invalid("expected %s", f.Type)
+		}
+		x, err := n.Float64()
+		if err != nil {
+			return invalid("expected %s", f.Type)
+		}
+		if f.Type == FieldInteger && x != math.Trunc(x) {
+			return invalid("expected integer")
+		}
+		if err := f.checkRange("value", x); err != "" {
+			return invalid(err)
+		}
+	case FieldBoolean:
+		if _, ok := v.(bool); !ok {
+			return invalid("expected boolean")
+		}
+	case FieldArray:
+		a, ok := v.([]any)
+		if !ok {
+			return invalid("expected array")
+		}
+		if err := f.checkRange("length", float64(len(a))); err != "" {
+			return invalid(err)
+		}
+		errs := []*FieldError{}
+		for i, e := range a {
+			errs = append(errs, f.Items.validateValue(fmt.Sprintf("%s[%d]", path, i), e)...)
+		}
+		return errs
+	case FieldObject:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return invalid("expected object")
+		}
+		// an object without declared fields accepts any fields
+		return validateFields(path, obj, f.Fields, len(f.Fields) == 0)
+	}
+	return nil
+}
+
+func (f *Field) checkRange(name string, x float64) string {
+	if f.Min != nil && x < *f.Min {
+		return fmt.Sprintf("%s is less than %v", name, *f.Min)
+	}
+	if f.Max != nil && x > *f.Max {
+		return fmt.Sprintf("%s is greater than %v", name, *f.Max)
+	}
+	return ""
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
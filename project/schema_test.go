@@ -0,0 +1,139 @@
+package project
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSchema_validate(t *testing.T) {
+	r := require.New(t)
+
+	lo, hi := 10.0, 1.0
+
+	t.Run("NilField", func(t *testing.T) {
+		s := &MessageSchema{Fields: map[string]*Field{"a": nil}}
+		r.ErrorIs(s.validate(), errInvalidMessageSchema)
+	})
+
+	t.Run("UnsupportedType", func(t *testing.T) {
+		s := &MessageSchema{Fields: map[string]*Field{"a": {Type: "any"}}}
+		r.ErrorIs(s.validate(), errInvalidMessageSchema)
+	})
+
+	t.Run("ArrayWithoutItems", func(t *testing.T) {
+		s := &MessageSchema{Fields: map[string]*Field{"a": {Type: FieldArray}}}
+		r.ErrorContains(s.validate(), "array items is required")
+	})
+
+	t.Run("InvalidNestedField", func(t *testing.T) {
+		s := &MessageSchema{Fields: map[string]*Field{"a": {Type: FieldObject, Fields: map[string]*Field{"b": {Type: "any"}}}}}
+		r.ErrorContains(s.validate(), "field a: field b")
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		s := &MessageSchema{Fields: map[string]*Field{"a": {Type: FieldNumber, Min: &lo, Max: &hi}}}
+		r.ErrorContains(s.validate(), "min is greater than max")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		s := &MessageSchema{Fields: map[string]*Field{
+			"a": {Type: FieldArray, Items: &Field{Type: FieldString}},
+			"b": {Type: FieldObject},
+		}}
+		r.NoError(s.validate())
+	})
+}
+
+func TestMessageSchema_Validate(t *testing.T) {
+	r := require.New(t)
+
+	s := &MessageSchema{}
+	r.NoError(json.Unmarshal([]byte(`{
+		"fields": {
+			"name":        {"type": "string", "required": true, "max": 3},
+			"level":       {"type": "string", "enum": ["low", "high"]},
+			"count":       {"type": "integer", "min": 0},
+			"temperature": {"type": "number"},
+			"enabled":     {"type": "boolean"},
+			"tags":        {"type": "array", "max": 2, "items": {"type": "string"}},
+			"location":    {"type": "object", "fields": {"lat": {"type": "number", "required": true}}},
+			"extra":       {"type": "object"}
+		}
+	}`), s))
+	r.NoError(s.validate())
+
+	t.Run("InvalidJson", func(t *testing.T) {
+		r.Equal([]*FieldError{{Error: "data is not valid json"}}, s.Validate([]byte("{")))
+	})
+
+	t.Run("TrailingData", func(t *testing.T) {
+		for _, data := range []string{`{"name": "a"}garbage`, `{"name": "a"}{"x": 2}`, `{"name": "a"} 1`} {
+			r.Equal([]*FieldError{{Error: "data is not valid json"}}, s.Validate([]byte(data)), data)
+		}
+		r.Empty(s.Validate([]byte(`{"name": "a"}` + " \n")))
+	})
+
+	t.Run("NotObject", func(t *testing.T) {
+		r.Equal([]*FieldError{{Error: "data is not a json object"}}, s.Validate([]byte("[]")))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		errs := s.Validate([]byte(`{
+			"level": "middle",
+			"count": 1.5,
+			"temperature": "hot",
+			"enabled": 1,
+			"tags": ["a", 1],
+			"location": {"lon": 1},
+			"unknown": true
+		}`))
+		r.Equal([]*FieldError{
+			{Field: "count", Error: "expected integer"},
+			{Field: "enabled", Error: "expected boolean"},
+			{Field: "level", Error: "expected one of [low high]"},
+			{Field: "location.lat", Error: "is required"},
+			{Field: "location.lon", Error: "is unknown"},
+			{Field: "name", Error: "is required"},
+			{Field: "tags[1]", Error: "expected string"},
+			{Field: "temperature", Error: "expected number"},
+			{Field: "unknown", Error: "is unknown"},
+		}, errs)
+	})
+
+	t.Run("OutOfRange", func(t *testing.T) {
+		errs := s.Validate([]byte(`{"name": "long", "count": -1, "tags": ["a", "b", "c"]}`))
+		r.Equal([]*FieldError{
+			{Field: "count", Error: "value is less than 0"},
+			{Field: "name", Error: "length is greater than 3"},
+			{Field: "tags", Error: "length is greater than 2"},
+		}, errs)
+	})
+
+	t.Run("AllowUnknownFields", func(t *testing.T) {
+		s := &MessageSchema{AllowUnknownFields: true}
+		r.Empty(s.Validate([]byte(`{"unknown": true}`)))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		errs := s.Validate([]byte(`{
+			"name": "abc",
+			"level": "low",
+			"count": 2,
+			"temperature": 1.5,
+			"enabled": true,
+			"tags": ["a"],
+			"location": {"lat": 1},
+			"extra": {"any": [1]}
+		}`))
+		r.Empty(errs)
+	})
+}
+
+func TestFieldError_String(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("a.b: is required", (&FieldError{Field: "a.b", Error: "is required"}).String())
+	r.Equal("data is not a json object", (&FieldError{Error: "data is not a json object"}).String())
+}
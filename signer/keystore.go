@@ -0,0 +1,138 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	solcommon "github.com/blocto/solana-go-sdk/common"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// NewKeystoreECDSA decrypts a go-ethereum encrypted keystore file
+func NewKeystoreECDSA(path, passphrase string) (ECDSA, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read keystore file %s", path)
+	}
+	key, err := keystore.DecryptKey(content, passphrase)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt keystore file %s", path)
+	}
+	return &rawECDSA{sk: key.PrivateKey}, nil
+}
+
+// ed25519KeyJSON is the keystore file of an ed25519 key, the private key is encrypted the same way as go-ethereum
+// keystore. the public key is base58 encoded, which is the solana account address
+type ed25519KeyJSON struct {
+	PublicKey string              `json:"publicKey"`
+	Crypto    keystore.CryptoJSON `json:"crypto"`
+}
+
+// EncryptED25519Key encrypts the ed25519 private key into keystore file content
+func EncryptED25519Key(sk ed25519.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	c, err := keystore.EncryptDataV3(sk, []byte(passphrase), scryptN, scryptP)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt ed25519 key")
+	}
+	return json.Marshal(&ed25519KeyJSON{
+		PublicKey: solcommon.PublicKeyFromBytes(sk.Public().(ed25519.PublicKey)).String(),
+		Crypto:    c,
+	})
+}
+
+// NewKeystoreED25519 decrypts an ed25519 keystore file
+func NewKeystoreED25519(path, passphrase string) (ED25519, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read keystore file %s", path)
+	}
+	k := &ed25519KeyJSON{}
+	if err := json.Unmarshal(content, k); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode keystore file %s", path)
+	}
+	b, err := keystore.DecryptDataV3(k.Crypto, passphrase)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt keystore file %s", path)
+	}
+	if len(b) != ed25519.PrivateKeySize {
+		return nil, errors.Errorf("invalid ed25519 private key length %d in keystore file %s", len(b), path)
+	}
+	return &rawED25519{sk: ed25519.PrivateKey(b)}, nil
+}
+
+// KeystoreDir resolves keys from the keystore files in a directory, an ecdsa key is identified by its address and
+// an ed25519 key is identified by its base58 public key. all files share the same passphrase
+type KeystoreDir struct {
+	dir        string
+	passphrase string
+}
+
+// keystoreFiles scans the directory, and returns the identities of ecdsa and ed25519 keys mapped to file path
+func (d *KeystoreDir) keystoreFiles() (map[common.Address]string, map[string]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read keystore directory %s", d.dir)
+	}
+	ecdsaFiles := map[common.Address]string{}
+	ed25519Files := map[string]string{}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(d.dir, e.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to read keystore file %s", path)
+		}
+		k := &struct {
+			Address   string `json:"address"`
+			PublicKey string `json:"publicKey"`
+		}{}
+		if err := json.Unmarshal(content, k); err != nil {
+			continue
+		}
+		switch {
+		case k.Address != "":
+			ecdsaFiles[common.HexToAddress(k.Address)] = path
+		case k.PublicKey != "":
+			ed25519Files[k.PublicKey] = path
+		}
+	}
+	return ecdsaFiles, ed25519Files, nil
+}
+
+func (d *KeystoreDir) ResolveECDSA(id string) (ECDSA, error) {
+	if !common.IsHexAddress(id) {
+		return nil, errors.Errorf("invalid ecdsa key identity %s, should be an address", id)
+	}
+	files, _, err := d.keystoreFiles()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := files[common.HexToAddress(id)]
+	if !ok {
+		return nil, errors.Errorf("ecdsa key %s not found in keystore directory %s", id, d.dir)
+	}
+	return NewKeystoreECDSA(path, d.passphrase)
+}
+
+func (d *KeystoreDir) ResolveED25519(id string) (ED25519, error) {
+	_, files, err := d.keystoreFiles()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := files[id]
+	if !ok {
+		return nil, errors.Errorf("ed25519 key %s not found in keystore directory %s", id, d.dir)
+	}
+	return NewKeystoreED25519(path, d.passphrase)
+}
+
+func NewKeystoreDir(dir, passphrase string) *KeystoreDir {
+	return &KeystoreDir{dir: dir, passphrase: passphrase}
+}
@@ -0,0 +1,97 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	solcommon "github.com/blocto/solana-go-sdk/common"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeystoreECDSA(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+	key := &keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(sk.PublicKey),
+		PrivateKey: sk,
+	}
+	content, err := keystore.EncryptKey(key, "passphrase", keystore.LightScryptN, keystore.LightScryptP)
+	r.NoError(err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	r.NoError(os.WriteFile(path, content, 0600))
+
+	t.Run("FileNotExist", func(t *testing.T) {
+		_, err := NewKeystoreECDSA(filepath.Join(t.TempDir(), "any"), "passphrase")
+		r.Error(err)
+	})
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		_, err := NewKeystoreECDSA(path, "any")
+		r.ErrorContains(err, "failed to decrypt")
+	})
+	t.Run("Success", func(t *testing.T) {
+		s, err := NewKeystoreECDSA(path, "passphrase")
+		r.NoError(err)
+		r.Equal(key.Address, s.Address())
+	})
+}
+
+func TestKeystoreDir(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+	content, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(sk.PublicKey),
+		PrivateKey: sk,
+	}, "passphrase", keystore.LightScryptN, keystore.LightScryptP)
+	r.NoError(err)
+	r.NoError(os.WriteFile(filepath.Join(dir, "ecdsa.json"), content, 0600))
+
+	_, edSK, err := ed25519.GenerateKey(nil)
+	r.NoError(err)
+	content, err = EncryptED25519Key(edSK, "passphrase", keystore.LightScryptN, keystore.LightScryptP)
+	r.NoError(err)
+	r.NoError(os.WriteFile(filepath.Join(dir, "ed25519.json"), content, 0600))
+	r.NoError(os.WriteFile(filepath.Join(dir, "README"), []byte("not a keystore file"), 0600))
+
+	d := NewKeystoreDir(dir, "passphrase")
+
+	t.Run("InvalidECDSAIdentity", func(t *testing.T) {
+		_, err := d.ResolveECDSA("any")
+		r.ErrorContains(err, "should be an address")
+	})
+	t.Run("ECDSANotFound", func(t *testing.T) {
+		_, err := d.ResolveECDSA("0x0000000000000000000000000000000000000001")
+		r.ErrorContains(err, "not found")
+	})
+	t.Run("ED25519NotFound", func(t *testing.T) {
+		_, err := d.ResolveED25519("any")
+		r.ErrorContains(err, "not found")
+	})
+	t.Run("DirectoryNotExist", func(t *testing.T) {
+		_, err := NewKeystoreDir(filepath.Join(dir, "any"), "").ResolveED25519("any")
+		r.ErrorContains(err, "failed to read keystore directory")
+	})
+	t.Run("Success", func(t *testing.T) {
+		address := crypto.PubkeyToAddress(sk.PublicKey)
+		s, err := d.ResolveECDSA(strings.ToLower(address.Hex()))
+		r.NoError(err)
+		r.Equal(address, s.Address())
+
+		pub := edSK.Public().(ed25519.PublicKey)
+		es, err := d.ResolveED25519(solcommon.PublicKeyFromBytes(pub).String())
+		r.NoError(err)
+		r.Equal(pub, es.PublicKey())
+	})
+}
@@ -0,0 +1,184 @@
+package signer
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Identity identifies the dedicated operator keys of a project, the meaning of an id depends on the resolver. an
+// empty id means the default operator key is used
+type Identity struct {
+	ECDSA   string `json:"ecdsa,omitempty"`
+	ED25519 string `json:"ed25519,omitempty"`
+}
+
+// Resolver resolves a signer by the key id
+type Resolver interface {
+	ResolveECDSA(id string) (ECDSA, error)
+	ResolveED25519(id string) (ED25519, error)
+}
+
+// ProjectIdentity returns the identity configured by the project itself, e.g. by the project contract attribute.
+// returns nil if the project has none
+type ProjectIdentity func(projectID uint64) *Identity
+
+// Registry maps a project to its operator signers. the identities in the mapping file take precedence over the ones
+// configured by projects, and a project without a dedicated key falls back to the default operator signers. a project
+// could only configure the keys claimable, since any key the resolver resolves would be signed with otherwise
+type Registry struct {
+	defaultECDSA    ECDSA
+	defaultED25519  ED25519
+	resolver        Resolver // optional, nil means only the default operator signers are available
+	identities      map[uint64]*Identity
+	projectIdentity ProjectIdentity // optional
+	claimable       map[string]bool // the key ids could be configured by projects, empty means none
+
+	mux          sync.Mutex
+	ecdsaCache   map[string]ECDSA
+	ed25519Cache map[string]ED25519
+}
+
+func (r *Registry) identity(projectID uint64) *Identity {
+	if id, ok := r.identities[projectID]; ok {
+		return id
+	}
+	if r.projectIdentity == nil {
+		return nil
+	}
+	id := r.projectIdentity(projectID)
+	if id == nil {
+		return nil
+	}
+	if (id.ECDSA != "" && !r.claimable[id.ECDSA]) || (id.ED25519 != "" && !r.claimable[id.ED25519]) {
+		slog.Warn("project operator key is not claimable", "project_id", projectID, "ecdsa", id.ECDSA, "ed25519", id.ED25519)
+		return nil
+	}
+	// a key dedicated to a project in the mapping file can't be claimed by other projects
+	for pid, reserved := range r.identities {
+		if pid == projectID {
+			continue
+		}
+		if (id.ECDSA != "" && id.ECDSA == reserved.ECDSA) || (id.ED25519 != "" && id.ED25519 == reserved.ED25519) {
+			return nil
+		}
+	}
+	return id
+}
+
+func (r *Registry) resolveECDSA(id string) (ECDSA, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if s, ok := r.ecdsaCache[id]; ok {
+		return s, nil
+	}
+	if r.resolver == nil {
+		return nil, errors.Errorf("no key resolver configured for ecdsa key %s", id)
+	}
+	s, err := r.resolver.ResolveECDSA(id)
+	if err != nil {
+		return nil, err
+	}
+	r.ecdsaCache[id] = s
+	return s, nil
+}
+
+func (r *Registry) resolveED25519(id string) (ED25519, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if s, ok := r.ed25519Cache[id]; ok {
+		return s, nil
+	}
+	if r.resolver == nil {
+		return nil, errors.Errorf("no key resolver configured for ed25519 key %s", id)
+	}
+	s, err := r.resolver.ResolveED25519(id)
+	if err != nil {
+		return nil, err
+	}
+	r.ed25519Cache[id] = s
+	return s, nil
+}
+
+// Signers returns the operator signers of the project, a nil signer means the key is not configured
+func (r *Registry) Signers(projectID uint64) (ECDSA, ED25519, error) {
+	ecdsaSigner, ed25519Signer := r.defaultECDSA, r.defaultED25519
+	id := r.identity(projectID)
+	if id == nil {
+		return ecdsaSigner, ed25519Signer, nil
+	}
+	var err error
+	if id.ECDSA != "" {
+		if ecdsaSigner, err = r.resolveECDSA(id.ECDSA); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to resolve ecdsa signer of project %d", projectID)
+		}
+	}
+	if id.ED25519 != "" {
+		if ed25519Signer, err = r.resolveED25519(id.ED25519); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to resolve ed25519 signer of project %d", projectID)
+		}
+	}
+	return ecdsaSigner, ed25519Signer, nil
+}
+
+// Default returns the default operator signers
+func (r *Registry) Default() (ECDSA, ED25519) {
+	return r.defaultECDSA, r.defaultED25519
+}
+
+// Dedicated reports whether the project has dedicated operator keys
+func (r *Registry) Dedicated(projectID uint64) bool {
+	return r.identity(projectID) != nil
+}
+
+// LoadIdentities loads the mapping file, which is a json object from project id to identity, e.g.
+// {"1": {"ecdsa": "0x...", "ed25519": "..."}}
+func LoadIdentities(path string) (map[uint64]*Identity, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read project operator file %s", path)
+	}
+	raw := map[string]*Identity{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode project operator file %s", path)
+	}
+	identities := make(map[uint64]*Identity, len(raw))
+	for k, v := range raw {
+		projectID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid project id %s in project operator file", k)
+		}
+		identities[projectID] = v
+	}
+	return identities, nil
+}
+
+// NewRegistry creates the registry, claimable is the key ids projects could configure by themselves
+func NewRegistry(defaultECDSA ECDSA, defaultED25519 ED25519, resolver Resolver,
+	identities map[uint64]*Identity, projectIdentity ProjectIdentity, claimable []string) *Registry {
+	if identities == nil {
+		identities = map[uint64]*Identity{}
+	}
+	claimableSet := make(map[string]bool, len(claimable))
+	for _, id := range claimable {
+		if id != "" {
+			claimableSet[id] = true
+		}
+	}
+	return &Registry{
+		defaultECDSA:    defaultECDSA,
+		defaultED25519:  defaultED25519,
+		resolver:        resolver,
+		identities:      identities,
+		projectIdentity: projectIdentity,
+		claimable:       claimableSet,
+		ecdsaCache:      map[string]ECDSA{},
+		ed25519Cache:    map[string]ED25519{},
+	}
+}
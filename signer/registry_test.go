@@ -0,0 +1,139 @@
+package signer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type mockResolver struct {
+	calls int
+}
+
+func (m *mockResolver) ResolveECDSA(id string) (ECDSA, error) {
+	m.calls++
+	if id == "notExist" {
+		return nil, errors.New("not exist")
+	}
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewECDSA(sk), nil
+}
+
+func (m *mockResolver) ResolveED25519(id string) (ED25519, error) {
+	m.calls++
+	return nil, errors.New("not exist")
+}
+
+func TestRegistry_Signers(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+	defaultECDSA := NewECDSA(sk)
+	resolver := &mockResolver{}
+	reg := NewRegistry(defaultECDSA, nil, resolver, map[uint64]*Identity{
+		1: {ECDSA: "project1"},
+		2: {ECDSA: "notExist"},
+		3: {ED25519: "project3"},
+	}, func(projectID uint64) *Identity {
+		switch projectID {
+		case 4:
+			return &Identity{ECDSA: "project4"}
+		case 5:
+			return &Identity{ECDSA: "project1"}
+		case 6:
+			return &Identity{ECDSA: "operator"}
+		case 7:
+			return &Identity{ECDSA: "project4", ED25519: "operator"}
+		}
+		return nil
+	}, []string{"project4", "project1", ""})
+
+	t.Run("Default", func(t *testing.T) {
+		s, es, err := reg.Signers(100)
+		r.NoError(err)
+		r.Equal(defaultECDSA, s)
+		r.Nil(es)
+		r.False(reg.Dedicated(100))
+	})
+	t.Run("FromMappingFile", func(t *testing.T) {
+		s, _, err := reg.Signers(1)
+		r.NoError(err)
+		r.NotEqual(defaultECDSA.Address(), s.Address())
+		r.True(reg.Dedicated(1))
+
+		calls := resolver.calls
+		cached, _, err := reg.Signers(1)
+		r.NoError(err)
+		r.Equal(s, cached)
+		r.Equal(calls, resolver.calls)
+	})
+	t.Run("FailedToResolve", func(t *testing.T) {
+		_, _, err := reg.Signers(2)
+		r.ErrorContains(err, "project 2")
+		_, _, err = reg.Signers(3)
+		r.ErrorContains(err, "ed25519")
+	})
+	t.Run("FromProjectIdentity", func(t *testing.T) {
+		s, _, err := reg.Signers(4)
+		r.NoError(err)
+		r.NotEqual(defaultECDSA.Address(), s.Address())
+	})
+	t.Run("ReservedIdentity", func(t *testing.T) {
+		s, _, err := reg.Signers(5)
+		r.NoError(err)
+		r.Equal(defaultECDSA, s)
+		r.False(reg.Dedicated(5))
+	})
+	t.Run("NotClaimableIdentity", func(t *testing.T) {
+		calls := resolver.calls
+		for _, projectID := range []uint64{6, 7} {
+			s, _, err := reg.Signers(projectID)
+			r.NoError(err)
+			r.Equal(defaultECDSA, s)
+			r.False(reg.Dedicated(projectID))
+		}
+		r.Equal(calls, resolver.calls)
+
+		// none of the keys is claimable by default
+		reg := NewRegistry(defaultECDSA, nil, resolver, nil, func(uint64) *Identity { return &Identity{ECDSA: "project4"} }, nil)
+		s, _, err := reg.Signers(4)
+		r.NoError(err)
+		r.Equal(defaultECDSA, s)
+	})
+	t.Run("NoResolver", func(t *testing.T) {
+		_, _, err := NewRegistry(nil, nil, nil, map[uint64]*Identity{1: {ECDSA: common.Address{}.Hex()}}, nil, nil).Signers(1)
+		r.ErrorContains(err, "no key resolver")
+	})
+}
+
+func TestLoadIdentities(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	t.Run("FileNotExist", func(t *testing.T) {
+		_, err := LoadIdentities(filepath.Join(dir, "any"))
+		r.Error(err)
+	})
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		r.NoError(os.WriteFile(path, []byte(`{"any":{}}`), 0600))
+		_, err := LoadIdentities(path)
+		r.ErrorContains(err, "invalid project id")
+	})
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(dir, "operators.json")
+		r.NoError(os.WriteFile(path, []byte(`{"1":{"ecdsa":"0x01","ed25519":"any"}}`), 0600))
+		identities, err := LoadIdentities(path)
+		r.NoError(err)
+		r.Equal(map[uint64]*Identity{1: {ECDSA: "0x01", ED25519: "any"}}, identities)
+	})
+}
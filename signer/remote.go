@@ -0,0 +1,210 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// schemes supported by the remote signer protocol
+const (
+	SchemeSecp256k1 = "secp256k1"
+	SchemeED25519   = "ed25519"
+)
+
+// SignRequest is the body of `POST /sign`, payload is the hash for secp256k1 and the message for ed25519
+type SignRequest struct {
+	KeyID   string        `json:"keyID"`
+	Scheme  string        `json:"scheme"`
+	Payload hexutil.Bytes `json:"payload"`
+}
+
+type SignResponse struct {
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// PublicKeyResponse is the body returned by `GET /publicKey?keyID=&scheme=`, the secp256k1 public key is
+// uncompressed 65 bytes
+type PublicKeyResponse struct {
+	PublicKey hexutil.Bytes `json:"publicKey"`
+}
+
+type remoteClient struct {
+	endpoint string
+	keyID    string
+	scheme   string
+	token    string
+	client   *http.Client
+}
+
+func (c *remoteClient) do(req *http.Request, v any) error {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to request remote signer %s", c.endpoint)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read remote signer response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("remote signer responded status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.Wrap(err, "failed to decode remote signer response")
+	}
+	return nil
+}
+
+func (c *remoteClient) sign(payload []byte) ([]byte, error) {
+	b, err := json.Marshal(&SignRequest{KeyID: c.keyID, Scheme: c.scheme, Payload: payload})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal sign request")
+	}
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+"/sign", bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create sign request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := &SignResponse{}
+	if err := c.do(req, resp); err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+func (c *remoteClient) publicKey() ([]byte, error) {
+	u := fmt.Sprintf("%s/publicKey?keyID=%s&scheme=%s", c.endpoint, url.QueryEscape(c.keyID), c.scheme)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create public key request")
+	}
+	resp := &PublicKeyResponse{}
+	if err := c.do(req, resp); err != nil {
+		return nil, err
+	}
+	return resp.PublicKey, nil
+}
+
+func newRemoteClient(endpoint, keyID, scheme, token string) *remoteClient {
+	return &remoteClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		keyID:    keyID,
+		scheme:   scheme,
+		token:    token,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type remoteECDSA struct {
+	*remoteClient
+	address common.Address
+}
+
+func (s *remoteECDSA) Address() common.Address {
+	return s.address
+}
+
+func (s *remoteECDSA) SignHash(hash []byte) ([]byte, error) {
+	sig, err := s.sign(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != crypto.SignatureLength {
+		return nil, errors.Errorf("invalid remote signature length %d", len(sig))
+	}
+	// accept the ethereum style V which is 27 or 28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to recover remote signature")
+	}
+	if crypto.PubkeyToAddress(*pub) != s.address {
+		return nil, errors.Errorf("remote signature is not signed by %s", s.address)
+	}
+	return sig, nil
+}
+
+type remoteED25519 struct {
+	*remoteClient
+	pubKey ed25519.PublicKey
+}
+
+func (s *remoteED25519) PublicKey() ed25519.PublicKey {
+	return s.pubKey
+}
+
+func (s *remoteED25519) Sign(message []byte) ([]byte, error) {
+	sig, err := s.sign(message)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(s.pubKey, message, sig) {
+		return nil, errors.Errorf("remote signature is not signed by %s", hexutil.Encode(s.pubKey))
+	}
+	return sig, nil
+}
+
+// NewRemoteECDSA returns a signer delegating to a remote signer service, the key never leaves the service.
+// the public key is fetched once and each signature is verified against it
+func NewRemoteECDSA(endpoint, keyID, token string) (ECDSA, error) {
+	c := newRemoteClient(endpoint, keyID, SchemeSecp256k1, token)
+	b, err := c.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := crypto.UnmarshalPubkey(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse remote ecdsa public key")
+	}
+	return &remoteECDSA{remoteClient: c, address: crypto.PubkeyToAddress(*pub)}, nil
+}
+
+// NewRemoteED25519 returns a signer delegating to a remote signer service
+func NewRemoteED25519(endpoint, keyID, token string) (ED25519, error) {
+	c := newRemoteClient(endpoint, keyID, SchemeED25519, token)
+	b, err := c.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("invalid remote ed25519 public key length %d", len(b))
+	}
+	return &remoteED25519{remoteClient: c, pubKey: ed25519.PublicKey(b)}, nil
+}
+
+// RemoteResolver resolves keys from the remote signer service, a key is identified by its key id
+type RemoteResolver struct {
+	endpoint string
+	token    string
+}
+
+func (r *RemoteResolver) ResolveECDSA(id string) (ECDSA, error) {
+	return NewRemoteECDSA(r.endpoint, id, r.token)
+}
+
+func (r *RemoteResolver) ResolveED25519(id string) (ED25519, error) {
+	return NewRemoteED25519(r.endpoint, id, r.token)
+}
+
+func NewRemoteResolver(endpoint, token string) *RemoteResolver {
+	return &RemoteResolver{endpoint: endpoint, token: token}
+}
@@ -0,0 +1,78 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteECDSA(t *testing.T) {
+	r := require.New(t)
+
+	sk, err := crypto.GenerateKey()
+	r.NoError(err)
+	s := NewServer("token")
+	s.AddECDSA("operator", sk)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		_, err := NewRemoteECDSA(srv.URL, "operator", "any")
+		r.ErrorContains(err, "status 401")
+	})
+	t.Run("KeyNotFound", func(t *testing.T) {
+		_, err := NewRemoteECDSA(srv.URL, "any", "token")
+		r.ErrorContains(err, "status 404")
+	})
+	t.Run("Success", func(t *testing.T) {
+		remote, err := NewRemoteECDSA(srv.URL+"/", "operator", "token")
+		r.NoError(err)
+		r.Equal(crypto.PubkeyToAddress(sk.PublicKey), remote.Address())
+
+		hash := crypto.Keccak256([]byte("any"))
+		sig, err := remote.SignHash(hash)
+		r.NoError(err)
+		expected, err := crypto.Sign(hash, sk)
+		r.NoError(err)
+		r.Equal(expected, sig)
+	})
+	t.Run("SignedByOtherKey", func(t *testing.T) {
+		remote, err := NewRemoteECDSA(srv.URL, "operator", "token")
+		r.NoError(err)
+		other, err := crypto.GenerateKey()
+		r.NoError(err)
+		s.AddECDSA("operator", other)
+		defer s.AddECDSA("operator", sk)
+
+		_, err = remote.SignHash(crypto.Keccak256([]byte("any")))
+		r.ErrorContains(err, "is not signed by")
+	})
+}
+
+func TestRemoteED25519(t *testing.T) {
+	r := require.New(t)
+
+	pub, sk, err := ed25519.GenerateKey(nil)
+	r.NoError(err)
+	s := NewServer("")
+	s.AddED25519("operator", sk)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	t.Run("KeyNotFound", func(t *testing.T) {
+		_, err := NewRemoteED25519(srv.URL, "any", "")
+		r.ErrorContains(err, "status 404")
+	})
+	t.Run("Success", func(t *testing.T) {
+		remote, err := NewRemoteED25519(srv.URL, "operator", "")
+		r.NoError(err)
+		r.Equal(pub, remote.PublicKey())
+
+		sig, err := remote.Sign([]byte("any"))
+		r.NoError(err)
+		r.True(ed25519.Verify(pub, []byte("any"), sig))
+	})
+}
@@ -0,0 +1,123 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Server is a local stand-in of the remote signer service, which serves the remote signer protocol with keys held
+// in memory. it is meant for development and testing, production deployments should use a real KMS or HSM
+type Server struct {
+	token    string
+	ecdsa    map[string]*ecdsa.PrivateKey
+	ed25519  map[string]ed25519.PrivateKey
+	serveMux *http.ServeMux
+}
+
+func (s *Server) AddECDSA(keyID string, sk *ecdsa.PrivateKey) {
+	s.ecdsa[keyID] = sk
+}
+
+func (s *Server) AddED25519(keyID string, sk ed25519.PrivateKey) {
+	s.ed25519[keyID] = sk
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	s.serveMux.ServeHTTP(w, r)
+}
+
+func (s *Server) sign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	req := &SignRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var sig []byte
+	switch req.Scheme {
+	case SchemeSecp256k1:
+		sk, ok := s.ecdsa[req.KeyID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "key not found")
+			return
+		}
+		var err error
+		if sig, err = crypto.Sign(req.Payload, sk); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	case SchemeED25519:
+		sk, ok := s.ed25519[req.KeyID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "key not found")
+			return
+		}
+		sig = ed25519.Sign(sk, req.Payload)
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported scheme "+req.Scheme)
+		return
+	}
+	writeJSON(w, http.StatusOK, &SignResponse{Signature: sig})
+}
+
+func (s *Server) publicKey(w http.ResponseWriter, r *http.Request) {
+	keyID, scheme := r.URL.Query().Get("keyID"), r.URL.Query().Get("scheme")
+
+	var pub []byte
+	switch scheme {
+	case SchemeSecp256k1:
+		sk, ok := s.ecdsa[keyID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "key not found")
+			return
+		}
+		pub = crypto.FromECDSAPub(&sk.PublicKey)
+	case SchemeED25519:
+		sk, ok := s.ed25519[keyID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "key not found")
+			return
+		}
+		pub = sk.Public().(ed25519.PublicKey)
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported scheme "+scheme)
+		return
+	}
+	writeJSON(w, http.StatusOK, &PublicKeyResponse{PublicKey: pub})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": strings.TrimSpace(msg)})
+}
+
+// NewServer returns a stand-in signer service, requests must carry the bearer token if it is not empty
+func NewServer(token string) *Server {
+	s := &Server{
+		token:    token,
+		ecdsa:    map[string]*ecdsa.PrivateKey{},
+		ed25519:  map[string]ed25519.PrivateKey{},
+		serveMux: http.NewServeMux(),
+	}
+	s.serveMux.HandleFunc("/sign", s.sign)
+	s.serveMux.HandleFunc("/publicKey", s.publicKey)
+	return s
+}
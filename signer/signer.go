@@ -0,0 +1,74 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// ECDSA signs with a secp256k1 key, which is used by ethereum and textile outputs
+type ECDSA interface {
+	Address() common.Address
+	// SignHash returns a 65 bytes [R || S || V] signature of hash, V is 0 or 1
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// ED25519 signs with an ed25519 key, which is used by solana output
+type ED25519 interface {
+	PublicKey() ed25519.PublicKey
+	Sign(message []byte) ([]byte, error)
+}
+
+type rawECDSA struct {
+	sk *ecdsa.PrivateKey
+}
+
+func (s *rawECDSA) Address() common.Address {
+	return crypto.PubkeyToAddress(s.sk.PublicKey)
+}
+
+func (s *rawECDSA) SignHash(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(hash, s.sk)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign hash")
+	}
+	return sig, nil
+}
+
+type rawED25519 struct {
+	sk ed25519.PrivateKey
+}
+
+func (s *rawED25519) PublicKey() ed25519.PublicKey {
+	return s.sk.Public().(ed25519.PublicKey)
+}
+
+func (s *rawED25519) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.sk, message), nil
+}
+
+// NewECDSA returns a signer holding the secp256k1 private key in memory
+func NewECDSA(sk *ecdsa.PrivateKey) ECDSA {
+	return &rawECDSA{sk: sk}
+}
+
+// NewECDSAFromHex returns a signer holding the hex encoded secp256k1 private key in memory
+func NewECDSAFromHex(privateKey string) (ECDSA, error) {
+	sk, err := crypto.ToECDSA(common.FromHex(privateKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse ecdsa private key")
+	}
+	return &rawECDSA{sk: sk}, nil
+}
+
+// NewED25519FromHex returns a signer holding the hex encoded ed25519 private key in memory
+func NewED25519FromHex(privateKey string) (ED25519, error) {
+	b := common.FromHex(privateKey)
+	if len(b) != ed25519.PrivateKeySize {
+		return nil, errors.Errorf("invalid ed25519 private key length %d", len(b))
+	}
+	return &rawED25519{sk: ed25519.PrivateKey(b)}, nil
+}
@@ -0,0 +1,53 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewECDSAFromHex(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		_, err := NewECDSAFromHex("0x01")
+		r.Error(err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		sk, err := crypto.GenerateKey()
+		r.NoError(err)
+		s, err := NewECDSAFromHex(common.Bytes2Hex(crypto.FromECDSA(sk)))
+		r.NoError(err)
+		r.Equal(crypto.PubkeyToAddress(sk.PublicKey), s.Address())
+
+		hash := crypto.Keccak256([]byte("any"))
+		sig, err := s.SignHash(hash)
+		r.NoError(err)
+		pub, err := crypto.SigToPub(hash, sig)
+		r.NoError(err)
+		r.Equal(s.Address(), crypto.PubkeyToAddress(*pub))
+	})
+}
+
+func TestNewED25519FromHex(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidKeyLength", func(t *testing.T) {
+		_, err := NewED25519FromHex("0x01")
+		r.Error(err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		pub, sk, err := ed25519.GenerateKey(nil)
+		r.NoError(err)
+		s, err := NewED25519FromHex(common.Bytes2Hex(sk))
+		r.NoError(err)
+		r.Equal(pub, s.PublicKey())
+
+		sig, err := s.Sign([]byte("any"))
+		r.NoError(err)
+		r.True(ed25519.Verify(pub, []byte("any"), sig))
+	})
+}
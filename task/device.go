@@ -0,0 +1,81 @@
+package task
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// the curves of device keys, named as the jwk crv
+const (
+	CurveP256      = "P-256"
+	CurveSecp256k1 = "secp256k1"
+)
+
+// DeviceSignature is the signature of message data by the device key in its ioID DID document, verified by the
+// sequencer before the message is accepted
+type DeviceSignature struct {
+	KeyID     string `json:"keyID"`     // the verification method id in the DID document
+	Curve     string `json:"curve"`     // P-256 or secp256k1
+	PublicKey []byte `json:"publicKey"` // uncompressed public key
+	Signature []byte `json:"signature"` // r || s over sha256 of message data
+}
+
+func curve(name string) (elliptic.Curve, error) {
+	switch name {
+	case CurveP256:
+		return elliptic.P256(), nil
+	case CurveSecp256k1:
+		return crypto.S256(), nil
+	default:
+		return nil, errors.Errorf("unsupported device key curve %s", name)
+	}
+}
+
+// NewDevicePublicKey returns the uncompressed public key of the curve point
+func NewDevicePublicKey(curveName string, x, y []byte) ([]byte, error) {
+	c, err := curve(curveName)
+	if err != nil {
+		return nil, err
+	}
+	px, py := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+	if !c.IsOnCurve(px, py) {
+		return nil, errors.New("device public key is not on curve")
+	}
+	size := (c.Params().BitSize + 7) / 8
+	pk := make([]byte, 1+2*size)
+	pk[0] = 4
+	px.FillBytes(pk[1 : 1+size])
+	py.FillBytes(pk[1+size:])
+	return pk, nil
+}
+
+// Verify verifies the signature of message data
+func (s *DeviceSignature) Verify(data []byte) error {
+	c, err := curve(s.Curve)
+	if err != nil {
+		return err
+	}
+	size := (c.Params().BitSize + 7) / 8
+	if len(s.PublicKey) != 1+2*size || s.PublicKey[0] != 4 {
+		return errors.New("invalid device public key")
+	}
+	if len(s.Signature) != 2*size {
+		return errors.Errorf("invalid device signature length %d", len(s.Signature))
+	}
+	pk := &ecdsa.PublicKey{
+		Curve: c,
+		X:     new(big.Int).SetBytes(s.PublicKey[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(s.PublicKey[1+size:]),
+	}
+	h := sha256.Sum256(data)
+	r, ss := new(big.Int).SetBytes(s.Signature[:size]), new(big.Int).SetBytes(s.Signature[size:])
+	if !ecdsa.Verify(pk, h[:], r, ss) {
+		return errors.New("device signature unmatched")
+	}
+	return nil
+}
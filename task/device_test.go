@@ -0,0 +1,86 @@
+package task
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func signDeviceData(t *testing.T, curveName string, data []byte) *DeviceSignature {
+	r := require.New(t)
+
+	c, err := curve(curveName)
+	r.NoError(err)
+	sk, err := ecdsa.GenerateKey(c, rand.Reader)
+	r.NoError(err)
+	h := sha256.Sum256(data)
+	sr, ss, err := ecdsa.Sign(rand.Reader, sk, h[:])
+	r.NoError(err)
+
+	sig := make([]byte, 64)
+	sr.FillBytes(sig[:32])
+	ss.FillBytes(sig[32:])
+	pk, err := NewDevicePublicKey(curveName, sk.X.Bytes(), sk.Y.Bytes())
+	r.NoError(err)
+	return &DeviceSignature{KeyID: "key", Curve: curveName, PublicKey: pk, Signature: sig}
+}
+
+func TestNewDevicePublicKey(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("UnsupportedCurve", func(t *testing.T) {
+		_, err := NewDevicePublicKey("P-384", nil, nil)
+		r.ErrorContains(err, "unsupported device key curve")
+	})
+
+	t.Run("NotOnCurve", func(t *testing.T) {
+		_, err := NewDevicePublicKey(CurveP256, []byte{1}, []byte{2})
+		r.ErrorContains(err, "not on curve")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		sk, err := crypto.GenerateKey()
+		r.NoError(err)
+		pk, err := NewDevicePublicKey(CurveSecp256k1, sk.X.Bytes(), sk.Y.Bytes())
+		r.NoError(err)
+		r.Equal(crypto.FromECDSAPub(&sk.PublicKey), pk)
+	})
+}
+
+func TestDeviceSignature_Verify(t *testing.T) {
+	r := require.New(t)
+
+	data := []byte("data")
+
+	t.Run("UnsupportedCurve", func(t *testing.T) {
+		s := &DeviceSignature{Curve: "P-384"}
+		r.ErrorContains(s.Verify(data), "unsupported device key curve")
+	})
+
+	t.Run("InvalidPublicKey", func(t *testing.T) {
+		s := &DeviceSignature{Curve: CurveP256, PublicKey: []byte{4}}
+		r.ErrorContains(s.Verify(data), "invalid device public key")
+	})
+
+	t.Run("InvalidSignatureLength", func(t *testing.T) {
+		s := signDeviceData(t, CurveP256, data)
+		s.Signature = append(s.Signature, 0)
+		r.ErrorContains(s.Verify(data), "invalid device signature length")
+	})
+
+	t.Run("Unmatched", func(t *testing.T) {
+		s := signDeviceData(t, CurveP256, data)
+		r.ErrorContains(s.Verify([]byte("other")), "device signature unmatched")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		for _, c := range []string{CurveP256, CurveSecp256k1} {
+			s := signDeviceData(t, c, data)
+			r.NoError(s.Verify(data), c)
+		}
+	})
+}
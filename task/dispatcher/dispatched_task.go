@@ -24,12 +24,26 @@ type dispatchedTask struct {
 }
 
 func (t *dispatchedTask) handleState(s *task.StateLog) {
-	if t.handler.handle(t.dispatchedTime, s, t.task) {
+	finished, held := t.handler.handle(t.dispatchedTime, s, t.task, func() { t.timeOut(resumed(s)) })
+	if held {
+		// a held task is neither retried nor timed out, its proved state is consumed again once resumed
+		t.cancel()
+		return
+	}
+	if finished {
 		t.cancel()
 		t.finished.Store(true)
 	}
 }
 
+// resumed returns the proved state log of a held task to be consumed again
+func resumed(s *task.StateLog) *task.StateLog {
+	r := *s
+	r.Comment = "output resumed"
+	r.CreatedAt = time.Now()
+	return &r
+}
+
 func (t *dispatchedTask) runWatchdog(ctx context.Context) {
 	retryChan := time.After(t.waitTime)
 	timeoutChan := time.After(2 * t.waitTime)
@@ -15,18 +15,47 @@ import (
 
 func TestDispatchedTask_handleState(t *testing.T) {
 	r := require.New(t)
-	p := gomonkey.NewPatches()
-	defer p.Reset()
+	t.Run("Finished", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		h := &taskStateHandler{}
+		p.ApplyPrivateMethod(h, "handle", func() (bool, bool) { return true, false })
+
+		d := &dispatchedTask{
+			cancel:  func() {},
+			handler: h,
+		}
+		d.handleState(nil)
+		r.Equal(d.finished.Load(), true)
+	})
+	t.Run("Held", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		h := &taskStateHandler{}
+		var resume func()
+		p.ApplyPrivateMethod(h, "handle", func(_ *taskStateHandler, _ time.Time, _ *task.StateLog, _ *task.Task, r func()) (bool, bool) {
+			resume = r
+			return false, true
+		})
 
-	h := &taskStateHandler{}
-	p.ApplyPrivateMethod(h, "handle", func() bool { return true })
+		canceled := false
+		consumed := []*task.StateLog{}
+		d := &dispatchedTask{
+			cancel:  func() { canceled = true },
+			timeOut: func(s *task.StateLog) { consumed = append(consumed, s) },
+			handler: h,
+		}
+		d.handleState(&task.StateLog{TaskID: 1, State: task.StateProved})
+		r.True(canceled)
+		r.False(d.finished.Load())
 
-	d := &dispatchedTask{
-		cancel:  func() {},
-		handler: h,
-	}
-	d.handleState(nil)
-	r.Equal(d.finished.Load(), true)
+		resume()
+		r.Len(consumed, 1)
+		r.Equal(task.StateProved, consumed[0].State)
+		r.Equal("output resumed", consumed[0].Comment)
+	})
 }
 
 func TestDispatchedTask_runWatchdog(t *testing.T) {
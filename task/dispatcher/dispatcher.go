@@ -9,10 +9,12 @@ import (
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 
 	"github.com/machinefi/sprout/datasource"
+	"github.com/machinefi/sprout/output"
 	"github.com/machinefi/sprout/p2p"
 	"github.com/machinefi/sprout/persistence/contract"
 	"github.com/machinefi/sprout/project"
 	"github.com/machinefi/sprout/scheduler"
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
@@ -30,6 +32,16 @@ type ProjectManager interface {
 	Project(projectID uint64) (*project.Project, error)
 }
 
+type Operators interface {
+	Signers(projectID uint64) (signer.ECDSA, signer.ED25519, error)
+}
+
+type Budget interface {
+	// Hold holds the output if the project budget is exceeded, returns false if the output could be done right now
+	Hold(projectID uint64, out func()) bool
+	Record(projectID uint64, spend *output.Spend)
+}
+
 type Persistence interface {
 	Create(tl *task.StateLog, t *task.Task) error
 	ProcessedTaskID(projectID uint64) (uint64, error)
@@ -37,24 +49,22 @@ type Persistence interface {
 }
 
 type Dispatcher struct {
-	local                     bool
-	projectDispatchers        *sync.Map // projectID(uint64) -> *ProjectDispatcher
-	projectOffsets            *scheduler.ProjectEpochOffsets
-	pubSubs                   *p2p.PubSubs
-	persistence               Persistence
-	newDatasource             NewDatasource
-	projectManager            ProjectManager
-	defaultDatasourceURI      string
-	bootNodeMultiaddr         string
-	operatorPrivateKey        string
-	operatorPrivateKeyED25519 string
-	sequencerPubKey           []byte
-	iotexChainID              int
-	projectNotification       <-chan uint64
-	chainHeadNotification     <-chan uint64
-	contract                  Contract
-	taskStateHandler          *taskStateHandler
-	windowSizeSetInterval     time.Duration
+	local                 bool
+	projectDispatchers    *sync.Map // projectID(uint64) -> *ProjectDispatcher
+	projectOffsets        *scheduler.ProjectEpochOffsets
+	pubSubs               *p2p.PubSubs
+	persistence           Persistence
+	newDatasource         NewDatasource
+	projectManager        ProjectManager
+	defaultDatasourceURI  string
+	bootNodeMultiaddr     string
+	sequencerPubKey       []byte
+	iotexChainID          int
+	projectNotification   <-chan uint64
+	chainHeadNotification <-chan uint64
+	contract              Contract
+	taskStateHandler      *taskStateHandler
+	windowSizeSetInterval time.Duration
 }
 
 func (d *Dispatcher) handleP2PData(data *p2p.Data, topic *pubsub.Topic) {
@@ -186,30 +196,29 @@ func (d *Dispatcher) Run() {
 }
 
 func New(persistence Persistence, newDatasource NewDatasource,
-	projectManager ProjectManager, defaultDatasourceURI, bootNodeMultiaddr, operatorPrivateKey, operatorPrivateKeyED25519, contractWhitelist string,
+	projectManager ProjectManager, defaultDatasourceURI, bootNodeMultiaddr string,
+	operators Operators, budget Budget, contractWhitelist string,
 	sequencerPubKey []byte, iotexChainID int, projectNotification <-chan uint64, chainHeadNotification <-chan uint64,
 	contract Contract, projectOffsets *scheduler.ProjectEpochOffsets) (*Dispatcher, error) {
 
 	projectDispatchers := &sync.Map{}
-	taskStateHandler := newTaskStateHandler(persistence, contract, projectManager, operatorPrivateKey, operatorPrivateKeyED25519, contractWhitelist)
+	taskStateHandler := newTaskStateHandler(persistence, contract, projectManager, operators, budget, contractWhitelist)
 	d := &Dispatcher{
-		local:                     false,
-		persistence:               persistence,
-		newDatasource:             newDatasource,
-		projectManager:            projectManager,
-		defaultDatasourceURI:      defaultDatasourceURI,
-		bootNodeMultiaddr:         bootNodeMultiaddr,
-		operatorPrivateKey:        operatorPrivateKey,
-		operatorPrivateKeyED25519: operatorPrivateKeyED25519,
-		sequencerPubKey:           sequencerPubKey,
-		iotexChainID:              iotexChainID,
-		projectNotification:       projectNotification,
-		chainHeadNotification:     chainHeadNotification,
-		contract:                  contract,
-		projectOffsets:            projectOffsets,
-		projectDispatchers:        projectDispatchers,
-		taskStateHandler:          taskStateHandler,
-		windowSizeSetInterval:     5 * time.Second,
+		local:                 false,
+		persistence:           persistence,
+		newDatasource:         newDatasource,
+		projectManager:        projectManager,
+		defaultDatasourceURI:  defaultDatasourceURI,
+		bootNodeMultiaddr:     bootNodeMultiaddr,
+		sequencerPubKey:       sequencerPubKey,
+		iotexChainID:          iotexChainID,
+		projectNotification:   projectNotification,
+		chainHeadNotification: chainHeadNotification,
+		contract:              contract,
+		projectOffsets:        projectOffsets,
+		projectDispatchers:    projectDispatchers,
+		taskStateHandler:      taskStateHandler,
+		windowSizeSetInterval: 5 * time.Second,
 	}
 	ps, err := p2p.NewPubSubs(d.handleP2PData, bootNodeMultiaddr, iotexChainID)
 	if err != nil {
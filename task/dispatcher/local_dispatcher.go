@@ -11,11 +11,12 @@ import (
 )
 
 func NewLocal(persistence Persistence, newDatasource NewDatasource,
-	projectManager ProjectManager, defaultDatasourceURI, operatorPrivateKey, operatorPrivateKeyED25519, bootNodeMultiaddr, contractWhitelist string,
+	projectManager ProjectManager, defaultDatasourceURI string, operators Operators, budget Budget,
+	bootNodeMultiaddr, contractWhitelist string,
 	sequencerPubKey []byte, iotexChainID int) (*Dispatcher, error) {
 
 	projectDispatchers := &sync.Map{}
-	taskStateHandler := newTaskStateHandler(persistence, nil, projectManager, operatorPrivateKey, operatorPrivateKeyED25519, contractWhitelist)
+	taskStateHandler := newTaskStateHandler(persistence, nil, projectManager, operators, budget, contractWhitelist)
 	d := &Dispatcher{
 		local:              true,
 		projectDispatchers: projectDispatchers,
@@ -18,6 +18,8 @@ import (
 type projectDispatcher struct {
 	window               *window
 	waitInterval         time.Duration
+	notifiedWaitInterval time.Duration
+	notification         <-chan struct{} // nil if the datasource does not notify new tasks
 	startTaskID          uint64
 	projectID            uint64
 	datasource           datasource.Datasource
@@ -47,12 +49,36 @@ func (d *projectDispatcher) run() {
 			continue
 		}
 		if nextTaskID == next {
-			time.Sleep(d.waitInterval)
+			d.wait()
 		}
 		nextTaskID = next
 	}
 }
 
+// wait waits for new tasks. the datasource notifying new tasks is still polled at a lower rate, since the
+// notifications may be lost
+func (d *projectDispatcher) wait() {
+	if d.notification == nil {
+		time.Sleep(d.waitInterval)
+		return
+	}
+	select {
+	case <-d.notification:
+	case <-time.After(d.notifiedWaitInterval):
+	}
+}
+
+// subscribe returns the notification of new tasks of the project, nil if the datasource does not support. the
+// project dispatcher never stops, so the subscription is never canceled
+func subscribe(ds datasource.Datasource, projectID uint64) <-chan struct{} {
+	s, ok := ds.(datasource.Subscriber)
+	if !ok {
+		return nil
+	}
+	notification, _ := s.Subscribe(projectID)
+	return notification
+}
+
 func (d *projectDispatcher) dispatch(nextTaskID uint64) (uint64, error) {
 	t, err := d.datasource.Retrieve(d.projectID, nextTaskID)
 	if err != nil {
@@ -108,6 +134,8 @@ func newProjectDispatcher(persistence Persistence, datasourceURI string, newData
 	d := &projectDispatcher{
 		window:               window,
 		waitInterval:         3 * time.Second,
+		notifiedWaitInterval: 30 * time.Second,
+		notification:         subscribe(datasource, p.ID),
 		startTaskID:          processedTaskID + 1,
 		datasource:           datasource,
 		projectID:            p.ID,
@@ -48,6 +48,53 @@ func TestProjectDispatcher_run(t *testing.T) {
 	r.Panics(func() { d.run() })
 }
 
+type mockSubscriber struct {
+	mockDatasource
+	*datasource.Notifier
+}
+
+func TestProjectDispatcher_wait(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Polling", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		slept := time.Duration(0)
+		p.ApplyFunc(time.Sleep, func(d time.Duration) { slept = d })
+
+		d := &projectDispatcher{waitInterval: time.Second}
+		d.wait()
+		r.Equal(time.Second, slept)
+	})
+
+	t.Run("Notified", func(t *testing.T) {
+		n := make(chan struct{}, 1)
+		n <- struct{}{}
+		d := &projectDispatcher{notifiedWaitInterval: time.Hour, notification: n}
+		d.wait()
+		r.Len(n, 0)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		d := &projectDispatcher{notifiedWaitInterval: time.Millisecond, notification: make(chan struct{})}
+		d.wait()
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	r := require.New(t)
+
+	r.Nil(subscribe(nil, 1))
+	r.Nil(subscribe(&mockDatasource{}, 1))
+
+	ds := &mockSubscriber{Notifier: datasource.NewNotifier()}
+	n := subscribe(ds, 1)
+	r.NotNil(n)
+	ds.Notify(1)
+	r.Len(n, 1)
+}
+
 func TestProjectDispatcher_dispatch(t *testing.T) {
 	r := require.New(t)
 	t.Run("FailedToRetrieveTask", func(t *testing.T) {
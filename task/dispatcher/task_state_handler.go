@@ -2,23 +2,28 @@ package dispatcher
 
 import (
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/machinefi/sprout/metrics"
 	"github.com/machinefi/sprout/output"
+	"github.com/machinefi/sprout/project"
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
 type taskStateHandler struct {
-	contract                  Contract // optional, will be nil in local model
-	persistence               Persistence
-	projectManager            ProjectManager
-	operatorPrivateKeyECDSA   string
-	operatorPrivateKeyED25519 string
-	contractWhitelist         string
+	contract          Contract // optional, will be nil in local model
+	persistence       Persistence
+	projectManager    ProjectManager
+	operators         Operators // optional, nil means no operator key configured
+	budget            Budget    // optional, nil means unlimited
+	contractWhitelist string
 }
 
-func (h *taskStateHandler) handle(dispatchedTime time.Time, s *task.StateLog, t *task.Task) (finished bool) {
+// handle handles the task state log, and returns whether the task is finished. the output of a proved task is held if
+// the project budget is exceeded, the held task is unfinished, and resume is called once the project is resumed
+func (h *taskStateHandler) handle(dispatchedTime time.Time, s *task.StateLog, t *task.Task, resume func()) (finished, held bool) {
 	// TODO dispatcher will send a failed TaskStateLog when timeout, without signature. maybe dispatcher need a sig also
 	// if h.latestProvers != nil && s.Signature != "" {
 	// 	ps := h.latestProvers()
@@ -41,29 +46,45 @@ func (h *taskStateHandler) handle(dispatchedTime time.Time, s *task.StateLog, t
 	// }
 	if err := h.persistence.Create(s, t); err != nil {
 		slog.Error("failed to create task state log", "error", err, "task_id", s.TaskID)
-		return
+		return false, false
 	}
 	if s.State == task.StateFailed {
 		metrics.FailedTaskNumMtc(t.ProjectID, t.ProjectVersion)
 		metrics.TaskFinalStateNumMtc(t.ProjectID, t.ProjectVersion, task.StateFailed.String())
-		return true
+		return true, false
 	}
 
 	if s.State != task.StateProved {
-		return
+		return false, false
 	}
 	p, err := h.projectManager.Project(t.ProjectID)
 	if err != nil {
 		slog.Error("failed to get project", "error", err, "project_id", t.ProjectID)
-		return
+		return false, false
 	}
 	c, err := p.DefaultConfig()
 	if err != nil {
 		slog.Error("failed to get project config", "error", err, "project_id", t.ProjectID, "project_version", p.DefaultVersion)
-		return
+		return false, false
 	}
 
-	output, err := output.New(&c.Output, h.operatorPrivateKeyECDSA, h.operatorPrivateKeyED25519, h.contractWhitelist)
+	if h.budget != nil && h.budget.Hold(t.ProjectID, resume) {
+		slog.Info("task output held since project budget exceeded", "project_id", t.ProjectID, "task_id", s.TaskID)
+		if err := h.persistence.Create(&task.StateLog{
+			TaskID:    s.TaskID,
+			State:     task.StateHeld,
+			Comment:   "output held since project budget exceeded",
+			CreatedAt: time.Now(),
+		}, t); err != nil {
+			slog.Error("failed to create held task state", "error", err, "task_id", s.TaskID)
+		}
+		return false, true
+	}
+	return h.output(dispatchedTime, s, t, c), false
+}
+
+func (h *taskStateHandler) output(dispatchedTime time.Time, s *task.StateLog, t *task.Task, c *project.Config) (finished bool) {
+	o, err := h.newOutput(t.ProjectID, &c.Output)
 	if err != nil {
 		slog.Error("failed to init output", "error", err, "project_id", t.ProjectID)
 		metrics.FailedTaskNumMtc(t.ProjectID, t.ProjectVersion)
@@ -81,7 +102,21 @@ func (h *taskStateHandler) handle(dispatchedTime time.Time, s *task.StateLog, t
 		return true
 	}
 
-	outRes, err := output.Output(t, s.Result)
+	// the spend of every output paying for transactions is recorded, including the chained ones
+	spent := func(spend *output.Spend) {
+		if h.budget != nil {
+			h.budget.Record(t.ProjectID, spend)
+		}
+	}
+	var outRes string
+	switch so := o.(type) {
+	case output.ProverSignedOutput:
+		outRes, err = so.OutputWithProverSignature(t, s.Result, s.Signature, spent)
+	case output.SpendOutput:
+		outRes, err = so.OutputWithSpend(t, s.Result, spent)
+	default:
+		outRes, err = o.Output(t, s.Result)
+	}
 	if err != nil {
 		slog.Error("failed to output", "error", err, "task_id", s.TaskID)
 		metrics.FailedTaskNumMtc(t.ProjectID, t.ProjectVersion)
@@ -103,10 +138,15 @@ func (h *taskStateHandler) handle(dispatchedTime time.Time, s *task.StateLog, t
 	metrics.SucceedTaskNumMtc(t.ProjectID, t.ProjectVersion)
 	metrics.TaskFinalStateNumMtc(t.ProjectID, t.ProjectVersion, task.StateOutputted.String())
 
+	comment := "output type: " + string(c.Output.Type)
+	// the result of a dry run is never sent, it should not be taken as an output by the consumers
+	if strings.HasPrefix(outRes, output.DryRunPrefix) {
+		comment = "dry run, " + comment
+	}
 	if err := h.persistence.Create(&task.StateLog{
 		TaskID:    s.TaskID,
 		State:     task.StateOutputted,
-		Comment:   "output type: " + string(c.Output.Type),
+		Comment:   comment,
 		Result:    []byte(outRes),
 		CreatedAt: time.Now(),
 	}, t); err != nil {
@@ -116,14 +156,30 @@ func (h *taskStateHandler) handle(dispatchedTime time.Time, s *task.StateLog, t
 	return true
 }
 
+// newOutput creates the output with the operator signers of the project
+func (h *taskStateHandler) newOutput(projectID uint64, conf *output.Config) (output.Output, error) {
+	var (
+		ecdsaSigner   signer.ECDSA
+		ed25519Signer signer.ED25519
+		err           error
+	)
+	if h.operators != nil {
+		ecdsaSigner, ed25519Signer, err = h.operators.Signers(projectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return output.New(conf, ecdsaSigner, ed25519Signer, h.contractWhitelist)
+}
+
 func newTaskStateHandler(persistence Persistence, contract Contract, projectManager ProjectManager,
-	operatorPrivateKeyECDSA, operatorPrivateKeyED25519, contractWhitelist string) *taskStateHandler {
+	operators Operators, budget Budget, contractWhitelist string) *taskStateHandler {
 	return &taskStateHandler{
-		contract:                  contract,
-		persistence:               persistence,
-		projectManager:            projectManager,
-		operatorPrivateKeyECDSA:   operatorPrivateKeyECDSA,
-		operatorPrivateKeyED25519: operatorPrivateKeyED25519,
-		contractWhitelist:         contractWhitelist,
+		contract:          contract,
+		persistence:       persistence,
+		projectManager:    projectManager,
+		operators:         operators,
+		budget:            budget,
+		contractWhitelist: contractWhitelist,
 	}
 }
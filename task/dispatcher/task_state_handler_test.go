@@ -11,6 +11,7 @@ import (
 	"github.com/machinefi/sprout/output"
 	"github.com/machinefi/sprout/persistence/postgres"
 	"github.com/machinefi/sprout/project"
+	"github.com/machinefi/sprout/signer"
 	"github.com/machinefi/sprout/task"
 )
 
@@ -20,6 +21,60 @@ func (m *mockOutput) Output(task *task.Task, proof []byte) (string, error) {
 	return "", nil
 }
 
+type mockProverSignedOutput struct {
+	mockOutput
+	signature string
+	spend     *output.Spend // spent by the chained output
+}
+
+func (m *mockProverSignedOutput) OutputWithProverSignature(task *task.Task, proof []byte, proverSignature string, spent func(*output.Spend)) (string, error) {
+	m.signature = proverSignature
+	if m.spend != nil {
+		spent(m.spend)
+	}
+	return "", nil
+}
+
+type mockSpendOutput struct {
+	mockOutput
+	spend *output.Spend
+}
+
+func (m *mockSpendOutput) OutputWithSpend(task *task.Task, proof []byte, spent func(*output.Spend)) (string, error) {
+	spent(m.spend)
+	return "", errors.New("reverted")
+}
+
+type mockBudget struct {
+	held  []func()
+	spent []*output.Spend
+}
+
+func (m *mockBudget) Hold(projectID uint64, out func()) bool {
+	if projectID != 1 {
+		return false
+	}
+	m.held = append(m.held, out)
+	return true
+}
+
+func (m *mockBudget) Record(projectID uint64, spend *output.Spend) {
+	m.spent = append(m.spent, spend)
+}
+
+type mockOperators struct {
+	err error
+}
+
+func (m *mockOperators) Signers(projectID uint64) (signer.ECDSA, signer.ED25519, error) {
+	return nil, nil, m.err
+}
+
+// finished returns whether the task is finished by the handled state log
+func finished(finished, _ bool) bool {
+	return finished
+}
+
 func TestTaskStateHandler_handle(t *testing.T) {
 	r := require.New(t)
 	t.Run("FailedToCreateTaskStateLog", func(t *testing.T) {
@@ -30,7 +85,7 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		h := &taskStateHandler{persistence: ps}
 		p.ApplyMethodReturn(ps, "Create", errors.New(t.Name()))
 
-		r.False(h.handle(time.Now(), &task.StateLog{}, &task.Task{}))
+		r.False(finished(h.handle(time.Now(), &task.StateLog{}, &task.Task{}, nil)))
 	})
 	t.Run("StateFailed", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -40,7 +95,7 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		h := &taskStateHandler{persistence: ps}
 		p.ApplyMethodReturn(ps, "Create", nil)
 
-		r.True(h.handle(time.Now(), &task.StateLog{State: task.StateFailed}, &task.Task{}))
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateFailed}, &task.Task{}, nil)))
 	})
 	t.Run("StateDispatched", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -50,7 +105,7 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		h := &taskStateHandler{persistence: ps}
 		p.ApplyMethodReturn(ps, "Create", nil)
 
-		r.False(h.handle(time.Now(), &task.StateLog{State: task.StateDispatched}, &task.Task{}))
+		r.False(finished(h.handle(time.Now(), &task.StateLog{State: task.StateDispatched}, &task.Task{}, nil)))
 	})
 	t.Run("FailedToGetProject", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -65,7 +120,7 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		p.ApplyMethodReturn(ps, "Create", nil)
 		p.ApplyMethodReturn(pm, "Project", nil, errors.New(t.Name()))
 
-		r.False(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}))
+		r.False(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
 	})
 	t.Run("FailedToGetProjectDefaultConfig", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -81,7 +136,7 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
 		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", nil, errors.New(t.Name()))
 
-		r.False(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}))
+		r.False(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
 	})
 	t.Run("FailedToNewOutput", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -98,7 +153,24 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{}, nil)
 		p.ApplyFuncReturn(output.New, nil, errors.New(t.Name()))
 
-		r.True(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}))
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
+	})
+	t.Run("FailedToGetOperatorSigners", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		ps := &postgres.Postgres{}
+		pm := &project.Manager{}
+		h := &taskStateHandler{
+			persistence:    ps,
+			projectManager: pm,
+			operators:      &mockOperators{err: errors.New(t.Name())},
+		}
+		p.ApplyMethodReturn(ps, "Create", nil)
+		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
+		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{}, nil)
+
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
 	})
 	t.Run("FailedToOutput", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -116,7 +188,7 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		p.ApplyFuncReturn(output.New, &mockOutput{}, nil)
 		p.ApplyMethodReturn(&mockOutput{}, "Output", "", errors.New(t.Name()))
 
-		r.True(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}))
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
 	})
 	t.Run("FailedToOutput", func(t *testing.T) {
 		p := gomonkey.NewPatches()
@@ -134,6 +206,129 @@ func TestTaskStateHandler_handle(t *testing.T) {
 		p.ApplyFuncReturn(output.New, &mockOutput{}, nil)
 		p.ApplyMethodReturn(&mockOutput{}, "Output", "", nil)
 
-		r.True(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}))
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
+	})
+	t.Run("DryRun", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		ps := &postgres.Postgres{}
+		pm := &project.Manager{}
+		h := &taskStateHandler{
+			persistence:    ps,
+			projectManager: pm,
+		}
+		ls := []*task.StateLog{}
+		p.ApplyMethod(ps, "Create", func(_ *postgres.Postgres, l *task.StateLog, _ *task.Task) error {
+			ls = append(ls, l)
+			return nil
+		})
+		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
+		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{Output: output.Config{Type: output.EthereumContract}}, nil)
+		p.ApplyFuncReturn(output.New, &mockOutput{}, nil)
+		p.ApplyMethodReturn(&mockOutput{}, "Output", output.DryRunPrefix+"0x01", nil)
+
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{}, nil)))
+		r.Len(ls, 2)
+		r.Equal(task.StateOutputted, ls[1].State)
+		r.Equal("dry run, output type: ethereumContract", ls[1].Comment)
+		r.Equal([]byte(output.DryRunPrefix+"0x01"), ls[1].Result)
+	})
+	t.Run("OutputWithProverSignature", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		ps := &postgres.Postgres{}
+		pm := &project.Manager{}
+		h := &taskStateHandler{
+			persistence:    ps,
+			projectManager: pm,
+		}
+		o := &mockProverSignedOutput{}
+		p.ApplyMethodReturn(ps, "Create", nil)
+		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
+		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{}, nil)
+		p.ApplyFuncReturn(output.New, o, nil)
+
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved, Signature: "0x01"}, &task.Task{}, nil)))
+		r.Equal("0x01", o.signature)
+	})
+	t.Run("HeldByBudget", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		ps := &postgres.Postgres{}
+		pm := &project.Manager{}
+		b := &mockBudget{}
+		h := &taskStateHandler{
+			persistence:    ps,
+			projectManager: pm,
+			budget:         b,
+		}
+		o := &mockProverSignedOutput{}
+		ls := []*task.StateLog{}
+		p.ApplyMethod(ps, "Create", func(_ *postgres.Postgres, l *task.StateLog, _ *task.Task) error {
+			ls = append(ls, l)
+			return nil
+		})
+		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
+		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{}, nil)
+		p.ApplyFuncReturn(output.New, o, nil)
+
+		resumed := false
+		finished, held := h.handle(time.Now(), &task.StateLog{State: task.StateProved, Signature: "0x01"}, &task.Task{ProjectID: 1}, func() { resumed = true })
+		r.False(finished)
+		r.True(held)
+		r.Len(b.held, 1)
+		r.Empty(o.signature)
+		r.Len(ls, 2)
+		r.Equal(task.StateHeld, ls[1].State)
+
+		b.held[0]()
+		r.True(resumed)
+	})
+	t.Run("RecordSpend", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		ps := &postgres.Postgres{}
+		pm := &project.Manager{}
+		b := &mockBudget{}
+		h := &taskStateHandler{
+			persistence:    ps,
+			projectManager: pm,
+			budget:         b,
+		}
+		spend := &output.Spend{GasUsed: 1}
+		p.ApplyMethodReturn(ps, "Create", nil)
+		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
+		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{}, nil)
+		p.ApplyFuncReturn(output.New, &mockSpendOutput{spend: spend}, nil)
+
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved}, &task.Task{ProjectID: 2}, nil)))
+		r.Equal([]*output.Spend{spend}, b.spent)
+	})
+	t.Run("RecordChainedSpend", func(t *testing.T) {
+		p := gomonkey.NewPatches()
+		defer p.Reset()
+
+		ps := &postgres.Postgres{}
+		pm := &project.Manager{}
+		b := &mockBudget{}
+		h := &taskStateHandler{
+			persistence:    ps,
+			projectManager: pm,
+			budget:         b,
+		}
+		spend := &output.Spend{GasUsed: 1}
+		o := &mockProverSignedOutput{spend: spend}
+		p.ApplyMethodReturn(ps, "Create", nil)
+		p.ApplyMethodReturn(pm, "Project", &project.Project{}, nil)
+		p.ApplyMethodReturn(&project.Project{}, "DefaultConfig", &project.Config{}, nil)
+		p.ApplyFuncReturn(output.New, o, nil)
+
+		r.True(finished(h.handle(time.Now(), &task.StateLog{State: task.StateProved, Signature: "0x01"}, &task.Task{ProjectID: 2}, nil)))
+		r.Equal("0x01", o.signature)
+		r.Equal([]*output.Spend{spend}, b.spent)
 	})
 }
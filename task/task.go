@@ -12,12 +12,15 @@ import (
 )
 
 type Task struct {
-	ID             uint64   `json:"id"`
-	ProjectID      uint64   `json:"projectID"`
-	ProjectVersion string   `json:"projectVersion"`
-	Data           [][]byte `json:"data"`
-	ClientID       string   `json:"clientID"`
-	Signature      string   `json:"signature"`
+	ID               uint64             `json:"id"`
+	ProjectID        uint64             `json:"projectID"`
+	ProjectVersion   string             `json:"projectVersion"`
+	Data             [][]byte           `json:"data"`
+	ClientID         string             `json:"clientID"` // empty if the task packs the messages of several clients
+	Signature        string             `json:"signature"`
+	DeviceSignatures []*DeviceSignature `json:"deviceSignatures,omitempty"` // aligned with Data, nil if the message is unsigned
+	MerkleRoot       string             `json:"merkleRoot,omitempty"`       // the util/merkle root over Data, committed by the sequencer
+	RootSignature    string             `json:"rootSignature,omitempty"`
 }
 
 func (t *Task) VerifySignature(pubkey []byte) error {
@@ -111,6 +114,7 @@ const (
 	_
 	StateOutputted
 	StateFailed
+	StateHeld // proved, the output is held until the project budget is resumed
 )
 
 func (s State) String() string {
@@ -125,6 +129,8 @@ func (s State) String() string {
 		return "outputted"
 	case StateFailed:
 		return "failed"
+	case StateHeld:
+		return "held"
 	default:
 		return "invalid"
 	}
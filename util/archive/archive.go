@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Store keeps the archives of purged rows, the archive is named by the component, the project and the task id range
+type Store interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+	// List returns the names with the prefix in ascending order
+	List(prefix string) ([]string, error)
+}
+
+// NewStore returns the store of uri, s3://{bucket}/{prefix} is an s3 compatible bucket, others are local directories
+func NewStore(uri string) (Store, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse archive uri %s", uri)
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3Store(u)
+	case "", "file":
+		if u.Path == "" {
+			return nil, errors.Errorf("empty archive directory, uri %s", uri)
+		}
+		return &dirStore{dir: u.Path}, nil
+	}
+	return nil, errors.Errorf("unsupported archive uri %s", uri)
+}
+
+// Name returns the archive name of the rows of task first to last, e.g. sequencer/1/00000000000000000001-00000000000000000100.jsonl.gz
+// the ids are zero padded so the names are listed in task order
+func Name(component string, projectID, first, last uint64) string {
+	return fmt.Sprintf("%s/%d/%020d-%020d.jsonl.gz", component, projectID, first, last)
+}
+
+func parseName(name string) (first, last uint64, ok bool) {
+	i := strings.LastIndex(name, "/")
+	if _, err := fmt.Sscanf(name[i+1:], "%020d-%020d.jsonl.gz", &first, &last); err != nil {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// Find returns the archives of the project containing the tasks from first to last
+func Find(s Store, component string, projectID, first, last uint64) ([]string, error) {
+	names, err := s.List(fmt.Sprintf("%s/%d/", component, projectID))
+	if err != nil {
+		return nil, err
+	}
+	found := []string{}
+	for _, n := range names {
+		f, l, ok := parseName(n)
+		if !ok || l < first || f > last {
+			continue
+		}
+		found = append(found, n)
+	}
+	return found, nil
+}
+
+// Encode encodes the records as gzip compressed json lines
+func Encode[T any](records []T) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	w := gzip.NewWriter(buf)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, errors.Wrap(err, "failed to encode archive record")
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress archive")
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decodes the records encoded by Encode
+func Decode[T any](data []byte) ([]T, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress archive")
+	}
+	defer r.Close()
+
+	records := []T{}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 64<<20)
+	for s.Scan() {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(s.Bytes(), &v); err != nil {
+			return nil, errors.Wrap(err, "failed to decode archive record")
+		}
+		records = append(records, v)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read archive")
+	}
+	return records, nil
+}
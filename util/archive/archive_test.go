@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStore(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Directory", func(t *testing.T) {
+		s, err := NewStore("./archive")
+		r.NoError(err)
+		r.Equal(&dirStore{dir: "./archive"}, s)
+
+		s, err = NewStore("file:///var/archive")
+		r.NoError(err)
+		r.Equal(&dirStore{dir: "/var/archive"}, s)
+	})
+
+	t.Run("EmptyDirectory", func(t *testing.T) {
+		_, err := NewStore("file://")
+		r.ErrorContains(err, "empty archive directory")
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "ak")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "sk")
+
+		s, err := NewStore("s3://bucket/sprout/archive?region=eu-west-1")
+		r.NoError(err)
+		s3 := s.(*s3Store)
+		r.Equal("bucket", s3.bucket)
+		r.Equal("sprout/archive/", s3.prefix)
+		r.Equal("https://s3.eu-west-1.amazonaws.com", s3.endpoint)
+	})
+
+	t.Run("EmptyS3Credential", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "")
+		_, err := NewStore("s3://bucket")
+		r.ErrorContains(err, "empty s3 credential")
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := NewStore("ftp://host/archive")
+		r.ErrorContains(err, "unsupported archive uri")
+	})
+}
+
+func TestName(t *testing.T) {
+	r := require.New(t)
+
+	name := Name("sequencer", 1, 2, 30)
+	r.Equal("sequencer/1/00000000000000000002-00000000000000000030.jsonl.gz", name)
+
+	first, last, ok := parseName(name)
+	r.True(ok)
+	r.Equal(uint64(2), first)
+	r.Equal(uint64(30), last)
+
+	_, _, ok = parseName("sequencer/1/invalid.jsonl.gz")
+	r.False(ok)
+}
+
+func TestFind(t *testing.T) {
+	r := require.New(t)
+
+	s := &dirStore{dir: t.TempDir()}
+	for _, n := range []string{
+		Name("sequencer", 1, 1, 10),
+		Name("sequencer", 1, 11, 20),
+		Name("sequencer", 1, 21, 30),
+		Name("sequencer", 12, 1, 100),
+		Name("coordinator", 1, 1, 100),
+	} {
+		r.NoError(s.Put(n, []byte("data")))
+	}
+
+	names, err := Find(s, "sequencer", 1, 15, 21)
+	r.NoError(err)
+	r.Equal([]string{Name("sequencer", 1, 11, 20), Name("sequencer", 1, 21, 30)}, names)
+
+	names, err = Find(s, "sequencer", 2, 0, 100)
+	r.NoError(err)
+	r.Empty(names)
+}
+
+func TestEncode(t *testing.T) {
+	r := require.New(t)
+
+	type record struct {
+		ID   uint64 `json:"id"`
+		Data []byte `json:"data"`
+	}
+	records := []*record{{ID: 1, Data: []byte("a")}, {ID: 2}}
+
+	data, err := Encode(records)
+	r.NoError(err)
+
+	decoded, err := Decode[*record](data)
+	r.NoError(err)
+	r.Equal(records, decoded)
+
+	_, err = Decode[*record]([]byte("not gzip"))
+	r.ErrorContains(err, "failed to decompress archive")
+
+	invalid, err := Encode([]string{"a"})
+	r.NoError(err)
+	_, err = Decode[*record](invalid)
+	r.ErrorContains(err, "failed to decode archive record")
+}
@@ -0,0 +1,43 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirStore(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	s := &dirStore{dir: filepath.Join(dir, "archive")}
+
+	t.Run("ListNotExist", func(t *testing.T) {
+		names, err := s.List("sequencer/")
+		r.NoError(err)
+		r.Empty(names)
+	})
+
+	t.Run("PutAndGet", func(t *testing.T) {
+		r.NoError(s.Put("sequencer/1/b.jsonl.gz", []byte("b")))
+		r.NoError(s.Put("sequencer/1/a.jsonl.gz", []byte("a")))
+		r.NoError(s.Put("coordinator/1/a.jsonl.gz", []byte("c")))
+		// a partially written archive
+		r.NoError(os.WriteFile(filepath.Join(dir, "archive", "sequencer", "1", "c.jsonl.gz.tmp"), []byte("c"), 0644))
+
+		data, err := s.Get("sequencer/1/b.jsonl.gz")
+		r.NoError(err)
+		r.Equal([]byte("b"), data)
+
+		names, err := s.List("sequencer/1/")
+		r.NoError(err)
+		r.Equal([]string{"sequencer/1/a.jsonl.gz", "sequencer/1/b.jsonl.gz"}, names)
+	})
+
+	t.Run("GetNotExist", func(t *testing.T) {
+		_, err := s.Get("sequencer/2/a.jsonl.gz")
+		r.ErrorContains(err, "failed to read archive")
+	})
+}
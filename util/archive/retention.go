@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Retention is the days the rows of each project are kept before archived and purged, 0 keeps them forever
+type Retention struct {
+	days     uint
+	projects map[uint64]uint
+}
+
+// LoadRetentionOverrides loads the retention file of per project days, e.g. {"1": 30, "2": 0}
+func LoadRetentionOverrides(path string) (map[string]uint, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read retention file %s", path)
+	}
+	overrides := map[string]uint{}
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode retention file %s", path)
+	}
+	return overrides, nil
+}
+
+// NewRetention returns the retention of days by default, the overrides are keyed by project id
+func NewRetention(days uint, overrides map[string]uint) (*Retention, error) {
+	r := &Retention{days: days, projects: map[uint64]uint{}}
+	for k, v := range overrides {
+		projectID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid project id %s", k)
+		}
+		r.projects[projectID] = v
+	}
+	return r, nil
+}
+
+// Enabled returns whether the rows of any project expire
+func (r *Retention) Enabled() bool {
+	if r.days > 0 {
+		return true
+	}
+	for _, d := range r.projects {
+		if d > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Of returns the retention of the project, 0 means forever
+func (r *Retention) Of(projectID uint64) time.Duration {
+	days, ok := r.projects[projectID]
+	if !ok {
+		days = r.days
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRetentionOverrides(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	t.Run("FailedToRead", func(t *testing.T) {
+		_, err := LoadRetentionOverrides(filepath.Join(dir, "not_exist.json"))
+		r.ErrorContains(err, "failed to read retention file")
+	})
+
+	t.Run("FailedToDecode", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		r.NoError(os.WriteFile(path, []byte("{"), 0600))
+		_, err := LoadRetentionOverrides(path)
+		r.ErrorContains(err, "failed to decode retention file")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(dir, "retention.json")
+		r.NoError(os.WriteFile(path, []byte(`{"1": 30, "2": 0}`), 0600))
+		overrides, err := LoadRetentionOverrides(path)
+		r.NoError(err)
+		r.Equal(map[string]uint{"1": 30, "2": 0}, overrides)
+	})
+}
+
+func TestNewRetention(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("InvalidProjectID", func(t *testing.T) {
+		_, err := NewRetention(0, map[string]uint{"a": 1})
+		r.ErrorContains(err, "invalid project id a")
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		rt, err := NewRetention(7, map[string]uint{"1": 30, "2": 0})
+		r.NoError(err)
+		r.True(rt.Enabled())
+		r.Equal(30*24*time.Hour, rt.Of(1))
+		r.Equal(time.Duration(0), rt.Of(2))
+		r.Equal(7*24*time.Hour, rt.Of(3))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		rt, err := NewRetention(0, map[string]uint{"1": 0})
+		r.NoError(err)
+		r.False(rt.Enabled())
+
+		rt, err = NewRetention(0, map[string]uint{"1": 1})
+		r.NoError(err)
+		r.True(rt.Enabled())
+		r.Equal(time.Duration(0), rt.Of(2))
+	})
+}
@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is an in memory bucket of path style requests
+type fakeS3 struct {
+	mux     sync.Mutex
+	objects map[string][]byte
+	auth    []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.auth = append(f.auth, req.Header.Get("Authorization"))
+	key := strings.TrimPrefix(req.URL.Path, "/bucket/")
+	switch {
+	case req.Method == http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		f.objects[key] = data
+	case req.URL.Query().Get("list-type") == "2":
+		keys := []string{}
+		for k := range f.objects {
+			if strings.HasPrefix(k, req.URL.Query().Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		// one key per page
+		start := 0
+		if token := req.URL.Query().Get("continuation-token"); token != "" {
+			start = sort.SearchStrings(keys, token)
+		}
+		res := &listBucketResult{}
+		if start < len(keys) {
+			res.Contents = append(res.Contents, struct {
+				Key string `xml:"Key"`
+			}{Key: keys[start]})
+		}
+		if start+1 < len(keys) {
+			res.IsTruncated = true
+			res.NextContinuationToken = keys[start+1]
+		}
+		_ = xml.NewEncoder(w).Encode(res)
+	default:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	r := require.New(t)
+
+	f := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "ak")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "sk")
+	u, err := url.Parse("s3://bucket/prefix?endpoint=" + srv.URL)
+	r.NoError(err)
+	s, err := newS3Store(u)
+	r.NoError(err)
+
+	t.Run("PutAndGet", func(t *testing.T) {
+		r.NoError(s.Put("sequencer/1/a.jsonl.gz", []byte("a")))
+		r.NoError(s.Put("sequencer/1/b.jsonl.gz", []byte("b")))
+		r.NoError(s.Put("coordinator/1/a.jsonl.gz", []byte("c")))
+		r.Contains(f.objects, "prefix/sequencer/1/a.jsonl.gz")
+
+		data, err := s.Get("sequencer/1/b.jsonl.gz")
+		r.NoError(err)
+		r.Equal([]byte("b"), data)
+	})
+
+	t.Run("GetNotExist", func(t *testing.T) {
+		_, err := s.Get("sequencer/2/a.jsonl.gz")
+		r.ErrorContains(err, "s3 responded 404")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		names, err := s.List("sequencer/1/")
+		r.NoError(err)
+		r.Equal([]string{"sequencer/1/a.jsonl.gz", "sequencer/1/b.jsonl.gz"}, names)
+	})
+
+	t.Run("Signed", func(t *testing.T) {
+		for _, a := range f.auth {
+			r.True(strings.HasPrefix(a, "AWS4-HMAC-SHA256 Credential=ak/"))
+			r.Contains(a, "/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=")
+		}
+	})
+}
+
+func TestS3Store_sign(t *testing.T) {
+	r := require.New(t)
+
+	s := &s3Store{
+		region:    "us-east-1",
+		accessKey: "ak",
+		secretKey: "sk",
+		now:       func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	sign := func() *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "https://s3.amazonaws.com/bucket/a.jsonl.gz", nil)
+		r.NoError(err)
+		s.sign(req, nil)
+		return req
+	}
+
+	req := sign()
+	r.Equal("20240102T030405Z", req.Header.Get("x-amz-date"))
+	r.Equal(sha256Hex(nil), req.Header.Get("x-amz-content-sha256"))
+	r.Contains(req.Header.Get("Authorization"), "Credential=ak/20240102/us-east-1/s3/aws4_request")
+	r.Equal(req.Header.Get("Authorization"), sign().Header.Get("Authorization"))
+
+	s.sessionToken = "token"
+	req = sign()
+	r.Equal("token", req.Header.Get("x-amz-security-token"))
+	r.Contains(req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token")
+}
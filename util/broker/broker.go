@@ -0,0 +1,78 @@
+package broker
+
+import "sync"
+
+// Broker fans out the published values to the subscribers of the key. a subscriber which could not keep up is
+// dropped by closing its channel, so it could resume from the persisted history
+type Broker[K comparable, V any] struct {
+	mux  sync.Mutex
+	subs map[K]map[chan V]struct{}
+	size int
+}
+
+// Subscribe returns the channel of values published to k, and the func to cancel the subscription
+func (b *Broker[K, V]) Subscribe(k K) (<-chan V, func()) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	ch := make(chan V, b.size)
+	if b.subs[k] == nil {
+		b.subs[k] = map[chan V]struct{}{}
+	}
+	b.subs[k][ch] = struct{}{}
+
+	return ch, func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		b.remove(k, ch)
+	}
+}
+
+// Subscribed returns whether k has any subscriber
+func (b *Broker[K, V]) Subscribed(k K) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return len(b.subs[k]) > 0
+}
+
+// Len returns the number of keys which have subscribers
+func (b *Broker[K, V]) Len() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return len(b.subs)
+}
+
+// Publish sends v to the subscribers of k without blocking
+func (b *Broker[K, V]) Publish(k K, v V) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for ch := range b.subs[k] {
+		select {
+		case ch <- v:
+		default:
+			b.remove(k, ch)
+		}
+	}
+}
+
+func (b *Broker[K, V]) remove(k K, ch chan V) {
+	if _, ok := b.subs[k][ch]; !ok {
+		return
+	}
+	delete(b.subs[k], ch)
+	if len(b.subs[k]) == 0 {
+		delete(b.subs, k)
+	}
+	close(ch)
+}
+
+// New returns a broker, size is the channel buffer of each subscriber
+func New[K comparable, V any](size int) *Broker[K, V] {
+	return &Broker[K, V]{
+		subs: map[K]map[chan V]struct{}{},
+		size: size,
+	}
+}
@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Publish", func(t *testing.T) {
+		b := New[uint64, string](1)
+		ch1, cancel1 := b.Subscribe(1)
+		defer cancel1()
+		ch2, cancel2 := b.Subscribe(2)
+		defer cancel2()
+
+		r.True(b.Subscribed(1))
+		r.Equal(2, b.Len())
+		b.Publish(1, "a")
+		r.Equal("a", <-ch1)
+		r.Len(ch2, 0)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		b := New[uint64, string](1)
+		ch, cancel := b.Subscribe(1)
+		cancel()
+		cancel()
+
+		_, ok := <-ch
+		r.False(ok)
+		r.False(b.Subscribed(1))
+		r.Equal(0, b.Len())
+		b.Publish(1, "a")
+	})
+
+	t.Run("DropSlowSubscriber", func(t *testing.T) {
+		b := New[uint64, string](1)
+		ch, cancel := b.Subscribe(1)
+		defer cancel()
+
+		b.Publish(1, "a")
+		b.Publish(1, "b")
+		r.Equal("a", <-ch)
+		_, ok := <-ch
+		r.False(ok)
+		r.False(b.Subscribed(1))
+	})
+}
@@ -0,0 +1,102 @@
+package merkle
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// the prefixes of leaf and inner node hashes, which prevent an inner node from being proved as a leaf
+var (
+	leafPrefix = []byte{0}
+	nodePrefix = []byte{1}
+)
+
+// ProofNode is a sibling on the path from a leaf to the root
+type ProofNode struct {
+	Hash common.Hash `json:"hash"`
+	Left bool        `json:"left"` // whether the sibling is the left child
+}
+
+// Tree is a keccak256 binary merkle tree, the leaf is keccak256(0x00 || data) and the inner node is
+// keccak256(0x01 || left || right). the last node of an odd level is promoted to the upper level as is
+type Tree struct {
+	levels [][]common.Hash // from leaves to root
+}
+
+func LeafHash(data []byte) common.Hash {
+	return crypto.Keccak256Hash(leafPrefix, data)
+}
+
+func nodeHash(left, right common.Hash) common.Hash {
+	return crypto.Keccak256Hash(nodePrefix, left.Bytes(), right.Bytes())
+}
+
+// New builds the tree over the ordered data
+func New(data [][]byte) *Tree {
+	leaves := make([]common.Hash, 0, len(data))
+	for _, d := range data {
+		leaves = append(leaves, LeafHash(d))
+	}
+	return FromLeaves(leaves)
+}
+
+// FromLeaves builds the tree over the ordered leaf hashes, the same tree as New over the data of the leaves
+func FromLeaves(leaves []common.Hash) *Tree {
+	level := leaves
+	t := &Tree{levels: [][]common.Hash{level}}
+	for len(level) > 1 {
+		upper := make([]common.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				upper = append(upper, level[i])
+				continue
+			}
+			upper = append(upper, nodeHash(level[i], level[i+1]))
+		}
+		t.levels = append(t.levels, upper)
+		level = upper
+	}
+	return t
+}
+
+// Root returns the root, the zero hash if the tree is empty
+func (t *Tree) Root() common.Hash {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return common.Hash{}
+	}
+	return top[0]
+}
+
+// Leaves returns the ordered leaf hashes
+func (t *Tree) Leaves() []common.Hash {
+	return t.levels[0]
+}
+
+// Proof returns the inclusion proof of the i-th data, nil if out of range
+func (t *Tree) Proof(i int) []*ProofNode {
+	if i < 0 || i >= len(t.levels[0]) {
+		return nil
+	}
+	proof := []*ProofNode{}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if sibling := i ^ 1; sibling < len(level) {
+			proof = append(proof, &ProofNode{Hash: level[sibling], Left: sibling < i})
+		}
+		i /= 2
+	}
+	return proof
+}
+
+// Verify verifies the data is included in the tree of the root
+func Verify(root common.Hash, data []byte, proof []*ProofNode) bool {
+	h := LeafHash(data)
+	for _, n := range proof {
+		if n.Left {
+			h = nodeHash(n.Hash, h)
+		} else {
+			h = nodeHash(h, n.Hash)
+		}
+	}
+	return h == root
+}
@@ -0,0 +1,61 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("Empty", func(t *testing.T) {
+		tr := New(nil)
+		r.Equal(common.Hash{}, tr.Root())
+		r.Nil(tr.Proof(0))
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		tr := New([][]byte{[]byte("a")})
+		r.Equal(LeafHash([]byte("a")), tr.Root())
+		r.Empty(tr.Proof(0))
+		r.True(Verify(tr.Root(), []byte("a"), tr.Proof(0)))
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		a, b, c := LeafHash([]byte("a")), LeafHash([]byte("b")), LeafHash([]byte("c"))
+		tr := New([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		r.Equal(nodeHash(nodeHash(a, b), c), tr.Root())
+	})
+
+	t.Run("Proof", func(t *testing.T) {
+		for n := 1; n <= 9; n++ {
+			data := [][]byte{}
+			for i := 0; i < n; i++ {
+				data = append(data, []byte(fmt.Sprintf("data%d", i)))
+			}
+			tr := New(data)
+			for i, d := range data {
+				r.True(Verify(tr.Root(), d, tr.Proof(i)), "%d of %d", i, n)
+				r.False(Verify(tr.Root(), []byte("other"), tr.Proof(i)))
+			}
+			r.Nil(tr.Proof(n))
+			r.Nil(tr.Proof(-1))
+		}
+	})
+
+	t.Run("FromLeaves", func(t *testing.T) {
+		tr := New([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		fromLeaves := FromLeaves(tr.Leaves())
+		r.Equal(tr.Root(), fromLeaves.Root())
+		r.Equal(tr.Proof(2), fromLeaves.Proof(2))
+	})
+
+	t.Run("InnerNodeIsNotLeaf", func(t *testing.T) {
+		a, b := LeafHash([]byte("a")), LeafHash([]byte("b"))
+		tr := New([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+		r.False(Verify(tr.Root(), append(a.Bytes(), b.Bytes()...), tr.Proof(2)[1:]))
+	})
+}